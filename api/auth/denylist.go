@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// Denylist records access tokens, by jti, that were revoked before they
+// expired. Entries only need to be kept until the token's own expiry.
+type Denylist interface {
+	Revoke(jti string, expiresAt time.Time) error
+	IsRevoked(jti string) (bool, error)
+}
+
+var denylist Denylist = NewMemoryDenylist()
+
+// SetDenylist replaces the denylist consulted when validating tokens
+func SetDenylist(d Denylist) {
+	denylist = d
+}
+
+func RevokeToken(jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return nil
+	}
+
+	return denylist.Revoke(jti, expiresAt)
+}
+
+func IsTokenRevoked(jti string) (bool, error) {
+	if jti == "" {
+		return false, nil
+	}
+
+	return denylist.IsRevoked(jti)
+}
+
+// MemoryDenylist is a process-local Denylist, used until a persistent one is
+// configured.
+type MemoryDenylist struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+}
+
+func NewMemoryDenylist() *MemoryDenylist {
+	return &MemoryDenylist{entries: map[string]time.Time{}}
+}
+
+func (d *MemoryDenylist) Revoke(jti string, expiresAt time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	for id, exp := range d.entries {
+		if exp.Before(now) {
+			delete(d.entries, id)
+		}
+	}
+
+	d.entries[jti] = expiresAt
+	return nil
+}
+
+func (d *MemoryDenylist) IsRevoked(jti string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	exp, ok := d.entries[jti]
+	return ok && exp.After(time.Now()), nil
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// Principal is the authenticated caller of a request, as described by the
+// access token it presented.
+type Principal struct {
+	UserID    uint32
+	Roles     []string
+	TokenID   string
+	ExpiresAt time.Time
+}
+
+type contextKey int
+
+const principalKey contextKey = iota
+
+// NewContext returns a copy of ctx carrying p
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+// FromContext returns the principal stored by the authentication middleware
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey).(*Principal)
+	return p, ok && p != nil
+}
@@ -0,0 +1,50 @@
+package auth
+
+const (
+	RoleUser   = "user"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// Permissions follow resource:action:scope. Owners may always act on their
+// own resources; the "any" scope extends that to everyone else's.
+const (
+	PermPostsUpdateAny    = "posts:update:any"
+	PermPostsDeleteAny    = "posts:delete:any"
+	PermCommentsDeleteAny = "comments:delete:any"
+	PermUsersManage       = "users:manage"
+)
+
+var rolePermissions = map[string][]string{
+	RoleUser:   {},
+	RoleEditor: {PermPostsUpdateAny, PermPostsDeleteAny, PermCommentsDeleteAny},
+	RoleAdmin:  {PermPostsUpdateAny, PermPostsDeleteAny, PermCommentsDeleteAny, PermUsersManage},
+}
+
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Can reports whether any of the principal's roles grants permission
+func (p *Principal) Can(permission string) bool {
+	for _, role := range p.Roles {
+		for _, granted := range rolePermissions[role] {
+			if granted == permission {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -1,9 +1,8 @@
 package auth
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"strconv"
@@ -13,17 +12,48 @@ import (
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
-func CreateToken(user_id uint32) (string, error) {
+const (
+	AccessTokenTTL  = time.Hour
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var secret string
+
+// SetSecret sets the key tokens are signed with. Until it is called the
+// API_SECRET environment variable is used.
+func SetSecret(s string) {
+	secret = s
+}
+
+func signingKey() []byte {
+	if secret == "" {
+		return []byte(os.Getenv("API_SECRET"))
+	}
+	return []byte(secret)
+}
+
+// CreateToken issues an access token for the user. The role is copied into
+// the token, so role changes apply once the user refreshes or logs in again.
+func CreateToken(user_id uint32, role string) (string, error) {
+	jti, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+
 	claims := jwt.MapClaims{}
 	claims["authorized"] = true
 	claims["user_id"] = user_id
-	claims["exp"] = time.Now().Add(time.Hour * 1).Unix() //Token expires after 1 hr
+	claims["jti"] = jti
+	claims["roles"] = []string{role}
+	claims["exp"] = time.Now().Add(AccessTokenTTL).Unix() //Token expires after 1 hr
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("API_SECRET")))
+	return token.SignedString(signingKey())
 }
 
-func ValidateToken(r *http.Request) error {
+// ParseToken verifies the bearer token on the request and returns the
+// principal it was issued to. Revoked tokens are rejected.
+func ParseToken(r *http.Request) (*Principal, error) {
 	tokenString := ExtractToken(r)
 
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
@@ -31,73 +61,63 @@ func ValidateToken(r *http.Request) error {
 			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
 		}
 
-		return []byte(os.Getenv("API_SECRET")), nil
+		return signingKey(), nil
 	})
 
 	if err != nil {
-		return err
-	}
-
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		Pretty(claims)
+		return nil, err
 	}
 
-	return nil
-}
-
-func ExtractToken(r *http.Request) string {
-	keys := r.URL.Query()
-	token := keys.Get("token")
+	claims, ok := token.Claims.(jwt.MapClaims)
 
-	if token != "" {
-		return token
+	if !ok || !token.Valid {
+		return nil, errors.New("Invalid token")
 	}
 
-	bearerToken := r.Header.Get("Authorization")
-	if len(strings.Split(bearerToken, " ")) == 2 {
-		return strings.Split(bearerToken, " ")[1]
+	uid, err := strconv.ParseUint(fmt.Sprintf("%.0f", claims["user_id"]), 10, 32)
+	if err != nil {
+		return nil, err
 	}
 
-	return ""
-}
+	principal := &Principal{UserID: uint32(uid)}
+	principal.TokenID, _ = claims["jti"].(string)
 
-func ExtractTokenId(r *http.Request) (uint32, error) {
-	tokenString := ExtractToken(r)
+	if exp, ok := claims["exp"].(float64); ok {
+		principal.ExpiresAt = time.Unix(int64(exp), 0)
+	}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+	if roles, ok := claims["roles"].([]interface{}); ok {
+		for _, role := range roles {
+			if name, ok := role.(string); ok {
+				principal.Roles = append(principal.Roles, name)
+			}
 		}
+	}
 
-		return []byte(os.Getenv("API_SECRET")), nil
-	})
-
+	revoked, err := IsTokenRevoked(principal.TokenID)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-
-	if ok && token.Valid {
-		uid, err := strconv.ParseUint(fmt.Sprintf("%.0f", claims["user_id"]), 10, 32)
-		if err != nil {
-			return 0, err
-		}
-
-		return uint32(uid), nil
+	if revoked {
+		return nil, errors.New("Token has been revoked")
 	}
 
-	return 0, nil
+	return principal, nil
 }
 
-// prettify claims for terminal
-func Pretty(data interface{}) {
-	b, err := json.MarshalIndent(data, "", " ")
+func ExtractToken(r *http.Request) string {
+	keys := r.URL.Query()
+	token := keys.Get("token")
 
-	if err != nil {
-		log.Println(err)
-		return
+	if token != "" {
+		return token
 	}
 
-	fmt.Println(string(b))
+	bearerToken := r.Header.Get("Authorization")
+	if len(strings.Split(bearerToken, " ")) == 2 {
+		return strings.Split(bearerToken, " ")[1]
+	}
+
+	return ""
 }
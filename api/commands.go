@@ -0,0 +1,365 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/auth"
+	"github.com/dmdinh22/go-blog/api/config"
+	"github.com/dmdinh22/go-blog/api/controllers"
+	"github.com/dmdinh22/go-blog/api/export"
+	"github.com/dmdinh22/go-blog/api/frontend"
+	"github.com/dmdinh22/go-blog/api/logger"
+	"github.com/dmdinh22/go-blog/api/migrations"
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/dmdinh22/go-blog/api/seed"
+	"golang.org/x/term"
+)
+
+const usage = `Usage: go-blog <command> [flags]
+
+Commands:
+  serve                  start the API and the blog (the default)
+  migrate up             apply the pending migrations
+  migrate down           revert the last migration
+  migrate status         list the migrations and whether they are applied
+  seed                   add sample users and posts to an empty database
+  user create [--admin]  create a user
+  token issue            print an access token for a user
+  export                 write the published posts as a static site
+
+Run "go-blog <command> -h" for the flags of a command.
+`
+
+// Execute runs the command named by the first argument, serve when there
+// is none
+func Execute(args []string) {
+	if len(args) == 0 {
+		Serve(nil)
+		return
+	}
+
+	switch args[0] {
+	case "serve":
+		Serve(args[1:])
+	case "migrate":
+		Migrate(args[1:])
+	case "seed":
+		Seed(args[1:])
+	case "user":
+		if len(args) < 2 || args[1] != "create" {
+			exitUsage()
+		}
+		CreateUser(args[2:])
+	case "token":
+		if len(args) < 2 || args[1] != "issue" {
+			exitUsage()
+		}
+		IssueToken(args[2:])
+	case "export":
+		Export(args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		exitUsage()
+	}
+}
+
+func exitUsage() {
+	fmt.Fprint(os.Stderr, usage)
+	os.Exit(2)
+}
+
+// Serve starts the server. Unless -migrate=false it first runs migrate up,
+// which rewrites data as well as the schema, e.g. gives posts without a
+// slug one. SIGINT and SIGTERM shut it down gracefully.
+func Serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	migrate := flags.Bool("migrate", true, "run migrate up before serving")
+	settings := config.AddFlags(flags)
+	flags.Parse(args)
+
+	cfg := loadConfig(settings)
+	server.Initialize(cfg)
+
+	if *migrate {
+		err := models.Migrate(server.DB)
+		if err != nil {
+			logger.Default().Fatal("Cannot migrate the database", "error", err)
+		}
+	}
+
+	// Kubernetes sends SIGTERM before stopping a pod
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	err := server.Run(ctx, cfg)
+	if err != nil {
+		logger.Default().Fatal("Server stopped", "error", err)
+	}
+
+	logger.Default().Info("Server stopped")
+}
+
+// Migrate runs migrate up, down or status
+func Migrate(args []string) {
+	if len(args) == 0 {
+		exitUsage()
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+	settings := config.AddFlags(flags)
+	dryRun := flags.Bool("dry-run", false, "print the SQL that would run instead of running it")
+	steps := flags.Int("steps", 1, "number of migrations migrate down reverts")
+	force := flags.Bool("force", false, "confirm migrate down, which drops data")
+	flags.Parse(args[1:])
+
+	cfg := loadConfig(settings)
+
+	db := connect(cfg)
+	defer db.Close()
+
+	migrator, err := migrations.New(db.DB(), db.Dialect().GetName())
+	if err != nil {
+		logger.Default().Fatal("Cannot load migrations", "error", err)
+	}
+	migrator.DryRun = *dryRun
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		if *dryRun {
+			_, err = migrator.Up(ctx)
+			if err != nil {
+				logger.Default().Fatal("Cannot migrate the database", "error", err)
+			}
+
+			// slugs are made in Go after the SQL, see models.Migrate
+			count, err := models.PostsWithoutSlug(db)
+			if err != nil {
+				logger.Default().Fatal("Cannot count the posts without a slug", "error", err)
+			}
+			fmt.Printf("-- then give the %d posts without a slug one\n", count)
+			return
+		}
+
+		err = models.Migrate(db)
+		if err != nil {
+			logger.Default().Fatal("Cannot migrate the database", "error", err)
+		}
+		fmt.Println("Schema is up to date")
+	case "down":
+		if !*force && !*dryRun {
+			logger.Default().Fatal("migrate down drops tables and their data, pass -force to confirm")
+		}
+
+		done, err := migrator.Down(ctx, *steps)
+		if err != nil {
+			logger.Default().Fatal("Cannot revert migrations", "error", err)
+		}
+
+		if !*dryRun {
+			for _, m := range done {
+				fmt.Printf("Reverted %s\n", m.ID())
+			}
+		}
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			logger.Default().Fatal("Cannot read migrations", "error", err)
+		}
+
+		for _, s := range status {
+			state := "pending"
+			if s.Dirty {
+				state = "dirty, fix by hand"
+			} else if s.Applied {
+				state = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%-32s %s\n", s.ID(), state)
+		}
+	default:
+		exitUsage()
+	}
+}
+
+// Seed adds the sample users and posts to an empty database
+func Seed(args []string) {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	settings := config.AddFlags(flags)
+	flags.Parse(args)
+
+	cfg := loadConfig(settings)
+
+	db := connect(cfg)
+	defer db.Close()
+
+	seed.Load(db)
+}
+
+// CreateUser creates a user, an admin with --admin. The password is never a
+// flag, where ps and the shell history would show it: it comes from
+// USER_PASSWORD_FILE, USER_PASSWORD or else standard input.
+func CreateUser(args []string) {
+	flags := flag.NewFlagSet("user create", flag.ExitOnError)
+	settings := config.AddFlags(flags)
+	username := flags.String("username", "", "username of the user")
+	email := flags.String("email", "", "email address of the user")
+	admin := flags.Bool("admin", false, "grant the admin role")
+	flags.Parse(args)
+
+	cfg := loadConfig(settings)
+
+	password := readPassword()
+
+	user := models.User{Username: *username, Email: *email, Password: password}
+	user.Prepare()
+
+	err := user.Validate("")
+	if err != nil {
+		logger.Default().Fatal("Invalid user", "error", err)
+	}
+
+	if *admin {
+		user.Role = auth.RoleAdmin
+	}
+
+	db := connect(cfg)
+	defer db.Close()
+
+	_, err = user.CreateUser(db)
+	if err != nil {
+		logger.Default().Fatal("Cannot create user", "error", err)
+	}
+
+	fmt.Printf("Created %s %d (%s)\n", user.Role, user.ID, user.Email)
+}
+
+// IssueToken prints an access token for a user, e.g. for scripts calling
+// the API
+func IssueToken(args []string) {
+	flags := flag.NewFlagSet("token issue", flag.ExitOnError)
+	settings := config.AddFlags(flags)
+	email := flags.String("email", "", "email address of the user")
+	flags.Parse(args)
+
+	cfg := loadConfig(settings)
+
+	if *email == "" {
+		logger.Default().Fatal("Required Email.")
+	}
+
+	db := connect(cfg)
+	defer db.Close()
+
+	user, err := models.NewGormUserRepository(db).GetByEmail(*email)
+	if err != nil {
+		logger.Default().Fatal("Cannot find user", "email", *email, "error", err)
+	}
+
+	auth.SetSecret(cfg.APISecret)
+	token, err := auth.CreateToken(user.ID, user.Role)
+	if err != nil {
+		logger.Default().Fatal("Cannot create token", "error", err)
+	}
+
+	fmt.Println(token)
+}
+
+// Export writes the published posts as a static site, see export.Exporter
+func Export(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	settings := config.AddFlags(flags)
+	out := flags.String("out", "public", "directory to write the site to")
+	baseURL := flags.String("base-url", "http://localhost:8080", "URL the site will be served at")
+	force := flags.Bool("force", false, "rewrite every post, not only the changed ones")
+	flags.Parse(args)
+
+	cfg := loadConfig(settings)
+
+	db := connect(cfg)
+	defer db.Close()
+
+	theme, err := frontend.Load(cfg.ThemeDir)
+	if err != nil {
+		logger.Default().Fatal("Cannot load theme", "error", err)
+	}
+
+	store, err := controllers.OpenStorage(cfg)
+	if err != nil {
+		logger.Default().Fatal("Cannot set up storage", "error", err)
+	}
+
+	exporter := export.Exporter{
+		DB:      db,
+		Theme:   theme,
+		Storage: store,
+		Dir:     *out,
+		BaseURL: *baseURL,
+		Force:   *force,
+	}
+
+	stats, err := exporter.Run()
+	if err != nil {
+		logger.Default().Fatal("Export failed", "error", err)
+	}
+
+	fmt.Printf("Exported to %s: %d posts written, %d unchanged, %d removed\n", *out, stats.Written, stats.Skipped, stats.Removed)
+}
+
+// readPassword reads the password of a new user from the file named by
+// USER_PASSWORD_FILE, from USER_PASSWORD or else from standard input
+func readPassword() string {
+	value, ok := os.LookupEnv("USER_PASSWORD")
+	file, fromFile := os.LookupEnv("USER_PASSWORD_FILE")
+
+	if ok && fromFile {
+		logger.Default().Fatal("USER_PASSWORD is set along with USER_PASSWORD_FILE, set only one")
+	}
+
+	if fromFile {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			logger.Default().Fatal("Cannot read USER_PASSWORD_FILE", "error", err)
+		}
+		return strings.TrimRight(string(data), "\r\n")
+	}
+
+	if ok {
+		return value
+	}
+
+	return readSecret("Password: ")
+}
+
+// readSecret reads a line from standard input, without echoing it when
+// standard input is a terminal
+func readSecret(prompt string) string {
+	fmt.Fprint(os.Stderr, prompt)
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		secret, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			logger.Default().Fatal("Cannot read from standard input", "error", err)
+		}
+		return strings.TrimSpace(string(secret))
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		logger.Default().Fatal("Cannot read from standard input", "error", err)
+	}
+
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,392 @@
+// Package config loads the settings of the blog. Each setting is looked up,
+// lowest precedence first, in:
+//
+//  1. the defaults
+//  2. the YAML file named by -config or CONFIG_FILE
+//  3. the environment, including a .env file in the working directory
+//  4. the command line flags
+//
+// Every environment variable can also be given as a file holding the value
+// by appending _FILE to its name, e.g. DB_PASSWORD_FILE=/run/secrets/db,
+// which is how Kubernetes secrets are usually mounted.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/logger"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is every setting of the blog
+type Config struct {
+	// Environment is informational, e.g. production or dev
+	Environment string `yaml:"environment"`
+	// Addr is the address the server listens on
+	Addr string `yaml:"addr"`
+	// MetricsAddr, when set, is the address /metrics is served on instead
+	// of Addr, so it can be kept off the public network
+	MetricsAddr string `yaml:"metrics_addr"`
+	APISecret   string `yaml:"api_secret"`
+	// ThemeDir holds templates overriding the embedded theme
+	ThemeDir string `yaml:"theme_dir"`
+
+	HTTP    HTTP    `yaml:"http"`
+	Log     Log     `yaml:"log"`
+	DB      DB      `yaml:"db"`
+	Storage Storage `yaml:"storage"`
+}
+
+// HTTP are the timeouts of the server, zero for none
+type HTTP struct {
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout bounds how long in-flight requests and background
+	// jobs get to finish on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// Log is how the server logs
+type Log struct {
+	// Level is debug, info, warn or error
+	Level string `yaml:"level"`
+	// Format is text or json
+	Format string `yaml:"format"`
+	// SQL logs every database query
+	SQL bool `yaml:"sql"`
+}
+
+// DB is the database to connect to
+type DB struct {
+	Driver   string `yaml:"driver"`
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+}
+
+// Storage is where uploaded media is kept
+type Storage struct {
+	Driver   string `yaml:"driver"`
+	LocalDir string `yaml:"local_dir"`
+	S3       S3     `yaml:"s3"`
+}
+
+// S3 is the bucket of the s3 storage driver
+type S3 struct {
+	Endpoint        string `yaml:"endpoint"`
+	Region          string `yaml:"region"`
+	Bucket          string `yaml:"bucket"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+}
+
+// Default returns the settings used when nothing else sets them
+func Default() Config {
+	return Config{
+		Addr: ":8080",
+		HTTP: HTTP{
+			ReadTimeout:       time.Minute,
+			ReadHeaderTimeout: 10 * time.Second,
+			WriteTimeout:      time.Minute,
+			IdleTimeout:       2 * time.Minute,
+			// below the 30s Kubernetes waits before killing a pod
+			ShutdownTimeout: 25 * time.Second,
+		},
+		Log:     Log{Level: "info", Format: "text"},
+		Storage: Storage{Driver: "local", LocalDir: "uploads"},
+	}
+}
+
+// setting ties a field of Config, a *string, *bool or *time.Duration, to its
+// environment variable. The flag is the variable in lower case with dashes,
+// e.g. -db-host for DB_HOST.
+type setting struct {
+	env   string
+	value interface{}
+}
+
+func (s setting) set(raw string) error {
+	switch value := s.value.(type) {
+	case *string:
+		*value = raw
+	case *bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", raw)
+		}
+		*value = b
+	case *time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration like 30s", raw)
+		}
+		*value = d
+	}
+
+	return nil
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"ENVIRONMENT", &c.Environment},
+		{"ADDR", &c.Addr},
+		{"METRICS_ADDR", &c.MetricsAddr},
+		{"API_SECRET", &c.APISecret},
+		{"THEME_DIR", &c.ThemeDir},
+		{"HTTP_READ_TIMEOUT", &c.HTTP.ReadTimeout},
+		{"HTTP_READ_HEADER_TIMEOUT", &c.HTTP.ReadHeaderTimeout},
+		{"HTTP_WRITE_TIMEOUT", &c.HTTP.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", &c.HTTP.IdleTimeout},
+		{"SHUTDOWN_TIMEOUT", &c.HTTP.ShutdownTimeout},
+		{"LOG_LEVEL", &c.Log.Level},
+		{"LOG_FORMAT", &c.Log.Format},
+		{"LOG_SQL", &c.Log.SQL},
+		{"DB_DRIVER", &c.DB.Driver},
+		{"DB_HOST", &c.DB.Host},
+		{"DB_PORT", &c.DB.Port},
+		{"DB_USER", &c.DB.User},
+		{"DB_PASSWORD", &c.DB.Password},
+		{"DB_NAME", &c.DB.Name},
+		{"STORAGE_DRIVER", &c.Storage.Driver},
+		{"STORAGE_LOCAL_DIR", &c.Storage.LocalDir},
+		{"S3_ENDPOINT", &c.Storage.S3.Endpoint},
+		{"S3_REGION", &c.Storage.S3.Region},
+		{"S3_BUCKET", &c.Storage.S3.Bucket},
+		{"S3_ACCESS_KEY_ID", &c.Storage.S3.AccessKeyID},
+		{"S3_SECRET_ACCESS_KEY", &c.Storage.S3.SecretAccessKey},
+	}
+}
+
+func flagName(env string) string {
+	return strings.ToLower(strings.Replace(env, "_", "-", -1))
+}
+
+// Flags are the command line overrides of the settings
+type Flags struct {
+	set    *flag.FlagSet
+	file   *string
+	values map[string]*string
+}
+
+// AddFlags registers -config and a flag for every setting on set
+func AddFlags(set *flag.FlagSet) *Flags {
+	f := &Flags{
+		set:    set,
+		file:   set.String("config", "", "YAML file with the settings, overrides CONFIG_FILE"),
+		values: map[string]*string{},
+	}
+
+	for _, s := range (&Config{}).settings() {
+		f.values[s.env] = set.String(flagName(s.env), "", "overrides "+s.env)
+	}
+
+	return f
+}
+
+// Load reads the settings from every source and validates them. flags may
+// be nil, then only the file, the environment and the defaults are used.
+func Load(flags *Flags) (*Config, error) {
+	err := godotenv.Load()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf(".env: %v", err)
+	}
+
+	c := Default()
+
+	file := os.Getenv("CONFIG_FILE")
+	if flags != nil && *flags.file != "" {
+		file = *flags.file
+	}
+
+	if file != "" {
+		err = c.readFile(file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	errs := ValidationError{}
+	c.readEnv(os.LookupEnv, errs)
+
+	if flags != nil {
+		flags.set.Visit(func(f *flag.Flag) {
+			for _, s := range c.settings() {
+				if f.Name != flagName(s.env) {
+					continue
+				}
+
+				err := s.set(*flags.values[s.env])
+				if err != nil {
+					errs.add(s.env, err.Error())
+				}
+			}
+		})
+	}
+
+	c.validate(errs)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return &c, nil
+}
+
+func (c *Config) readFile(name string) error {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	err = yaml.UnmarshalStrict(data, c)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	return nil
+}
+
+// readEnv sets the settings given in the environment, reading the ones
+// given as _FILE from their file
+func (c *Config) readEnv(lookup func(string) (string, bool), errs ValidationError) {
+	for _, s := range c.settings() {
+		value, ok := lookup(s.env)
+		file, fromFile := lookup(s.env + "_FILE")
+
+		if ok && fromFile {
+			errs.add(s.env, "is set along with "+s.env+"_FILE, set only one")
+			continue
+		}
+
+		if fromFile {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				errs.add(s.env+"_FILE", err.Error())
+				continue
+			}
+			value, ok = strings.TrimRight(string(data), "\r\n"), true
+		}
+
+		if !ok {
+			continue
+		}
+
+		err := s.set(value)
+		if err != nil {
+			errs.add(s.env, err.Error())
+		}
+	}
+}
+
+func (c *Config) validate(errs ValidationError) {
+	if c.APISecret == "" {
+		errs.add("API_SECRET", "is required")
+	}
+
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		errs.add("ADDR", fmt.Sprintf("%q is not a host:port address", c.Addr))
+	}
+
+	if c.MetricsAddr != "" {
+		if _, _, err := net.SplitHostPort(c.MetricsAddr); err != nil {
+			errs.add("METRICS_ADDR", fmt.Sprintf("%q is not a host:port address", c.MetricsAddr))
+		}
+	}
+
+	timeouts := map[string]time.Duration{
+		"HTTP_READ_TIMEOUT":        c.HTTP.ReadTimeout,
+		"HTTP_READ_HEADER_TIMEOUT": c.HTTP.ReadHeaderTimeout,
+		"HTTP_WRITE_TIMEOUT":       c.HTTP.WriteTimeout,
+		"HTTP_IDLE_TIMEOUT":        c.HTTP.IdleTimeout,
+		"SHUTDOWN_TIMEOUT":         c.HTTP.ShutdownTimeout,
+	}
+	for key, value := range timeouts {
+		if value < 0 {
+			errs.add(key, "cannot be negative")
+		}
+	}
+
+	if _, err := logger.ParseLevel(c.Log.Level); err != nil {
+		errs.add("LOG_LEVEL", err.Error())
+	}
+
+	if !logger.ValidFormat(c.Log.Format) {
+		errs.add("LOG_FORMAT", fmt.Sprintf("%q is not text or json", c.Log.Format))
+	}
+
+	switch c.DB.Driver {
+	case "mysql", "postgres":
+		required := map[string]string{"DB_HOST": c.DB.Host, "DB_USER": c.DB.User, "DB_NAME": c.DB.Name}
+		for key, value := range required {
+			if value == "" {
+				errs.add(key, "is required")
+			}
+		}
+
+		if port, err := strconv.Atoi(c.DB.Port); err != nil || port < 1 || port > 65535 {
+			errs.add("DB_PORT", fmt.Sprintf("%q is not a port number", c.DB.Port))
+		}
+	case "sqlite3":
+		// the database is a file, or :memory:
+		if c.DB.Name == "" {
+			errs.add("DB_NAME", "is required, a file name or :memory:")
+		}
+	case "":
+		errs.add("DB_DRIVER", "is required")
+	default:
+		errs.add("DB_DRIVER", fmt.Sprintf("%q is not mysql, postgres or sqlite3", c.DB.Driver))
+	}
+
+	switch c.Storage.Driver {
+	case "local":
+		if c.Storage.LocalDir == "" {
+			errs.add("STORAGE_LOCAL_DIR", "is required for local storage")
+		}
+	case "s3":
+		if c.Storage.S3.Endpoint == "" {
+			errs.add("S3_ENDPOINT", "is required for s3 storage")
+		}
+		if c.Storage.S3.Bucket == "" {
+			errs.add("S3_BUCKET", "is required for s3 storage")
+		}
+	default:
+		errs.add("STORAGE_DRIVER", fmt.Sprintf("%q is not local or s3", c.Storage.Driver))
+	}
+}
+
+// ValidationError lists every invalid setting, keyed by its environment
+// variable
+type ValidationError map[string]string
+
+func (e ValidationError) add(key, problem string) {
+	if _, ok := e[key]; !ok {
+		e[key] = problem
+	}
+}
+
+func (e ValidationError) Error() string {
+	keys := []string{}
+	for key := range e {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	problems := []string{}
+	for _, key := range keys {
+		problems = append(problems, key+" "+e[key])
+	}
+
+	return "Invalid configuration: " + strings.Join(problems, "; ")
+}
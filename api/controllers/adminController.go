@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/dmdinh22/go-blog/api/responses"
+	"github.com/dmdinh22/go-blog/api/utils/formaterror"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+)
+
+type roleRequest struct {
+	Role string `json:"role"`
+}
+
+// UpdateUserRole godoc
+// @Summary Change a user's role
+// @Description Grants a user the admin, editor or user role. Requires the users:manage permission.
+// @Tags admin
+// @Param id path int true "User ID"
+// @Param role body controllers.roleRequest true "new role"
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.User
+// @Router /api/admin/users/{id}/role [put]
+func (server *Server) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uid, err := strconv.ParseUint(vars["id"], 10, 32)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	request := roleRequest{}
+	err = json.Unmarshal(body, &request)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	updatedUser, err := server.users().UpdateRole(uint32(uid), request.Role)
+
+	if gorm.IsRecordNotFoundError(err) {
+		responses.ERROR(w, http.StatusNotFound, errors.New("User not found"))
+		return
+	}
+
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, updatedUser)
+}
+
+// AdminDeleteUser godoc
+// @Summary Delete any user
+// @Description Deletes a user and their posts. Requires the users:manage permission.
+// @Tags admin
+// @Param id path int true "User ID"
+// @Accept  json
+// @Produce  json
+// @Success 204
+// @Router /api/admin/users/{id} [delete]
+func (server *Server) AdminDeleteUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uid, err := strconv.ParseUint(vars["id"], 10, 32)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	_, err = server.users().Delete(uint32(uid))
+
+	if gorm.IsRecordNotFoundError(err) {
+		responses.ERROR(w, http.StatusNotFound, errors.New("User not found"))
+		return
+	}
+
+	if err != nil {
+		formattedError := formaterror.FormatError(err.Error())
+		responses.ERROR(w, http.StatusInternalServerError, formattedError)
+		return
+	}
+
+	w.Header().Set("Entity", fmt.Sprintf("%d", uid))
+	responses.JSON(w, http.StatusNoContent, "")
+}
@@ -1,64 +1,320 @@
 package controllers
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/jinzhu/gorm"
 
 	_ "github.com/jinzhu/gorm/dialects/mysql"    //mysql db driver
 	_ "github.com/jinzhu/gorm/dialects/postgres" //postgres db driver
+	_ "github.com/jinzhu/gorm/dialects/sqlite"   //sqlite db driver
+	_ "github.com/mattn/go-sqlite3"              //the sqlite driver OpenDB passes _foreign_keys to
 
+	"github.com/dmdinh22/go-blog/api/auth"
+	"github.com/dmdinh22/go-blog/api/config"
+	"github.com/dmdinh22/go-blog/api/frontend"
+	"github.com/dmdinh22/go-blog/api/logger"
+	"github.com/dmdinh22/go-blog/api/middlewares"
+	"github.com/dmdinh22/go-blog/api/migrations"
 	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/dmdinh22/go-blog/api/storage"
 )
 
 type Server struct {
 	DB     *gorm.DB
 	Router *mux.Router
+	Search models.PostSearcher
+
+	// Posts and Users keep the posts and users, in the database unless
+	// set to other repositories
+	Posts models.PostRepository
+	Users models.UserRepository
+
+	// Storage keeps the files of uploaded media
+	Storage storage.ObjectStore
+
+	// MetricsAddr, when set, is the address Run serves /metrics on instead
+	// of the router
+	MetricsAddr string
+
+	// Theme renders the pages of the public frontend
+	Theme *frontend.Theme
+
+	// Scheduler publishes scheduled posts while the server runs
+	Scheduler *PublishScheduler
+
+	// HealthCheckTimeout bounds each readiness check, see CheckHealth
+	HealthCheckTimeout time.Duration
+
+	healthMu       sync.Mutex
+	healthCheckers []HealthChecker
+}
+
+// OpenDB connects to the mysql, postgres or sqlite3 database. The sqlite3
+// database is the file DbName, or in memory when it is :memory:.
+func OpenDB(Dbdriver, DbUser, DbPassword, DbPort, DbHost, DbName string) (*gorm.DB, error) {
+	var DBURL string
+
+	switch Dbdriver {
+	case "mysql":
+		DBURL = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True&loc=Local", DbUser, DbPassword, DbHost, DbPort, DbName)
+	case "postgres":
+		DBURL = fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=disable password=%s", DbHost, DbPort, DbUser, DbName, DbPassword)
+	case "sqlite3":
+		DBURL = DbName + "?_foreign_keys=1"
+	default:
+		return nil, fmt.Errorf("Unsupported database driver %q, use mysql, postgres or sqlite3", Dbdriver)
+	}
+
+	db, err := gorm.Open(Dbdriver, DBURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// every connection to :memory: opens a database of its own
+	if Dbdriver == "sqlite3" && DbName == ":memory:" {
+		db.DB().SetMaxOpenConns(1)
+	}
+
+	return db, nil
+}
+
+// OpenStorage builds the media store of the configuration
+func OpenStorage(cfg *config.Config) (storage.ObjectStore, error) {
+	return storage.Open(storage.Config{
+		Driver:            cfg.Storage.Driver,
+		LocalDir:          cfg.Storage.LocalDir,
+		S3Endpoint:        cfg.Storage.S3.Endpoint,
+		S3Region:          cfg.Storage.S3.Region,
+		S3Bucket:          cfg.Storage.S3.Bucket,
+		S3AccessKeyID:     cfg.Storage.S3.AccessKeyID,
+		S3SecretAccessKey: cfg.Storage.S3.SecretAccessKey,
+	})
 }
 
 //	  the receiver
-func (server *Server) Initialize(Dbdriver, DbUser, DbPassword, DbPort, DbHost, DbName string) {
+func (server *Server) Initialize(cfg *config.Config) {
 	var err error
 
-	// api supports both mysql and postgresql - define which in env
-	if Dbdriver == "mysql" {
-		DBURL := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True&loc=Local", DbUser, DbPassword, DbHost, DbPort, DbName)
+	// api supports mysql, postgresql and sqlite - define which in config
+	server.DB, err = OpenDB(cfg.DB.Driver, cfg.DB.User, cfg.DB.Password, cfg.DB.Port, cfg.DB.Host, cfg.DB.Name)
 
-		server.DB, err = gorm.Open(Dbdriver, DBURL)
+	if err != nil {
+		logger.Default().Fatal("Cannot connect to the database", "driver", cfg.DB.Driver, "error", err)
+	}
 
-		if err != nil {
-			fmt.Printf("Cannot connect to %s database", Dbdriver)
-			log.Fatal("This is the error:", err)
-		} else {
-			fmt.Printf("We are connected to the %s database", Dbdriver)
-		}
+	logger.Default().Info("Connected to the database", "driver", cfg.DB.Driver)
+	SetDBLogger(server.DB, cfg)
+
+	server.Posts = models.NewGormPostRepository(server.DB)
+	server.Users = models.NewGormUserRepository(server.DB)
+
+	// full-text search uses the native index of the driver
+	server.Search = models.NewPostSearcher(cfg.DB.Driver)
+
+	server.Storage, err = OpenStorage(cfg)
+
+	if err != nil {
+		logger.Default().Fatal("Cannot set up storage", "driver", cfg.Storage.Driver, "error", err)
 	}
 
-	if Dbdriver == "postgres" {
-		DBURL := fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=disable password=%s", DbHost, DbPort, DbUser, DbName, DbPassword)
+	// the theme dir overrides templates of the embedded theme
+	server.Theme, err = frontend.Load(cfg.ThemeDir)
+
+	if err != nil {
+		logger.Default().Fatal("Cannot load theme", "dir", cfg.ThemeDir, "error", err)
+	}
 
-		server.DB, err = gorm.Open(Dbdriver, DBURL)
+	auth.SetSecret(cfg.APISecret)
 
-		if err != nil {
-			fmt.Printf("Cannot connect to %s db", Dbdriver)
-			log.Fatal("DB error:", err)
-		} else {
-			fmt.Printf("Successfully connected to the %s db", Dbdriver)
-		}
+	server.Scheduler = NewPublishScheduler(server.DB, DefaultSchedulerInterval)
+
+	migrator, err := migrations.New(server.DB.DB(), server.DB.Dialect().GetName())
+
+	if err != nil {
+		logger.Default().Fatal("Cannot load migrations", "driver", cfg.DB.Driver, "error", err)
 	}
 
-	// run db migration
-	server.DB.Debug().AutoMigrate(&models.User{}, &models.Post{})
+	// the server is ready once these pass, see GetReadiness
+	server.RegisterHealthChecker(NewDatabaseHealthChecker(server.DB))
+	server.RegisterHealthChecker(migrator)
+	server.RegisterHealthChecker(server.Scheduler)
+
+	// share access token revocations between replicas
+	auth.SetDenylist(models.NewTokenDenylist(server.DB))
 
+	server.MetricsAddr = cfg.MetricsAddr
 	server.Router = mux.NewRouter()
 
 	server.initializeRoutes()
 }
 
-func (server *Server) Run(addr string) {
-	fmt.Println("Listening on port 8080. 🚀")
-	log.Fatal(http.ListenAndServe(addr, server.Router))
+// posts returns the post repository, the database when none is set
+func (server *Server) posts() models.PostRepository {
+	if server.Posts == nil {
+		return models.NewGormPostRepository(server.DB)
+	}
+
+	return server.Posts
+}
+
+// users returns the user repository, the database when none is set
+func (server *Server) users() models.UserRepository {
+	if server.Users == nil {
+		return models.NewGormUserRepository(server.DB)
+	}
+
+	return server.Users
+}
+
+// NewDatabaseHealthChecker pings db
+func NewDatabaseHealthChecker(db *gorm.DB) HealthChecker {
+	return NewHealthChecker("database", func(ctx context.Context) error {
+		return db.DB().PingContext(ctx)
+	})
+}
+
+// SetDBLogger sends the errors of db to the default logger, and every query
+// when cfg.Log.SQL is set
+func SetDBLogger(db *gorm.DB, cfg *config.Config) {
+	db.SetLogger(logger.SQLLogger{})
+
+	if cfg.Log.SQL {
+		db.LogMode(true)
+	}
+}
+
+// Handler is the router wrapped in the middlewares every request goes
+// through: request IDs, the access log and the request metrics
+func (server *Server) Handler() http.Handler {
+	return middlewares.SetMiddlewareRequestID(middlewares.SetMiddlewareAccessLog(middlewares.SetMiddlewareMetrics(server.Router)))
+}
+
+// Run listens on cfg.Addr and serves until ctx is done, see Serve. The
+// metrics are served on MetricsAddr when it is set.
+func (server *Server) Run(ctx context.Context, cfg *config.Config) error {
+	listener, err := net.Listen("tcp", cfg.Addr)
+
+	if err != nil {
+		return err
+	}
+
+	if server.MetricsAddr != "" {
+		metricsListener, err := net.Listen("tcp", server.MetricsAddr)
+
+		if err != nil {
+			listener.Close()
+			return err
+		}
+
+		metricsServer := server.serveMetrics(metricsListener, cfg.HTTP)
+		defer metricsServer.Close()
+	}
+
+	return server.Serve(ctx, listener, cfg.HTTP)
+}
+
+// serveMetrics serves /metrics alone on listener until the returned server
+// is closed
+func (server *Server) serveMetrics(listener net.Listener, timeouts config.HTTP) *http.Server {
+	router := http.NewServeMux()
+	router.HandleFunc("/metrics", server.GetMetrics)
+
+	metricsServer := &http.Server{
+		Handler:           router,
+		ReadTimeout:       timeouts.ReadTimeout,
+		ReadHeaderTimeout: timeouts.ReadHeaderTimeout,
+		WriteTimeout:      timeouts.WriteTimeout,
+		IdleTimeout:       timeouts.IdleTimeout,
+	}
+
+	go metricsServer.Serve(listener)
+
+	logger.Default().Info("Serving metrics", "addr", listener.Addr().String())
+	return metricsServer
+}
+
+// Serve serves on listener and runs the background jobs until ctx is done.
+// It then stops the jobs and accepting connections, gives both until the
+// shutdown timeout to finish and closes the database.
+func (server *Server) Serve(ctx context.Context, listener net.Listener, timeouts config.HTTP) error {
+	httpServer := &http.Server{
+		Handler:           server.Handler(),
+		ReadTimeout:       timeouts.ReadTimeout,
+		ReadHeaderTimeout: timeouts.ReadHeaderTimeout,
+		WriteTimeout:      timeouts.WriteTimeout,
+		IdleTimeout:       timeouts.IdleTimeout,
+	}
+
+	if server.Scheduler != nil {
+		server.Scheduler.Start()
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- httpServer.Serve(listener)
+	}()
+
+	logger.Default().Info("Listening", "addr", listener.Addr().String())
+
+	var err error
+	select {
+	case err = <-served:
+		// the listener failed, still stop the jobs and close the database
+	case <-ctx.Done():
+		logger.Default().Info("Shutting down, waiting for requests to finish", "timeout", timeouts.ShutdownTimeout)
+	}
+
+	shutdownCtx := context.Background()
+	if timeouts.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, timeouts.ShutdownTimeout)
+		defer cancel()
+	}
+
+	// the scheduler is stopped first, so requests slow to finish cannot use
+	// up the time its last run needs; Shutdown below reports it timing out
+	if server.Scheduler != nil {
+		server.Scheduler.Shutdown(shutdownCtx)
+	}
+
+	if shutdownErr := httpServer.Shutdown(shutdownCtx); shutdownErr != nil {
+		// out of time, drop the connections still open before the database
+		// they use is closed
+		httpServer.Close()
+
+		if err == nil {
+			err = shutdownErr
+		}
+	}
+
+	if shutdownErr := server.Shutdown(shutdownCtx); err == nil {
+		err = shutdownErr
+	}
+
+	return err
+}
+
+// Shutdown stops the background jobs, waiting for them until ctx is done,
+// and closes the database. A job still running then keeps the database
+// open, the process exits without closing it.
+func (server *Server) Shutdown(ctx context.Context) error {
+	if server.Scheduler != nil {
+		err := server.Scheduler.Shutdown(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if server.DB != nil {
+		return server.DB.Close()
+	}
+
+	return nil
 }
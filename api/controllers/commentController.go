@@ -0,0 +1,269 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/dmdinh22/go-blog/api/auth"
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/dmdinh22/go-blog/api/responses"
+	"github.com/gorilla/mux"
+)
+
+// CreateComment godoc
+// @Summary Comment on a post
+// @Description Adds a comment to a post, or a reply when parentId is given
+// @Tags comments
+// @Param id path int true "post ID"
+// @Param comment body models.Comment true "content and optional parentId"
+// @Accept  json
+// @Produce  json
+// @Success 201 {object} models.Comment
+// @Router /api/posts/{id}/comments [post]
+func (server *Server) CreateComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	pid, err := strconv.ParseUint(vars["id"], 10, 64)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	principal, ok := auth.FromContext(r.Context())
+
+	if !ok {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+
+	// Check if post exists
+	post, err := server.posts().GetByID(pid)
+
+	if err != nil || !post.VisibleTo(viewerID(r)) {
+		responses.ERROR(w, http.StatusNotFound, errors.New("Post not found"))
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	comment := models.Comment{}
+	err = json.Unmarshal(body, &comment)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	comment.Prepare()
+	comment.PostID = post.ID
+	comment.AuthorID = principal.UserID
+	err = comment.Validate()
+
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	createdComment, err := comment.SaveComment(server.DB)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s%s/%d", r.Host, r.URL.Path, createdComment.ID))
+	responses.JSON(w, http.StatusCreated, createdComment)
+}
+
+// GetComments godoc
+// @Summary Get the comments of a post
+// @Description Get the comments of a post as a tree of replies, oldest first
+// @Tags comments
+// @Param id path int true "post ID"
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} models.Comment
+// @Router /api/posts/{id}/comments [get]
+func (server *Server) GetComments(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	pid, err := strconv.ParseUint(vars["id"], 10, 64)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	post, err := server.posts().GetByID(pid)
+
+	if err != nil || !post.VisibleTo(viewerID(r)) {
+		responses.ERROR(w, http.StatusNotFound, errors.New("Post not found"))
+		return
+	}
+
+	comment := models.Comment{}
+	comments, err := comment.GetCommentsByPostID(server.DB, pid)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, comments)
+}
+
+// UpdateComment godoc
+// @Summary Edit a comment
+// @Description Edit the content of a comment. Only its author may edit it.
+// @Tags comments
+// @Param id path int true "post ID"
+// @Param commentId path int true "comment ID"
+// @Param comment body models.Comment true "new content"
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.Comment
+// @Router /api/posts/{id}/comments/{commentId} [put]
+func (server *Server) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	pid, err := strconv.ParseUint(vars["id"], 10, 64)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cid, err := strconv.ParseUint(vars["commentId"], 10, 64)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	principal, ok := auth.FromContext(r.Context())
+
+	if !ok {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+
+	// Check if comment exists
+	comment := models.Comment{}
+	_, err = comment.GetCommentByID(server.DB, pid, cid)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusNotFound, errors.New("Comment not found"))
+		return
+	}
+
+	// Check if authorized author
+	if principal.UserID != comment.AuthorID {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	commentUpdate := models.Comment{}
+	err = json.Unmarshal(body, &commentUpdate)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	commentUpdate.Prepare()
+	commentUpdate.ID = comment.ID
+	commentUpdate.PostID = comment.PostID
+	commentUpdate.AuthorID = comment.AuthorID
+	err = commentUpdate.Validate()
+
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	updatedComment, err := commentUpdate.UpdateComment(server.DB)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, updatedComment)
+}
+
+// DeleteComment godoc
+// @Summary Delete a comment
+// @Description Delete a comment and its replies. Only its author or a moderator may delete it.
+// @Tags comments
+// @Param id path int true "post ID"
+// @Param commentId path int true "comment ID"
+// @Accept  json
+// @Produce  json
+// @Success 204
+// @Router /api/posts/{id}/comments/{commentId} [delete]
+func (server *Server) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	pid, err := strconv.ParseUint(vars["id"], 10, 64)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cid, err := strconv.ParseUint(vars["commentId"], 10, 64)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	principal, ok := auth.FromContext(r.Context())
+
+	if !ok {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+
+	comment := models.Comment{}
+	_, err = comment.GetCommentByID(server.DB, pid, cid)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusNotFound, errors.New("Comment not found"))
+		return
+	}
+
+	if principal.UserID != comment.AuthorID && !principal.Can(auth.PermCommentsDeleteAny) {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+
+	deleted, err := comment.DeleteComment(server.DB, cid)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// deleted meanwhile, e.g. along with the comment it replied to
+	if deleted == 0 {
+		responses.ERROR(w, http.StatusNotFound, errors.New("Comment not found"))
+		return
+	}
+
+	w.Header().Set("Entity", fmt.Sprintf("%d", cid))
+	responses.JSON(w, http.StatusNoContent, "")
+}
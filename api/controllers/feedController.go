@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/feeds"
+	"github.com/dmdinh22/go-blog/api/frontend"
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/dmdinh22/go-blog/api/responses"
+	"github.com/gorilla/mux"
+)
+
+// GetFeed godoc
+// @Summary Feed of the latest posts
+// @Description The newest published posts as RSS 2.0, Atom 1.0 or JSON Feed 1.1. Supports conditional requests with If-None-Match and If-Modified-Since.
+// @Tags feeds
+// @Param format path string true "rss, atom or json"
+// @Produce  xml
+// @Produce  json
+// @Success 200 {string} string
+// @Success 304
+// @Router /feed.{format} [get]
+func (server *Server) GetFeed(w http.ResponseWriter, r *http.Request) {
+	posts, err := models.GetFeedPosts(server.DB, 0, models.DefaultFeedLength)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	feed := feeds.Feed{
+		Title:       frontend.SiteTitle,
+		Description: frontend.SiteDescription,
+		Link:        baseURL(r) + "/",
+	}
+
+	server.writeFeed(w, r, feed, *posts)
+}
+
+// GetUserFeed godoc
+// @Summary Feed of the latest posts of a user
+// @Description The newest published posts of one author as RSS 2.0, Atom 1.0 or JSON Feed 1.1. Supports conditional requests with If-None-Match and If-Modified-Since.
+// @Tags feeds
+// @Param id path int true "user ID"
+// @Param format path string true "rss, atom or json"
+// @Produce  xml
+// @Produce  json
+// @Success 200 {string} string
+// @Success 304
+// @Router /api/users/{id}/feed.{format} [get]
+func (server *Server) GetUserFeed(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uid, err := strconv.ParseUint(vars["id"], 10, 32)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := server.users().GetByID(uint32(uid))
+
+	if err != nil {
+		responses.ERROR(w, http.StatusNotFound, errors.New("User not found"))
+		return
+	}
+
+	posts, err := models.GetFeedPosts(server.DB, user.ID, models.DefaultFeedLength)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	feed := feeds.Feed{
+		Title:       fmt.Sprintf("%s - %s", frontend.SiteTitle, html.UnescapeString(user.Username)),
+		Description: fmt.Sprintf("The latest posts by %s", html.UnescapeString(user.Username)),
+		Link:        fmt.Sprintf("%s/authors/%d", baseURL(r), user.ID),
+	}
+
+	server.writeFeed(w, r, feed, *posts)
+}
+
+// writeFeed adds the posts to the feed and writes it in the format of the
+// route, or answers 304 when the client already has this version.
+func (server *Server) writeFeed(w http.ResponseWriter, r *http.Request, feed feeds.Feed, posts []models.Post) {
+	format := mux.Vars(r)["format"]
+	contentType, ok := feeds.ContentTypes[format]
+
+	if !ok {
+		responses.ERROR(w, http.StatusNotFound, feeds.ErrInvalidFormat)
+		return
+	}
+
+	// the feed changes when a post in it is edited, or when posts join or
+	// leave it
+	version := sha256.New()
+	fmt.Fprintf(version, "%s %s\n", format, r.URL.Path)
+
+	for _, post := range posts {
+		fmt.Fprintf(version, "%d %d\n", post.ID, post.UpdatedAt.UnixNano())
+	}
+
+	feed.Updated = feeds.LastUpdated(posts)
+	etag := fmt.Sprintf(`W/"%s"`, hex.EncodeToString(version.Sum(nil))[:32])
+	w.Header().Set("ETag", etag)
+
+	// an empty feed has no modification time to give
+	if !feed.Updated.IsZero() {
+		w.Header().Set("Last-Modified", feed.Updated.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, feed.Updated) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	base := baseURL(r)
+	feed.FeedURL = base + r.URL.Path
+
+	var err error
+	feed.Items, err = feeds.PostItems(base, posts)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	body, err := feeds.Write(feed, format)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// notModified reports whether the client's copy, named by If-None-Match or
+// dated by If-Modified-Since, is current. If-None-Match wins when both are
+// sent.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || modified.IsZero() {
+		return false
+	}
+
+	// HTTP dates have whole seconds
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// baseURL is the scheme and host the request was made to
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+
+	return scheme + "://" + r.Host
+}
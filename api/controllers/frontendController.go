@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/dmdinh22/go-blog/api/frontend"
+	"github.com/dmdinh22/go-blog/api/logger"
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/dmdinh22/go-blog/api/render"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+)
+
+// errNoSuchPage is returned by listPosts when the cursor or filters of the
+// page are invalid
+var errNoSuchPage = errors.New("This page does not exist.")
+
+// Index renders the front page, the newest published posts first. Older
+// posts are paged with ?cursor= and can be narrowed down with ?tag=.
+func (server *Server) Index(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := models.PostQuery{
+		Limit:  frontend.PageSize,
+		Cursor: params.Get("cursor"),
+		Tags:   params["tag"],
+	}
+
+	posts, next, err := server.listPosts(query)
+
+	if err == errNoSuchPage {
+		server.renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		server.renderError(w, http.StatusInternalServerError, "Something went wrong.")
+		logger.FromContext(r.Context()).Error("Cannot list posts", "error", err)
+		return
+	}
+
+	data := frontend.PageData{
+		Site:     frontend.SiteTitle,
+		Feed:     "/feed.atom",
+		Posts:    posts,
+		NextPage: nextPage(r, next),
+	}
+
+	server.renderPage(w, http.StatusOK, frontend.PageIndex, data)
+}
+
+// PostPage renders a published post. Old slugs redirect to the current one.
+func (server *Server) PostPage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	postRetrieved, err := server.posts().GetBySlug(vars["slug"])
+
+	if gorm.IsRecordNotFoundError(err) {
+		movedPost, err := server.posts().GetByOldSlug(vars["slug"])
+
+		if err != nil || !movedPost.VisibleTo(0) {
+			server.renderError(w, http.StatusNotFound, "This post does not exist.")
+			return
+		}
+
+		http.Redirect(w, r, frontend.PostPath(movedPost.Slug), http.StatusMovedPermanently)
+		return
+	}
+
+	if err != nil {
+		server.renderError(w, http.StatusInternalServerError, "Something went wrong.")
+		logger.FromContext(r.Context()).Error("Cannot get post", "slug", vars["slug"], "error", err)
+		return
+	}
+
+	if !postRetrieved.VisibleTo(0) {
+		server.renderError(w, http.StatusNotFound, "This post does not exist.")
+		return
+	}
+
+	err = postRetrieved.Render(render.FormatHTML)
+
+	if err != nil {
+		server.renderError(w, http.StatusInternalServerError, "Something went wrong.")
+		logger.FromContext(r.Context()).Error("Cannot render post", "post_id", postRetrieved.ID, "error", err)
+		return
+	}
+
+	data := frontend.PageData{
+		Site:  frontend.SiteTitle,
+		Title: html.UnescapeString(postRetrieved.Title),
+		Feed:  fmt.Sprintf("/api/users/%d/feed.atom", postRetrieved.AuthorID),
+		Post:  *postRetrieved,
+	}
+
+	server.renderPage(w, http.StatusOK, frontend.PagePost, data)
+}
+
+// AuthorPage renders the published posts of a user, newest first
+func (server *Server) AuthorPage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uid, err := strconv.ParseUint(vars["id"], 10, 32)
+
+	if err != nil {
+		server.renderError(w, http.StatusNotFound, "This author does not exist.")
+		return
+	}
+
+	user, err := server.users().GetByID(uint32(uid))
+
+	if err != nil {
+		server.renderError(w, http.StatusNotFound, "This author does not exist.")
+		return
+	}
+
+	query := models.PostQuery{
+		Limit:    frontend.PageSize,
+		Cursor:   r.URL.Query().Get("cursor"),
+		AuthorID: user.ID,
+	}
+
+	posts, next, err := server.listPosts(query)
+
+	if err == errNoSuchPage {
+		server.renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		server.renderError(w, http.StatusInternalServerError, "Something went wrong.")
+		logger.FromContext(r.Context()).Error("Cannot list posts", "error", err)
+		return
+	}
+
+	data := frontend.PageData{
+		Site:     frontend.SiteTitle,
+		Title:    html.UnescapeString(user.Username),
+		Feed:     fmt.Sprintf("/api/users/%d/feed.atom", user.ID),
+		Posts:    posts,
+		NextPage: nextPage(r, next),
+		Author:   *user,
+	}
+
+	server.renderPage(w, http.StatusOK, frontend.PageAuthor, data)
+}
+
+// listPosts returns a page of published posts with their excerpts, or
+// errNoSuchPage when the query asks for a page that cannot exist
+func (server *Server) listPosts(query models.PostQuery) ([]models.Post, string, error) {
+	query.Status = models.PostStatusPublished
+
+	err := query.Validate()
+	if err != nil {
+		return nil, "", errNoSuchPage
+	}
+
+	posts, next, err := server.posts().Find(query)
+	if err == models.ErrInvalidCursor {
+		return nil, "", errNoSuchPage
+	}
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = models.RenderPosts(*posts, render.FormatPlain)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for i := range *posts {
+		(*posts)[i].ContentText = render.Excerpt((*posts)[i].ContentText, frontend.ExcerptLength)
+	}
+
+	return *posts, next, nil
+}
+
+// nextPage is the current page moved on to the cursor, empty without one
+func nextPage(r *http.Request, cursor string) string {
+	if cursor == "" {
+		return ""
+	}
+
+	params := r.URL.Query()
+	params.Set("cursor", cursor)
+
+	return (&url.URL{Path: r.URL.Path, RawQuery: params.Encode()}).String()
+}
+
+func (server *Server) renderPage(w http.ResponseWriter, status int, page string, data frontend.PageData) {
+	err := server.Theme.Render(w, status, page, data)
+
+	if err != nil {
+		logger.Default().Error("Cannot render page", "page", page, "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+func (server *Server) renderError(w http.ResponseWriter, status int, message string) {
+	data := frontend.PageData{
+		Site:    frontend.SiteTitle,
+		Title:   http.StatusText(status),
+		Status:  status,
+		Message: message,
+	}
+
+	server.renderPage(w, status, frontend.PageError, data)
+}
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/responses"
+)
+
+// DefaultHealthCheckTimeout bounds how long a readiness check may take
+const DefaultHealthCheckTimeout = 2 * time.Second
+
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusError       = "error"
+	HealthStatusUnavailable = "unavailable"
+)
+
+// HealthChecker is a dependency the server needs to serve requests, e.g.
+// the database. Subsystems register theirs with RegisterHealthChecker and
+// the server is ready once every check passes.
+type HealthChecker interface {
+	// Name identifies the check in the readiness report
+	Name() string
+	// Check returns an error when the dependency is not usable. It should
+	// give up once ctx is done.
+	Check(ctx context.Context) error
+}
+
+type healthCheckFunc struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+func (h healthCheckFunc) Name() string {
+	return h.name
+}
+
+func (h healthCheckFunc) Check(ctx context.Context) error {
+	return h.check(ctx)
+}
+
+// NewHealthChecker turns a function into a HealthChecker named name
+func NewHealthChecker(name string, check func(ctx context.Context) error) HealthChecker {
+	return healthCheckFunc{name: name, check: check}
+}
+
+// RegisterHealthChecker adds a check to the readiness report
+func (server *Server) RegisterHealthChecker(checker HealthChecker) {
+	server.healthMu.Lock()
+	defer server.healthMu.Unlock()
+	server.healthCheckers = append(server.healthCheckers, checker)
+}
+
+// HealthCheckResult is the outcome of one check
+type HealthCheckResult struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// HealthReport is the body of the health endpoints
+type HealthReport struct {
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks,omitempty"`
+}
+
+// GetHealth godoc
+// @Summary Liveness of the server
+// @Description Answers as long as the process serves requests, without checking its dependencies
+// @Tags health
+// @Produce  json
+// @Success 200 {object} controllers.HealthReport
+// @Router /healthz [get]
+func (server *Server) GetHealth(w http.ResponseWriter, r *http.Request) {
+	responses.JSON(w, http.StatusOK, HealthReport{Status: HealthStatusOK})
+}
+
+// GetReadiness godoc
+// @Summary Readiness of the server
+// @Description Runs the registered checks, e.g. the database, the migrations and the publish scheduler, and reports each of them
+// @Tags health
+// @Produce  json
+// @Success 200 {object} controllers.HealthReport
+// @Failure 503 {object} controllers.HealthReport
+// @Router /readyz [get]
+func (server *Server) GetReadiness(w http.ResponseWriter, r *http.Request) {
+	report := server.CheckHealth(r.Context())
+
+	status := http.StatusOK
+	if report.Status != HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	responses.JSON(w, status, report)
+}
+
+// CheckHealth runs the registered checks concurrently, each for at most
+// HealthCheckTimeout
+func (server *Server) CheckHealth(ctx context.Context) HealthReport {
+	server.healthMu.Lock()
+	checkers := append([]HealthChecker{}, server.healthCheckers...)
+	server.healthMu.Unlock()
+
+	timeout := server.HealthCheckTimeout
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+
+	type result struct {
+		name string
+		HealthCheckResult
+	}
+
+	results := make(chan result, len(checkers))
+	for _, checker := range checkers {
+		go func(checker HealthChecker) {
+			start := time.Now()
+			err := runHealthCheck(ctx, checker, timeout)
+
+			res := result{name: checker.Name(), HealthCheckResult: HealthCheckResult{Status: HealthStatusOK}}
+			if err != nil {
+				res.Status = HealthStatusError
+				res.Error = err.Error()
+			}
+			res.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+
+			results <- res
+		}(checker)
+	}
+
+	report := HealthReport{Status: HealthStatusOK, Checks: map[string]HealthCheckResult{}}
+	for range checkers {
+		res := <-results
+		report.Checks[res.name] = res.HealthCheckResult
+
+		if res.Status != HealthStatusOK {
+			report.Status = HealthStatusUnavailable
+		}
+	}
+
+	return report
+}
+
+// runHealthCheck returns once the check is done or timed out, whether or
+// not the check gives up on its context
+func runHealthCheck(ctx context.Context, checker HealthChecker, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- checker.Check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("Timed out after %v", timeout)
+		}
+		return ctx.Err()
+	}
+}
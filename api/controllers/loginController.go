@@ -4,12 +4,12 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/dmdinh22/go-blog/api/auth"
 	"github.com/dmdinh22/go-blog/api/models"
 	"github.com/dmdinh22/go-blog/api/responses"
 	"github.com/dmdinh22/go-blog/api/utils/formaterror"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // Login godoc
@@ -24,12 +24,14 @@ import (
 func (server *Server) Login(w http.ResponseWriter, r *http.Request) {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
+		loginAttempts.WithLabelValues("invalid").Inc()
 		responses.ERROR(w, http.StatusUnprocessableEntity, err)
 		return
 	}
 	user := models.User{}
 	err = json.Unmarshal(body, &user)
 	if err != nil {
+		loginAttempts.WithLabelValues("invalid").Inc()
 		responses.ERROR(w, http.StatusUnprocessableEntity, err)
 		return
 	}
@@ -37,35 +39,75 @@ func (server *Server) Login(w http.ResponseWriter, r *http.Request) {
 	user.Prepare()
 	err = user.Validate("login")
 	if err != nil {
+		loginAttempts.WithLabelValues("invalid").Inc()
 		responses.ERROR(w, http.StatusUnprocessableEntity, err)
 		return
 	}
-	bearerToken, err := server.SignIn(user.Email, user.Password)
+	signedInUser, err := server.authenticate(user.Email, user.Password)
 	if err != nil {
+		loginAttempts.WithLabelValues("failure").Inc()
 		formattedError := formaterror.FormatError(err.Error())
 		responses.ERROR(w, http.StatusUnprocessableEntity, formattedError)
 		return
 	}
 
-	response := map[string]interface{}{
-		"token": bearerToken,
+	response, err := server.issueTokens(signedInUser)
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
 	}
+
+	loginAttempts.WithLabelValues("success").Inc()
 	responses.JSON(w, http.StatusOK, response)
 }
 
 func (server *Server) SignIn(email, password string) (string, error) {
+	user, err := server.authenticate(email, password)
+	if err != nil {
+		return "", err
+	}
 
-	var err error
+	return auth.CreateToken(user.ID, user.Role)
+}
 
-	user := models.User{}
+// authenticate checks the credentials and returns the matching user
+func (server *Server) authenticate(email, password string) (*models.User, error) {
 
-	err = server.DB.Debug().Model(models.User{}).Where("email = ?", email).Take(&user).Error
+	user, err := server.users().GetByEmail(email)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	err = models.VerifyPassword(user.Password, password)
-	if err != nil && err == bcrypt.ErrMismatchedHashAndPassword {
-		return "", err
+	if err != nil {
+		return nil, err
 	}
-	return auth.CreateToken(user.ID)
+	return user, nil
+}
+
+// issueTokens creates an access token and a persisted refresh token for user
+func (server *Server) issueTokens(user *models.User) (map[string]interface{}, error) {
+	accessToken, err := auth.CreateToken(user.ID, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := auth.CreateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	stored := models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: auth.HashRefreshToken(refreshToken),
+		ExpiresAt: time.Now().Add(auth.RefreshTokenTTL),
+	}
+	_, err = stored.CreateRefreshToken(server.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"token":         accessToken,
+		"refresh_token": refreshToken,
+	}, nil
 }
@@ -0,0 +1,298 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // decode gif dimensions
+	_ "image/jpeg" // decode jpeg dimensions
+	_ "image/png"  // decode png dimensions
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/dmdinh22/go-blog/api/auth"
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/dmdinh22/go-blog/api/responses"
+	"github.com/dmdinh22/go-blog/api/storage"
+	"github.com/gorilla/mux"
+)
+
+// multipart overhead allowed on top of the file itself
+const uploadFormOverhead = 1 << 20
+
+// UploadMedia godoc
+// @Summary Upload an image or attachment
+// @Description Upload a file as multipart form data under "file". Images (jpeg, png, gif, webp) and pdf up to 10 MB are accepted; the type is sniffed from the content.
+// @Tags media
+// @Accept  mpfd
+// @Produce  json
+// @Param file formData file true "the file to upload"
+// @Success 201 {object} models.Media
+// @Router /api/media [post]
+func (server *Server) UploadMedia(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.FromContext(r.Context())
+
+	if !ok {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+
+	tooLarge := fmt.Errorf("File is larger than %d MB", models.MaxMediaSize>>20)
+	r.Body = http.MaxBytesReader(w, r.Body, models.MaxMediaSize+uploadFormOverhead)
+
+	file, header, err := r.FormFile("file")
+
+	if err != nil {
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			responses.ERROR(w, http.StatusRequestEntityTooLarge, tooLarge)
+			return
+		}
+
+		responses.ERROR(w, http.StatusBadRequest, errors.New("Required file"))
+		return
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(file, models.MaxMediaSize+1))
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(data) > models.MaxMediaSize {
+		responses.ERROR(w, http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+
+	// trust the content, not the name or the header sent by the client
+	contentType := http.DetectContentType(data)
+	ext, ok := models.AllowedMediaTypes[contentType]
+
+	if !ok {
+		responses.ERROR(w, http.StatusUnsupportedMediaType, errors.New("Unsupported file type"))
+		return
+	}
+
+	media := models.Media{
+		Filename:    mediaFilename(header.Filename, ext),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		UserID:      principal.UserID,
+	}
+
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		media.Width = config.Width
+		media.Height = config.Height
+	}
+
+	err = media.Validate()
+
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	media.Key, err = mediaKey(principal.UserID, ext)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = server.Storage.Put(r.Context(), media.Key, bytes.NewReader(data), media.Size, media.ContentType)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	savedMedia, err := media.SaveMedia(server.DB)
+
+	if err != nil {
+		// do not leave an object nothing points at
+		server.Storage.Delete(r.Context(), media.Key)
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s%s/%d", r.Host, r.URL.Path, savedMedia.ID))
+	responses.JSON(w, http.StatusCreated, savedMedia)
+}
+
+// GetMedia godoc
+// @Summary Get an upload
+// @Description Get the details of an upload. Its content is served at the url it returns.
+// @Tags media
+// @Param id path int true "media ID"
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.Media
+// @Router /api/media/{id} [get]
+func (server *Server) GetMedia(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	mid, err := strconv.ParseUint(vars["id"], 10, 64)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	media := models.Media{}
+	receivedMedia, err := media.GetMediaByID(server.DB, mid)
+
+	if err == models.ErrMediaNotFound {
+		responses.ERROR(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, receivedMedia)
+}
+
+// GetMediaContent godoc
+// @Summary Download an upload
+// @Description Streams the stored file with its content type
+// @Tags media
+// @Param id path int true "media ID"
+// @Produce  octet-stream
+// @Success 200 {file} file
+// @Router /api/media/{id}/content [get]
+func (server *Server) GetMediaContent(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	mid, err := strconv.ParseUint(vars["id"], 10, 64)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	media := models.Media{}
+	_, err = media.GetMediaByID(server.DB, mid)
+
+	if err == models.ErrMediaNotFound {
+		responses.ERROR(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	content, err := server.Storage.Get(r.Context(), media.Key)
+
+	if err == storage.ErrNotFound {
+		responses.ERROR(w, http.StatusNotFound, models.ErrMediaNotFound)
+		return
+	}
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", media.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(media.Size, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", media.Filename))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, content)
+}
+
+// DeleteMedia godoc
+// @Summary Delete an upload
+// @Description Delete an upload and detach it from posts. Only its uploader may delete it.
+// @Tags media
+// @Param id path int true "media ID"
+// @Success 204
+// @Router /api/media/{id} [delete]
+func (server *Server) DeleteMedia(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	mid, err := strconv.ParseUint(vars["id"], 10, 64)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	principal, ok := auth.FromContext(r.Context())
+
+	if !ok {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+
+	media := models.Media{}
+	_, err = media.GetMediaByID(server.DB, mid)
+
+	if err == models.ErrMediaNotFound {
+		responses.ERROR(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if principal.UserID != media.UserID {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+
+	_, err = media.DeleteMedia(server.DB, mid)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = server.Storage.Delete(r.Context(), media.Key)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Entity", fmt.Sprintf("%d", mid))
+	responses.JSON(w, http.StatusNoContent, "")
+}
+
+// mediaKey returns a fresh, unguessable object key in the folder of the user
+func mediaKey(uid uint32, ext string) (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d/%s%s", uid, hex.EncodeToString(b), ext), nil
+}
+
+// mediaFilename keeps the base name the client sent, falling back to a
+// generic one
+func mediaFilename(name, ext string) string {
+	name = filepath.Base(filepath.Clean("/" + name))
+	if name == "/" || name == "." {
+		name = "upload" + ext
+	}
+
+	if len(name) > 255 {
+		name = name[len(name)-255:]
+	}
+
+	return name
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// loginAttempts counts logins by result: success, failure for wrong
+// credentials or invalid for requests that could not be read
+var loginAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "login_attempts_total",
+	Help: "Login attempts by result, success, failure or invalid.",
+}, []string{"result"})
+
+// GetMetrics godoc
+// @Summary Metrics of the server
+// @Description Requests by route, login attempts, the database connection pool and the Go runtime in the Prometheus text format. Not authenticated; set METRICS_ADDR to serve it on an address of its own.
+// @Tags metrics
+// @Produce  plain
+// @Success 200 {string} string
+// @Router /metrics [get]
+func (server *Server) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	gatherers := prometheus.Gatherers{prometheus.DefaultGatherer}
+
+	if server.DB != nil {
+		pool := prometheus.NewRegistry()
+		pool.MustRegister(collectors.NewDBStatsCollector(server.DB.DB(), server.DB.Dialect().GetName()))
+		gatherers = append(gatherers, pool)
+	}
+
+	promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
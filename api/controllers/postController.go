@@ -6,13 +6,17 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/dmdinh22/go-blog/api/auth"
 	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/dmdinh22/go-blog/api/render"
 	"github.com/dmdinh22/go-blog/api/responses"
 	"github.com/dmdinh22/go-blog/api/utils/formaterror"
 	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
 )
 
 // CreatePost godoc
@@ -23,11 +27,19 @@ import (
 // @Param Title query string true "post's title"
 // @Param Content query string true "post's content"
 // @Param AuthorID query string true "id of user that created this post"
+// @Param format query string false "render the body as markdown, html (contentHtml) or plain (contentText)" default(html)
 // @Accept  json
 // @Produce  json
 // @Success 200 {object} models.Post
 // @Router /api/posts [post]
 func (server *Server) CreatePost(w http.ResponseWriter, r *http.Request) {
+	format, err := renderFormat(r)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
 	body, err := ioutil.ReadAll(r.Body)
 
 	if err != nil {
@@ -51,19 +63,24 @@ func (server *Server) CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	uid, err := auth.ExtractTokenId(r)
+	principal, ok := auth.FromContext(r.Context())
 
-	if err != nil {
+	if !ok {
 		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
 		return
 	}
 
-	if uid != post.AuthorID {
+	if principal.UserID != post.AuthorID {
 		responses.ERROR(w, http.StatusUnauthorized, errors.New(http.StatusText(http.StatusUnauthorized)))
 		return
 	}
 
-	createdPost, err := post.CreatePost(server.DB)
+	createdPost, err := server.posts().Create(&post)
+
+	if err == models.ErrMediaNotFound {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
 
 	if err != nil {
 		formattedError := formaterror.FormatError(err.Error())
@@ -71,29 +88,140 @@ func (server *Server) CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = createdPost.Render(format)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if createdPost.Status == models.PostStatusScheduled {
+		server.Scheduler.Wake()
+	}
+
 	w.Header().Set("Location", fmt.Sprintf("%s%s/%d", r.Host, r.URL.Path, createdPost.ID))
 
 	responses.JSON(w, http.StatusCreated, createdPost)
 }
 
 // GetPosts godoc
-// @Summary Get details of all posts
-// @Description Get details of all posts
+// @Summary Get a page of posts
+// @Description Get a page of posts, newest first unless sorted otherwise
 // @Tags posts
+// @Param limit query int false "page size, max 100" default(20)
+// @Param cursor query string false "next_cursor from the previous page"
+// @Param author_id query int false "only posts by this author"
+// @Param since query string false "only posts created at or after this RFC3339 time"
+// @Param until query string false "only posts created before this RFC3339 time"
+// @Param sort query string false "created_at or -created_at" default(-created_at)
+// @Param tag query []string false "only posts with this tag, may be repeated" collectionFormat(multi)
+// @Param tag_mode query string false "all to require every tag, any to match one of them" default(all)
+// @Param status query string false "only posts with this status; unpublished posts are only listed for their author"
+// @Param format query string false "render the body as markdown, html (contentHtml) or plain (contentText)" default(html)
 // @Accept  json
 // @Produce  json
-// @Success 200 {array} models.Post
+// @Success 200 {object} responses.Page
 // @Router /api/posts [get]
 func (server *Server) GetPosts(w http.ResponseWriter, r *http.Request) {
-	post := models.Post{}
-	posts, err := post.GetAllPosts(server.DB)
+	format, err := renderFormat(r)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	query, err := parsePostQuery(r)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	posts, nextCursor, err := server.posts().Find(query)
+
+	if err == models.ErrInvalidCursor {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
 
 	if err != nil {
 		responses.ERROR(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusOK, posts)
+	err = models.RenderPosts(*posts, format)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.PAGE(w, http.StatusOK, posts, nextCursor)
+}
+
+// parsePostQuery reads the listing filters from the query string
+func parsePostQuery(r *http.Request) (models.PostQuery, error) {
+	params := r.URL.Query()
+	query := models.PostQuery{
+		Cursor:  params.Get("cursor"),
+		Sort:    params.Get("sort"),
+		Tags:    params["tag"],
+		TagMode: params.Get("tag_mode"),
+		Status:  params.Get("status"),
+	}
+
+	// signed in authors also see their own unpublished posts
+	query.ViewerID = viewerID(r)
+
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return query, errors.New("Invalid limit")
+		}
+		query.Limit = limit
+	}
+
+	if v := params.Get("author_id"); v != "" {
+		aid, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return query, errors.New("Invalid author_id")
+		}
+		query.AuthorID = uint32(aid)
+	}
+
+	if v := params.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return query, errors.New("Invalid since, expected RFC3339")
+		}
+		query.Since = since
+	}
+
+	if v := params.Get("until"); v != "" {
+		until, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return query, errors.New("Invalid until, expected RFC3339")
+		}
+		query.Until = until
+	}
+
+	return query, query.Validate()
+}
+
+// renderFormat reads the format post bodies are rendered in, see
+// models.Post.Render
+func renderFormat(r *http.Request) (string, error) {
+	return render.ParseFormat(r.URL.Query().Get("format"))
+}
+
+// viewerID returns the signed in user, or 0 for anonymous requests
+func viewerID(r *http.Request) uint32 {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		return 0
+	}
+
+	return principal.UserID
 }
 
 // GetPost godoc
@@ -101,11 +229,19 @@ func (server *Server) GetPosts(w http.ResponseWriter, r *http.Request) {
 // @Description Get details of a post by ID
 // @Tags posts
 // @Param id path int true "post ID"
+// @Param format query string false "render the body as markdown, html (contentHtml) or plain (contentText)" default(html)
 // @Accept  json
 // @Produce  json
 // @Success 200 {object} models.Post
 // @Router /api/posts/{id} [get]
 func (server *Server) GetPost(w http.ResponseWriter, r *http.Request) {
+	format, err := renderFormat(r)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
 	vars := mux.Vars(r)
 	pid, err := strconv.ParseUint(vars["id"], 10, 64)
 
@@ -114,9 +250,77 @@ func (server *Server) GetPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post := models.Post{}
+	postRetrieved, err := server.posts().GetByID(pid)
 
-	postRetrieved, err := post.GetPostByID(server.DB, pid)
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// drafts and other unpublished posts are only shown to their author
+	if !postRetrieved.VisibleTo(viewerID(r)) {
+		responses.ERROR(w, http.StatusNotFound, errors.New("Post not found"))
+		return
+	}
+
+	err = postRetrieved.Render(format)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, postRetrieved)
+}
+
+// GetPostBySlug godoc
+// @Summary Get post by slug
+// @Description Get details of a post by its slug. Slugs the post had before its title changed redirect to the current one.
+// @Tags posts
+// @Param slug path string true "post slug"
+// @Param format query string false "render the body as markdown, html (contentHtml) or plain (contentText)" default(html)
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.Post
+// @Success 301
+// @Router /api/posts/by-slug/{slug} [get]
+func (server *Server) GetPostBySlug(w http.ResponseWriter, r *http.Request) {
+	format, err := renderFormat(r)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	postRetrieved, err := server.posts().GetBySlug(vars["slug"])
+
+	if gorm.IsRecordNotFoundError(err) {
+		// an old slug moves to the current one
+		movedPost, err := server.posts().GetByOldSlug(vars["slug"])
+
+		if err != nil || !movedPost.VisibleTo(viewerID(r)) {
+			responses.ERROR(w, http.StatusNotFound, errors.New("Post not found"))
+			return
+		}
+
+		location := url.URL{Path: "/api/posts/by-slug/" + movedPost.Slug, RawQuery: r.URL.RawQuery}
+		http.Redirect(w, r, location.String(), http.StatusMovedPermanently)
+		return
+	}
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !postRetrieved.VisibleTo(viewerID(r)) {
+		responses.ERROR(w, http.StatusNotFound, errors.New("Post not found"))
+		return
+	}
+
+	err = postRetrieved.Render(format)
 
 	if err != nil {
 		responses.ERROR(w, http.StatusInternalServerError, err)
@@ -132,11 +336,19 @@ func (server *Server) GetPost(w http.ResponseWriter, r *http.Request) {
 // @Tags posts
 // @Param id path int true "Post ID"
 // @Param Post body models.Post true "Update Request Body"
+// @Param format query string false "render the body as markdown, html (contentHtml) or plain (contentText)" default(html)
 // @Accept  json
 // @Produce  json
 // @Success 200 {object} models.Post
 // @Router /api/posts/{id} [put]
 func (server *Server) UpdatePost(w http.ResponseWriter, r *http.Request) {
+	format, err := renderFormat(r)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
 	vars := mux.Vars(r)
 
 	// Check if  postId is valid
@@ -147,25 +359,24 @@ func (server *Server) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if auth token valid & get the userId
-	uid, err := auth.ExtractTokenId(r)
+	// Get the authenticated user
+	principal, ok := auth.FromContext(r.Context())
 
-	if err != nil {
+	if !ok {
 		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
 		return
 	}
 
 	// Check if post exists
-	post := models.Post{}
-	err = server.DB.Debug().Model(models.Post{}).Where("id = ?", pid).Take(&post).Error
+	post, err := server.posts().GetByID(pid)
 
 	if err != nil {
 		responses.ERROR(w, http.StatusNotFound, errors.New("Post not found"))
 		return
 	}
 
-	// Check if authorized author
-	if uid != post.AuthorID {
+	// Check if authorized author, or allowed to edit anyone's posts
+	if principal.UserID != post.AuthorID && !principal.Can(auth.PermPostsUpdateAny) {
 		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
 		return
 	}
@@ -187,8 +398,8 @@ func (server *Server) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//Also check if the request user id is equal to the one gotten from token
-	if uid != postUpdate.AuthorID {
+	//Also check the request does not hand the post over to another author
+	if post.AuthorID != postUpdate.AuthorID {
 		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
 		return
 	}
@@ -203,7 +414,12 @@ func (server *Server) UpdatePost(w http.ResponseWriter, r *http.Request) {
 
 	//this is important to tell the model the post id to update, the other update field are set above
 	postUpdate.ID = post.ID
-	updatedPost, err := postUpdate.UpdatePost(server.DB)
+	updatedPost, err := server.posts().Update(&postUpdate)
+
+	if err == models.ErrMediaNotFound {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
 
 	if err != nil {
 		formattedError := formaterror.FormatError(err.Error())
@@ -211,6 +427,17 @@ func (server *Server) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = updatedPost.Render(format)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if updatedPost.Status == models.PostStatusScheduled {
+		server.Scheduler.Wake()
+	}
+
 	responses.JSON(w, http.StatusOK, updatedPost)
 }
 
@@ -235,29 +462,28 @@ func (server *Server) DeletePost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if user authenticated?
-	uid, err := auth.ExtractTokenId(r)
+	principal, ok := auth.FromContext(r.Context())
 
-	if err != nil {
+	if !ok {
 		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
 		return
 	}
 
 	// Check if the post exists
-	post := models.Post{}
-	err = server.DB.Debug().Model(models.Post{}).Where("id = ?", pid).Take(&post).Error
+	post, err := server.posts().GetByID(pid)
 
 	if err != nil {
 		responses.ERROR(w, http.StatusNotFound, errors.New("Unauthorized"))
 		return
 	}
 
-	// Is the authenticated user, the owner of this post?
-	if uid != post.AuthorID {
+	// Is the authenticated user, the owner of this post or a moderator?
+	if principal.UserID != post.AuthorID && !principal.Can(auth.PermPostsDeleteAny) {
 		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
 		return
 	}
 
-	_, err = post.DeletePost(server.DB, pid, uid)
+	_, err = server.posts().Delete(pid, post.AuthorID)
 
 	if err != nil {
 		responses.ERROR(w, http.StatusBadRequest, err)
@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/dmdinh22/go-blog/api/auth"
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/dmdinh22/go-blog/api/responses"
+	"github.com/dmdinh22/go-blog/api/utils/diff"
+	"github.com/dmdinh22/go-blog/api/utils/formaterror"
+	"github.com/gorilla/mux"
+)
+
+// GetRevisions godoc
+// @Summary Get the revisions of a post
+// @Description Get every saved version of a post, newest first
+// @Tags posts
+// @Param id path int true "post ID"
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} models.PostRevision
+// @Router /api/posts/{id}/revisions [get]
+func (server *Server) GetRevisions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	pid, err := strconv.ParseUint(vars["id"], 10, 64)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	post, err := server.posts().GetByID(pid)
+
+	if err != nil || !post.VisibleTo(viewerID(r)) {
+		responses.ERROR(w, http.StatusNotFound, errors.New("Post not found"))
+		return
+	}
+
+	revisions, err := post.GetRevisions(server.DB, pid)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, revisions)
+}
+
+// GetRevisionDiff godoc
+// @Summary Compare two revisions of a post
+// @Description Get a unified diff between two revisions, by default between the latest one and the one before. Revision 0 is the empty text. Texts over 2000 lines are refused with 422.
+// @Tags posts
+// @Param id path int true "post ID"
+// @Param from query int false "older revision"
+// @Param to query int false "newer revision"
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.RevisionDiff
+// @Router /api/posts/{id}/revisions/diff [get]
+func (server *Server) GetRevisionDiff(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	pid, err := strconv.ParseUint(vars["id"], 10, 64)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	post, err := server.posts().GetByID(pid)
+
+	if err != nil || !post.VisibleTo(viewerID(r)) {
+		responses.ERROR(w, http.StatusNotFound, errors.New("Post not found"))
+		return
+	}
+
+	params := r.URL.Query()
+	to := 0
+
+	if v := params.Get("to"); v != "" {
+		to, err = strconv.Atoi(v)
+
+		if err != nil {
+			responses.ERROR(w, http.StatusBadRequest, errors.New("Invalid to"))
+			return
+		}
+	} else {
+		to, err = post.LatestRevision(server.DB, pid)
+
+		if err != nil {
+			responses.ERROR(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	// the first revision is compared with the empty text
+	from := to - 1
+	if from < 0 {
+		from = 0
+	}
+
+	if v := params.Get("from"); v != "" {
+		from, err = strconv.Atoi(v)
+
+		if err != nil {
+			responses.ERROR(w, http.StatusBadRequest, errors.New("Invalid from"))
+			return
+		}
+	}
+
+	revisionDiff, err := post.DiffRevisions(server.DB, pid, from, to)
+
+	if err == models.ErrRevisionNotFound {
+		responses.ERROR(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err == diff.ErrTooLarge {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, revisionDiff)
+}
+
+// RestoreRevision godoc
+// @Summary Restore a revision of a post
+// @Description Put back the title and content of an older revision. This is saved as a new revision.
+// @Tags posts
+// @Param id path int true "post ID"
+// @Param rev path int true "revision to restore"
+// @Param format query string false "render the body as markdown, html (contentHtml) or plain (contentText)" default(html)
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.Post
+// @Router /api/posts/{id}/revisions/{rev}/restore [post]
+func (server *Server) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	format, err := renderFormat(r)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	pid, err := strconv.ParseUint(vars["id"], 10, 64)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	rev, err := strconv.Atoi(vars["rev"])
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	principal, ok := auth.FromContext(r.Context())
+
+	if !ok {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+
+	// Check if post exists
+	post, err := server.posts().GetByID(pid)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusNotFound, errors.New("Post not found"))
+		return
+	}
+
+	// Same rule as editing the post
+	if principal.UserID != post.AuthorID && !principal.Can(auth.PermPostsUpdateAny) {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+
+	revision, err := post.GetRevision(server.DB, pid, rev)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusNotFound, err)
+		return
+	}
+
+	postUpdate := models.Post{
+		ID:       post.ID,
+		Title:    revision.Title,
+		Content:  revision.Content,
+		AuthorID: post.AuthorID,
+	}
+	updatedPost, err := server.posts().Update(&postUpdate)
+
+	if err != nil {
+		formattedError := formaterror.FormatError(err.Error())
+		responses.ERROR(w, http.StatusInternalServerError, formattedError)
+		return
+	}
+
+	err = updatedPost.Render(format)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, updatedPost)
+}
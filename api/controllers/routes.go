@@ -1,8 +1,7 @@
 package controllers
 
 import (
-	"net/http"
-
+	"github.com/dmdinh22/go-blog/api/auth"
 	"github.com/dmdinh22/go-blog/api/middlewares"
 	httpSwagger "github.com/swaggo/http-swagger"
 
@@ -15,6 +14,8 @@ func (s *Server) initializeRoutes() {
 
 	// Login Route
 	s.Router.HandleFunc("/api/login", middlewares.SetMiddlewareJSON(s.Login)).Methods("POST")
+	s.Router.HandleFunc("/api/token/refresh", middlewares.SetMiddlewareJSON(s.RefreshToken)).Methods("POST")
+	s.Router.HandleFunc("/api/logout", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.Logout))).Methods("POST")
 
 	// User routes
 	s.Router.HandleFunc("/api/users", middlewares.SetMiddlewareJSON(s.CreateUser)).Methods("POST")
@@ -23,18 +24,59 @@ func (s *Server) initializeRoutes() {
 	s.Router.HandleFunc("/api/users/{id}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.UpdateUser))).Methods("PUT")
 	s.Router.HandleFunc("/api/users/{id}", middlewares.SetMiddlewareAuthentication(s.DeleteUser)).Methods("DELETE")
 
+	// Admin routes
+	s.Router.HandleFunc("/api/admin/users/{id}/role", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(middlewares.SetMiddlewarePermission(auth.PermUsersManage, s.UpdateUserRole)))).Methods("PUT")
+	s.Router.HandleFunc("/api/admin/users/{id}", middlewares.SetMiddlewareAuthentication(middlewares.SetMiddlewarePermission(auth.PermUsersManage, s.AdminDeleteUser))).Methods("DELETE")
+
 	//Post routes
-	s.Router.HandleFunc("/api/posts", middlewares.SetMiddlewareJSON(s.CreatePost)).Methods("POST")
-	s.Router.HandleFunc("/api/posts", middlewares.SetMiddlewareJSON(s.GetPosts)).Methods("GET")
-	s.Router.HandleFunc("/api/posts/{id}", middlewares.SetMiddlewareJSON(s.GetPost)).Methods("GET")
+	s.Router.HandleFunc("/api/posts", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.CreatePost))).Methods("POST")
+	s.Router.HandleFunc("/api/posts", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareOptionalAuthentication(s.GetPosts))).Methods("GET")
+	s.Router.HandleFunc("/api/posts/by-slug/{slug}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareOptionalAuthentication(s.GetPostBySlug))).Methods("GET")
+	s.Router.HandleFunc("/api/posts/{id}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareOptionalAuthentication(s.GetPost))).Methods("GET")
 	s.Router.HandleFunc("/api/posts/{id}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.UpdatePost))).Methods("PUT")
 	s.Router.HandleFunc("/api/posts/{id}", middlewares.SetMiddlewareAuthentication(s.DeletePost)).Methods("DELETE")
 
+	// Comment routes
+	s.Router.HandleFunc("/api/posts/{id}/comments", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareOptionalAuthentication(s.GetComments))).Methods("GET")
+	s.Router.HandleFunc("/api/posts/{id}/comments", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.CreateComment))).Methods("POST")
+	s.Router.HandleFunc("/api/posts/{id}/comments/{commentId}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.UpdateComment))).Methods("PUT")
+	s.Router.HandleFunc("/api/posts/{id}/comments/{commentId}", middlewares.SetMiddlewareAuthentication(s.DeleteComment)).Methods("DELETE")
+
+	// Revision routes
+	s.Router.HandleFunc("/api/posts/{id}/revisions", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareOptionalAuthentication(s.GetRevisions))).Methods("GET")
+	s.Router.HandleFunc("/api/posts/{id}/revisions/diff", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareOptionalAuthentication(s.GetRevisionDiff))).Methods("GET")
+	s.Router.HandleFunc("/api/posts/{id}/revisions/{rev}/restore", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.RestoreRevision))).Methods("POST")
+
+	// Media routes
+	s.Router.HandleFunc("/api/media", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.UploadMedia))).Methods("POST")
+	s.Router.HandleFunc("/api/media/{id}", middlewares.SetMiddlewareJSON(s.GetMedia)).Methods("GET")
+	s.Router.HandleFunc("/api/media/{id}/content", s.GetMediaContent).Methods("GET")
+	s.Router.HandleFunc("/api/media/{id}", middlewares.SetMiddlewareAuthentication(s.DeleteMedia)).Methods("DELETE")
+
+	// Search routes
+	s.Router.HandleFunc("/api/search", middlewares.SetMiddlewareJSON(s.SearchPosts)).Methods("GET")
+
+	// Tag routes
+	s.Router.HandleFunc("/api/tags", middlewares.SetMiddlewareJSON(s.GetTags)).Methods("GET")
+
+	// Feed routes
+	s.Router.HandleFunc("/feed.{format:rss|atom|json}", s.GetFeed).Methods("GET", "HEAD")
+	s.Router.HandleFunc("/api/users/{id}/feed.{format:rss|atom|json}", s.GetUserFeed).Methods("GET", "HEAD")
+
+	// Health routes
+	s.Router.HandleFunc("/healthz", s.GetHealth).Methods("GET", "HEAD")
+	s.Router.HandleFunc("/readyz", s.GetReadiness).Methods("GET", "HEAD")
+
+	// Metrics, on a listener of their own when MetricsAddr is set
+	if s.MetricsAddr == "" {
+		s.Router.HandleFunc("/metrics", s.GetMetrics).Methods("GET")
+	}
+
 	// Swagger
 	s.Router.PathPrefix("/swagger").Handler(httpSwagger.WrapHandler)
 
-	// redirect base route to swagger
-	s.Router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		http.Redirect(w, r, "/"+"swagger/", http.StatusSeeOther)
-	})
+	// Frontend routes
+	s.Router.HandleFunc("/", s.Index).Methods("GET")
+	s.Router.HandleFunc("/posts/{slug}", s.PostPage).Methods("GET")
+	s.Router.HandleFunc("/authors/{id}", s.AuthorPage).Methods("GET")
 }
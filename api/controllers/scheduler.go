@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/dmdinh22/go-blog/api/logger"
+	"github.com/dmdinh22/go-blog/api/models"
+)
+
+// DefaultSchedulerInterval bounds how long the scheduler sleeps between runs
+const DefaultSchedulerInterval = time.Minute
+
+// PublishScheduler publishes scheduled posts once they are due. It sleeps
+// until the next scheduled post is due, but never longer than Interval, and
+// can be woken up early when a post is scheduled.
+type PublishScheduler struct {
+	DB       *gorm.DB
+	Interval time.Duration
+
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+
+	// started is set to 1 by Start
+	started int32
+}
+
+func NewPublishScheduler(db *gorm.DB, interval time.Duration) *PublishScheduler {
+	return &PublishScheduler{
+		DB:       db,
+		Interval: interval,
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start runs the scheduler in the background until Stop is called. Starting
+// it again does nothing.
+func (s *PublishScheduler) Start() {
+	if !atomic.CompareAndSwapInt32(&s.started, 0, 1) {
+		return
+	}
+	go s.run()
+}
+
+// Stop ends a started scheduler and waits for its last run to finish
+func (s *PublishScheduler) Stop() {
+	s.Shutdown(context.Background())
+}
+
+// Shutdown ends a started scheduler and waits for its last run to finish,
+// or for ctx to be done. It returns at once on a scheduler never started
+// and may be called again, e.g. to wait longer.
+func (s *PublishScheduler) Shutdown(ctx context.Context) error {
+	if atomic.LoadInt32(&s.started) == 0 {
+		return nil
+	}
+
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+
+	// a scheduler already done has stopped, whatever the state of ctx
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Name and Check make the scheduler a HealthChecker
+func (s *PublishScheduler) Name() string {
+	return "scheduler"
+}
+
+// Check fails until the scheduler is started and once it has stopped
+func (s *PublishScheduler) Check(ctx context.Context) error {
+	if atomic.LoadInt32(&s.started) == 0 {
+		return errors.New("The scheduler has not been started")
+	}
+
+	select {
+	case <-s.done:
+		return errors.New("The scheduler has stopped")
+	default:
+		return nil
+	}
+}
+
+// Wake makes the scheduler look for due posts again, so a post scheduled in
+// the near future is not published late. It is safe on a nil scheduler.
+func (s *PublishScheduler) Wake() {
+	if s == nil {
+		return
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *PublishScheduler) run() {
+	defer close(s.done)
+
+	for {
+		timer := time.NewTimer(s.publishDue(time.Now()))
+
+		select {
+		case <-timer.C:
+		case <-s.wake:
+			timer.Stop()
+		case <-s.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// publishDue publishes the posts due at now and returns how long to wait
+// before the next run.
+func (s *PublishScheduler) publishDue(now time.Time) time.Duration {
+	count, err := models.PublishDuePosts(s.DB, now)
+	if err != nil {
+		logger.Default().Error("Cannot publish scheduled posts", "error", err)
+		return s.Interval
+	}
+
+	if count > 0 {
+		logger.Default().Info("Published scheduled posts", "count", count)
+	}
+
+	next, err := models.NextScheduledPublish(s.DB)
+	if err != nil {
+		logger.Default().Error("Cannot find the next scheduled post", "error", err)
+		return s.Interval
+	}
+
+	if next == nil || next.Sub(now) > s.Interval {
+		return s.Interval
+	}
+
+	if next.Before(now) {
+		return 0
+	}
+
+	return next.Sub(now)
+}
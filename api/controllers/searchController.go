@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/dmdinh22/go-blog/api/responses"
+)
+
+// SearchPosts godoc
+// @Summary Search posts
+// @Description Search post titles and content, most relevant first
+// @Tags posts
+// @Param q query string true "search terms"
+// @Param limit query int false "number of results, max 100" default(20)
+// @Param format query string false "render the body as markdown, html (contentHtml) or plain (contentText)" default(html)
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} models.Post
+// @Router /api/search [get]
+func (server *Server) SearchPosts(w http.ResponseWriter, r *http.Request) {
+	format, err := renderFormat(r)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	params := r.URL.Query()
+	limit := models.DefaultPostLimit
+
+	if v := params.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+
+		if err != nil {
+			responses.ERROR(w, http.StatusBadRequest, errors.New("Invalid limit"))
+			return
+		}
+	}
+
+	query := params.Get("q")
+	err = models.ValidateSearch(query, limit)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	searcher := server.Search
+	if searcher == nil {
+		searcher = models.TextSearcher{}
+	}
+
+	posts, err := searcher.SearchPosts(server.DB, query, limit)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = models.RenderPosts(*posts, format)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, posts)
+}
@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/dmdinh22/go-blog/api/responses"
+)
+
+// GetTags godoc
+// @Summary Get all tags
+// @Description Get every tag with the number of posts carrying it, most used first
+// @Tags tags
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} models.TagCount
+// @Router /api/tags [get]
+func (server *Server) GetTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := models.GetTagsWithPostCounts(server.DB)
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, tags)
+}
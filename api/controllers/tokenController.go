@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/auth"
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/dmdinh22/go-blog/api/responses"
+)
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshToken godoc
+// @Summary Exchanges a refresh token for new tokens
+// @Description Rotates the refresh token and issues a new access token. A refresh token can only be used once.
+// @Tags login
+// @Param refresh body controllers.refreshRequest true "refresh token from login"
+// @Accept  json
+// @Produce  json
+// @Success 200 {string} string "token"
+// @Router /api/token/refresh [post]
+func (server *Server) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	request := refreshRequest{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	if request.RefreshToken == "" {
+		responses.ERROR(w, http.StatusUnprocessableEntity, errors.New("Required Refresh Token"))
+		return
+	}
+
+	refreshToken, err := auth.CreateRefreshToken()
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	replacement := models.RefreshToken{
+		TokenHash: auth.HashRefreshToken(refreshToken),
+		ExpiresAt: time.Now().Add(auth.RefreshTokenTTL),
+	}
+	rotated, err := replacement.RotateRefreshToken(server.DB, auth.HashRefreshToken(request.RefreshToken))
+
+	if err == models.ErrRefreshTokenInvalid || err == models.ErrRefreshTokenReused {
+		responses.ERROR(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// pick up role changes made since the previous token was issued
+	user, err := server.users().GetByID(rotated.UserID)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, models.ErrRefreshTokenInvalid)
+		return
+	}
+
+	accessToken, err := auth.CreateToken(user.ID, user.Role)
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, map[string]interface{}{
+		"token":         accessToken,
+		"refresh_token": refreshToken,
+	})
+}
+
+// Logout godoc
+// @Summary Logs a user out
+// @Description Revokes the access token used for the request along with the given refresh token, or every refresh token of the user when none is given
+// @Tags login
+// @Param refresh body controllers.refreshRequest false "refresh token to revoke"
+// @Accept  json
+// @Produce  json
+// @Success 204
+// @Router /api/logout [post]
+func (server *Server) Logout(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	request := refreshRequest{}
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &request)
+		if err != nil {
+			responses.ERROR(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+	}
+
+	if request.RefreshToken != "" {
+		err = models.RevokeRefreshToken(server.DB, auth.HashRefreshToken(request.RefreshToken), principal.UserID)
+	} else {
+		err = models.RevokeUserRefreshTokens(server.DB, principal.UserID)
+	}
+
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = auth.RevokeToken(principal.TokenID, principal.ExpiresAt)
+	if err != nil {
+		responses.ERROR(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, "")
+}
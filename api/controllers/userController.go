@@ -51,7 +51,7 @@ func (server *Server) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	createdUser, err := user.CreateUser(server.DB)
+	createdUser, err := server.users().Create(&user)
 
 	if err != nil {
 		formattedError := formaterror.FormatError(err.Error())
@@ -73,8 +73,7 @@ func (server *Server) CreateUser(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {array} models.User
 // @Router /api/users [get]
 func (server *Server) GetUsers(w http.ResponseWriter, r *http.Request) {
-	user := models.User{}
-	users, err := user.GetAllUsers(server.DB)
+	users, err := server.users().FindAll()
 
 	if err != nil {
 		responses.ERROR(w, http.StatusInternalServerError, err)
@@ -102,8 +101,7 @@ func (server *Server) GetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user := models.User{}
-	userRetrieved, err := user.GetUserById(server.DB, uint32(uid))
+	userRetrieved, err := server.users().GetByID(uint32(uid))
 
 	if err != nil {
 		responses.ERROR(w, http.StatusBadRequest, err)
@@ -147,14 +145,14 @@ func (server *Server) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokenID, err := auth.ExtractTokenId(r)
+	principal, ok := auth.FromContext(r.Context())
 
-	if err != nil {
+	if !ok {
 		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
 		return
 	}
 
-	if tokenID != uint32(uid) {
+	if principal.UserID != uint32(uid) {
 		responses.ERROR(w, http.StatusUnauthorized, errors.New(http.StatusText(http.StatusUnauthorized)))
 		return
 	}
@@ -167,7 +165,7 @@ func (server *Server) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updatedUser, err := user.UpdateUser(server.DB, uint32(uid))
+	updatedUser, err := server.users().Update(uint32(uid), &user)
 
 	if err != nil {
 		formattedError := formaterror.FormatError(err.Error())
@@ -189,7 +187,6 @@ func (server *Server) UpdateUser(w http.ResponseWriter, r *http.Request) {
 // @Router /api/users/{id} [delete]
 func (server *Server) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	user := models.User{}
 	uid, err := strconv.ParseUint(vars["id"], 10, 32)
 
 	if err != nil {
@@ -197,19 +194,19 @@ func (server *Server) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokenID, err := auth.ExtractTokenId(r)
+	principal, ok := auth.FromContext(r.Context())
 
-	if err != nil {
+	if !ok {
 		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
 		return
 	}
 
-	if tokenID != 0 && tokenID != uint32(uid) {
+	if principal.UserID != uint32(uid) {
 		responses.ERROR(w, http.StatusUnauthorized, errors.New(http.StatusText(http.StatusUnauthorized)))
 		return
 	}
 
-	_, err = user.DeleteUser(server.DB, uint32(uid))
+	_, err = server.users().Delete(uint32(uid))
 
 	if err != nil {
 		responses.ERROR(w, http.StatusInternalServerError, err)
@@ -0,0 +1,621 @@
+// Package export writes the published blog as a static site: the frontend
+// pages, the feeds and a sitemap, laid out so any static file host can
+// serve it at the same paths the API does.
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/dmdinh22/go-blog/api/feeds"
+	"github.com/dmdinh22/go-blog/api/frontend"
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/dmdinh22/go-blog/api/render"
+	"github.com/dmdinh22/go-blog/api/storage"
+)
+
+// ManifestFile records what the last export wrote, so the next one can
+// skip posts that have not changed since.
+const ManifestFile = ".export-manifest.json"
+
+// Exporter writes the site of the published posts in DB to Dir
+type Exporter struct {
+	DB    *gorm.DB
+	Theme *frontend.Theme
+
+	// Storage, when set, is read to copy the media posts use into the site
+	Storage storage.ObjectStore
+
+	Dir string
+	// BaseURL is the scheme and host the site will be served at, used for
+	// the absolute links of the feeds and the sitemap
+	BaseURL string
+	// Force rewrites every post page, e.g. after a theme change
+	Force bool
+}
+
+// Stats counts the post pages of an export. Listings, feeds and the sitemap
+// are written every time.
+type Stats struct {
+	Written int
+	Skipped int
+	Removed int
+}
+
+type manifest struct {
+	// Posts maps post ids to the page written for them
+	Posts map[string]manifestPost `json:"posts"`
+	// Redirects are the pages written for old slugs
+	Redirects []string `json:"redirects"`
+	// Listings and Feeds are the files of the listing pages and the feeds
+	Listings []string `json:"listings"`
+	Feeds    []string `json:"feeds"`
+}
+
+type manifestPost struct {
+	Path      string    `json:"path"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Run exports the site
+func (e *Exporter) Run() (Stats, error) {
+	stats := Stats{}
+	e.BaseURL = strings.TrimSuffix(e.BaseURL, "/")
+
+	posts, err := publishedPosts(e.DB)
+	if err != nil {
+		return stats, err
+	}
+
+	previous, err := e.readManifest()
+	if err != nil {
+		return stats, err
+	}
+
+	current := manifest{Posts: map[string]manifestPost{}}
+
+	for _, post := range posts {
+		id := strconv.FormatUint(post.ID, 10)
+		page := manifestPost{Path: frontend.PostPath(post.Slug), UpdatedAt: post.UpdatedAt}
+		current.Posts[id] = page
+
+		last, ok := previous.Posts[id]
+		if !e.Force && ok && last.Path == page.Path && last.UpdatedAt.Equal(page.UpdatedAt) && e.exists(indexFile(page.Path)) {
+			stats.Skipped++
+			continue
+		}
+
+		err = e.writePost(post)
+		if err != nil {
+			return stats, err
+		}
+		stats.Written++
+	}
+
+	// pages of posts that were unpublished, deleted or moved to a new slug
+	for id, last := range previous.Posts {
+		if page, ok := current.Posts[id]; ok && page.Path == last.Path {
+			continue
+		}
+
+		err = os.RemoveAll(e.path(last.Path))
+		if err != nil {
+			return stats, err
+		}
+		stats.Removed++
+	}
+
+	current.Redirects, err = e.writeRedirects(posts, previous.Redirects)
+	if err != nil {
+		return stats, err
+	}
+
+	// listings and feeds of authors with no published post left, and the
+	// trailing pages of shorter listings, are removed
+	current.Listings, err = e.writeListings(posts)
+	if err != nil {
+		return stats, err
+	}
+
+	err = e.removeStale(previous.Listings, current.Listings)
+	if err != nil {
+		return stats, err
+	}
+
+	current.Feeds, err = e.writeFeeds(posts)
+	if err != nil {
+		return stats, err
+	}
+
+	err = e.removeStale(previous.Feeds, current.Feeds)
+	if err != nil {
+		return stats, err
+	}
+
+	err = e.writeSitemap(posts)
+	if err != nil {
+		return stats, err
+	}
+
+	return stats, e.writeManifest(current)
+}
+
+// publishedPosts returns every published post, newest first
+func publishedPosts(db *gorm.DB) ([]models.Post, error) {
+	all := []models.Post{}
+	query := models.PostQuery{Limit: models.MaxPostLimit, Status: models.PostStatusPublished}
+
+	for {
+		post := models.Post{}
+		posts, next, err := post.FindPosts(db, query)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, *posts...)
+		if next == "" {
+			return all, nil
+		}
+		query.Cursor = next
+	}
+}
+
+func (e *Exporter) writePost(post models.Post) error {
+	err := post.Render(render.FormatHTML)
+	if err != nil {
+		return err
+	}
+
+	data := frontend.PageData{
+		Site:  frontend.SiteTitle,
+		Title: html.UnescapeString(post.Title),
+		Feed:  fmt.Sprintf("/api/users/%d/feed.atom", post.AuthorID),
+		Post:  post,
+	}
+
+	err = e.writePage(frontend.PostPath(post.Slug), frontend.PagePost, data)
+	if err != nil {
+		return err
+	}
+
+	return e.copyMedia(post)
+}
+
+// copyMedia writes the media of the post to the path the API serves it at.
+// Uploads never change, so existing files are kept.
+func (e *Exporter) copyMedia(post models.Post) error {
+	if e.Storage == nil {
+		return nil
+	}
+
+	for _, media := range post.Media {
+		name := strings.TrimPrefix(media.URL, "/")
+		if e.exists(name) {
+			continue
+		}
+
+		err := e.copyObject(media, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *Exporter) copyObject(media models.Media, name string) error {
+	found := models.Media{}
+	_, err := found.GetMediaByID(e.DB, media.ID)
+	if err != nil {
+		return err
+	}
+
+	content, err := e.Storage.Get(context.Background(), found.Key)
+	if err != nil {
+		return fmt.Errorf("media %d: %v", media.ID, err)
+	}
+	defer content.Close()
+
+	data, err := ioutil.ReadAll(content)
+	if err != nil {
+		return err
+	}
+
+	return e.writeFile(name, data)
+}
+
+var redirect = template.Must(template.New("redirect").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Moved</title>
+  <link rel="canonical" href="{{.}}">
+  <meta http-equiv="refresh" content="0; url={{.}}">
+</head>
+<body>
+  <p>This post has moved to <a href="{{.}}">{{.}}</a>.</p>
+</body>
+</html>
+`))
+
+// writeRedirects sends the old slugs of posts on to their current page and
+// removes the redirects of posts that are no longer published. It returns
+// the paths redirected.
+func (e *Exporter) writeRedirects(posts []models.Post, previous []string) ([]string, error) {
+	slugs := map[uint64]string{}
+	for _, post := range posts {
+		slugs[post.ID] = post.Slug
+	}
+
+	old, err := models.GetOldSlugs(e.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	written := map[string]bool{}
+	paths := []string{}
+
+	for _, s := range *old {
+		current, ok := slugs[s.PostID]
+		if !ok {
+			continue
+		}
+
+		var out strings.Builder
+		err = redirect.Execute(&out, frontend.PostPath(current))
+		if err != nil {
+			return nil, err
+		}
+
+		p := frontend.PostPath(s.Slug)
+		err = e.writeFile(indexFile(p), []byte(out.String()))
+		if err != nil {
+			return nil, err
+		}
+
+		written[p] = true
+		paths = append(paths, p)
+	}
+
+	for _, p := range previous {
+		if written[p] {
+			continue
+		}
+
+		// the path may be the page of a post again
+		if e.isRedirect(p) {
+			err = os.RemoveAll(e.path(p))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// isRedirect reports whether the page at urlPath is a redirect page
+func (e *Exporter) isRedirect(urlPath string) bool {
+	data, err := ioutil.ReadFile(e.path(indexFile(urlPath)))
+	return err == nil && strings.Contains(string(data), `http-equiv="refresh"`)
+}
+
+// writeListings writes the front page and the author pages, split into
+// pages of frontend.PageSize posts. It returns the files written.
+func (e *Exporter) writeListings(posts []models.Post) ([]string, error) {
+	excerpts := make([]models.Post, len(posts))
+	for i, post := range posts {
+		err := post.Render(render.FormatPlain)
+		if err != nil {
+			return nil, err
+		}
+		post.ContentText = render.Excerpt(post.ContentText, frontend.ExcerptLength)
+		excerpts[i] = post
+	}
+
+	data := frontend.PageData{Site: frontend.SiteTitle, Feed: "/feed.atom"}
+	files, err := e.writeListing("/", frontend.PageIndex, data, excerpts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, author := range authors(excerpts) {
+		data := frontend.PageData{
+			Site:   frontend.SiteTitle,
+			Title:  html.UnescapeString(author.Username),
+			Feed:   fmt.Sprintf("/api/users/%d/feed.atom", author.ID),
+			Author: author,
+		}
+
+		written, err := e.writeListing(authorPath(author.ID), frontend.PageAuthor, data, byAuthor(excerpts, author.ID))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, written...)
+	}
+
+	return files, nil
+}
+
+func (e *Exporter) writeListing(dir, page string, data frontend.PageData, posts []models.Post) ([]string, error) {
+	files := []string{}
+
+	for n := 1; n == 1 || (n-1)*frontend.PageSize < len(posts); n++ {
+		start := (n - 1) * frontend.PageSize
+		end := start + frontend.PageSize
+		if end > len(posts) {
+			end = len(posts)
+		}
+
+		data.Posts = posts[start:end]
+		data.NextPage = ""
+		if end < len(posts) {
+			data.NextPage = pagePath(dir, n+1)
+		}
+
+		err := e.writePage(pagePath(dir, n), page, data)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, indexFile(pagePath(dir, n)))
+	}
+
+	return files, nil
+}
+
+// writeFeeds writes the site feed and the feed of every author in each
+// format, at the paths the API serves them at. It returns the files written.
+func (e *Exporter) writeFeeds(posts []models.Post) ([]string, error) {
+	latest := posts
+	if len(latest) > models.DefaultFeedLength {
+		latest = latest[:models.DefaultFeedLength]
+	}
+
+	feed := feeds.Feed{
+		Title:       frontend.SiteTitle,
+		Description: frontend.SiteDescription,
+		Link:        e.BaseURL + "/",
+	}
+
+	files, err := e.writeFeed("/feed", feed, latest)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, author := range authors(posts) {
+		latest := byAuthor(posts, author.ID)
+		if len(latest) > models.DefaultFeedLength {
+			latest = latest[:models.DefaultFeedLength]
+		}
+
+		feed := feeds.Feed{
+			Title:       fmt.Sprintf("%s - %s", frontend.SiteTitle, html.UnescapeString(author.Username)),
+			Description: fmt.Sprintf("The latest posts by %s", html.UnescapeString(author.Username)),
+			Link:        e.BaseURL + authorPath(author.ID),
+		}
+
+		written, err := e.writeFeed(fmt.Sprintf("/api/users/%d/feed", author.ID), feed, latest)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, written...)
+	}
+
+	return files, nil
+}
+
+func (e *Exporter) writeFeed(name string, feed feeds.Feed, posts []models.Post) ([]string, error) {
+	items, err := feeds.PostItems(e.BaseURL, posts)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+
+	feed.Items = items
+	feed.Updated = feeds.LastUpdated(posts)
+
+	for _, format := range []string{feeds.FormatRSS, feeds.FormatAtom, feeds.FormatJSON} {
+		feed.FeedURL = e.BaseURL + name + "." + format
+
+		body, err := feeds.Write(feed, format)
+		if err != nil {
+			return nil, err
+		}
+
+		err = e.writeFile(name+"."+format, body)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, name+"."+format)
+	}
+
+	return files, nil
+}
+
+// removeStale removes the files of the previous export that the current one
+// did not write again
+func (e *Exporter) removeStale(previous, current []string) error {
+	written := map[string]bool{}
+	for _, name := range current {
+		written[name] = true
+	}
+
+	for _, name := range previous {
+		if written[name] {
+			continue
+		}
+
+		err := e.removeFile(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeFile removes name below Dir, then the directories that leaves empty
+func (e *Exporter) removeFile(name string) error {
+	file := e.path(name)
+
+	err := os.Remove(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	root := filepath.Clean(e.Dir)
+	for dir := filepath.Dir(file); dir != root; dir = filepath.Dir(dir) {
+		// fails once a directory holds something else
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (e *Exporter) writePage(urlPath, page string, data frontend.PageData) error {
+	var out strings.Builder
+
+	err := e.Theme.Execute(&out, page, data)
+	if err != nil {
+		return err
+	}
+
+	return e.writeFile(indexFile(urlPath), []byte(out.String()))
+}
+
+func (e *Exporter) readManifest() (manifest, error) {
+	m := manifest{Posts: map[string]manifestPost{}}
+
+	data, err := ioutil.ReadFile(e.path(ManifestFile))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+
+	if err != nil {
+		return m, err
+	}
+
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		return m, fmt.Errorf("%s: %v", ManifestFile, err)
+	}
+
+	if m.Posts == nil {
+		m.Posts = map[string]manifestPost{}
+	}
+
+	return m, nil
+}
+
+func (e *Exporter) writeManifest(m manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return e.writeFile(ManifestFile, data)
+}
+
+// writeFile writes data to name below Dir through a temporary file, so a
+// host serving the directory never sees a partly written file
+func (e *Exporter) writeFile(name string, data []byte) error {
+	file := e.path(name)
+
+	err := os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(file), ".export-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
+
+func (e *Exporter) exists(name string) bool {
+	_, err := os.Stat(e.path(name))
+	return err == nil
+}
+
+// path is the file of a slash separated name below Dir
+func (e *Exporter) path(name string) string {
+	return filepath.Join(e.Dir, filepath.FromSlash(path.Clean("/"+name)))
+}
+
+// indexFile is the file served for a page URL path
+func indexFile(urlPath string) string {
+	return path.Join(urlPath, "index.html")
+}
+
+// pagePath is the path of page n of the listing at dir
+func pagePath(dir string, n int) string {
+	if n == 1 {
+		return dir
+	}
+	return path.Join(dir, "page", strconv.Itoa(n)) + "/"
+}
+
+func authorPath(uid uint32) string {
+	return fmt.Sprintf("/authors/%d/", uid)
+}
+
+// authors returns the authors of the posts, ordered by id
+func authors(posts []models.Post) []models.User {
+	seen := map[uint32]bool{}
+	users := []models.User{}
+
+	for _, post := range posts {
+		if !seen[post.AuthorID] {
+			seen[post.AuthorID] = true
+			users = append(users, post.Author)
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+func byAuthor(posts []models.Post, uid uint32) []models.Post {
+	result := []models.Post{}
+	for _, post := range posts {
+		if post.AuthorID == uid {
+			result = append(result, post)
+		}
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+package export
+
+import (
+	"encoding/xml"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/frontend"
+	"github.com/dmdinh22/go-blog/api/models"
+)
+
+// SitemapNS is the namespace of the sitemaps.org protocol
+const SitemapNS = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+type urlSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	NS      string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// writeSitemap lists the front page, the author pages and every post
+func (e *Exporter) writeSitemap(posts []models.Post) error {
+	set := urlSet{NS: SitemapNS}
+	set.URLs = append(set.URLs, sitemapURL{Loc: e.BaseURL + "/", LastMod: lastMod(posts)})
+
+	for _, author := range authors(posts) {
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:     e.BaseURL + authorPath(author.ID),
+			LastMod: lastMod(byAuthor(posts, author.ID)),
+		})
+	}
+
+	for _, post := range posts {
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:     e.BaseURL + frontend.PostPath(post.Slug),
+			LastMod: post.UpdatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+
+	data, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return e.writeFile("sitemap.xml", append([]byte(xml.Header), append(data, '\n')...))
+}
+
+// lastMod is the time the newest of the posts was updated
+func lastMod(posts []models.Post) string {
+	latest := time.Time{}
+	for _, post := range posts {
+		if post.UpdatedAt.After(latest) {
+			latest = post.UpdatedAt
+		}
+	}
+
+	if latest.IsZero() {
+		return ""
+	}
+	return latest.UTC().Format(time.RFC3339)
+}
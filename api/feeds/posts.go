@@ -0,0 +1,67 @@
+package feeds
+
+import (
+	"fmt"
+	"html"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/frontend"
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/dmdinh22/go-blog/api/render"
+)
+
+// SummaryLength is the number of characters of a post shown as the summary
+// of its entry
+const SummaryLength = 200
+
+// PostItems turns posts into feed entries linking to their pages below base,
+// the scheme and host of the blog.
+func PostItems(base string, posts []models.Post) ([]Item, error) {
+	items := []Item{}
+
+	for _, post := range posts {
+		content, err := render.HTML(post.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		summary, err := render.Plain(post.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		item := Item{
+			// the id stays the same when the title, and so the slug, changes
+			ID:        fmt.Sprintf("%s/api/posts/%d", base, post.ID),
+			Title:     html.UnescapeString(post.Title),
+			Link:      base + frontend.PostPath(post.Slug),
+			Author:    html.UnescapeString(post.Author.Username),
+			Summary:   render.Excerpt(summary, SummaryLength),
+			Content:   content,
+			Published: frontend.Published(post),
+			Updated:   post.UpdatedAt,
+		}
+
+		for _, tag := range post.Tags {
+			item.Tags = append(item.Tags, tag.Name)
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// LastUpdated is the newest UpdatedAt of the posts, the zero time when
+// there are none
+func LastUpdated(posts []models.Post) time.Time {
+	updated := time.Time{}
+
+	for _, post := range posts {
+		if post.UpdatedAt.After(updated) {
+			updated = post.UpdatedAt
+		}
+	}
+
+	return updated
+}
@@ -0,0 +1,168 @@
+// Package frontend renders the public pages of the blog with html/template.
+//
+// The default theme is embedded in the binary. A theme directory can
+// override any of its templates by holding a file of the same name.
+package frontend
+
+import (
+	"bytes"
+	"embed"
+	"html"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/models"
+)
+
+// SiteTitle and SiteDescription name the blog on its pages and feeds
+const (
+	SiteTitle       = "Go Blog"
+	SiteDescription = "The latest posts"
+)
+
+const (
+	// PageSize is the number of posts on a page of a listing
+	PageSize = 10
+
+	// ExcerptLength is the number of characters of a post shown in listings
+	ExcerptLength = 280
+)
+
+// Pages that can be rendered. Each is rendered inside layout.html.
+const (
+	PageIndex  = "index.html"
+	PagePost   = "post.html"
+	PageAuthor = "author.html"
+	PageError  = "error.html"
+)
+
+const layout = "layout.html"
+
+var pages = []string{PageIndex, PagePost, PageAuthor, PageError}
+
+//go:embed templates/*.html
+var defaults embed.FS
+
+var funcs = template.FuncMap{
+	// titles and usernames are stored html escaped
+	"title": html.UnescapeString,
+	"name":  html.UnescapeString,
+	"date": func(t time.Time) string {
+		return t.Format("January 2, 2006")
+	},
+	"published": Published,
+	"postPath":  func(p models.Post) string { return PostPath(p.Slug) },
+	// only for HTML that went through the sanitizer in render
+	"sanitized": func(s string) template.HTML {
+		return template.HTML(s)
+	},
+}
+
+// Theme holds the parsed templates of every page
+type Theme struct {
+	pages map[string]*template.Template
+}
+
+// Load parses the embedded theme, using the templates in dir instead where
+// it has them. An empty dir loads the embedded theme alone.
+func Load(dir string) (*Theme, error) {
+	base, err := readTemplate(dir, layout)
+	if err != nil {
+		return nil, err
+	}
+
+	theme := &Theme{pages: map[string]*template.Template{}}
+
+	for _, page := range pages {
+		text, err := readTemplate(dir, page)
+		if err != nil {
+			return nil, err
+		}
+
+		t, err := template.New(layout).Funcs(funcs).Parse(string(base))
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = t.New(page).Parse(string(text))
+		if err != nil {
+			return nil, err
+		}
+
+		theme.pages[page] = t
+	}
+
+	return theme, nil
+}
+
+func readTemplate(dir, name string) ([]byte, error) {
+	if dir != "" {
+		text, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err == nil || !os.IsNotExist(err) {
+			return text, err
+		}
+	}
+
+	return defaults.ReadFile("templates/" + name)
+}
+
+// Execute writes the page to w
+func (t *Theme) Execute(w io.Writer, page string, data PageData) error {
+	return t.pages[page].ExecuteTemplate(w, layout, data)
+}
+
+// Render writes the page with the given status. The page is rendered in
+// full before anything is written, so a template error can still become a
+// 500.
+func (t *Theme) Render(w http.ResponseWriter, status int, page string, data PageData) error {
+	var buf bytes.Buffer
+
+	err := t.Execute(&buf, page, data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+
+	return nil
+}
+
+// PostPath is the path of the page of the post with the given slug
+func PostPath(slug string) string {
+	return "/posts/" + slug
+}
+
+// Published is the date a post is shown with
+func Published(p models.Post) time.Time {
+	if p.PublishAt != nil {
+		return *p.PublishAt
+	}
+	return p.CreatedAt
+}
+
+// PageData is what every page is rendered with. Pages use the fields that
+// apply to them.
+type PageData struct {
+	Site  string
+	Title string
+	// Feed is the path of the feed of the page, if it has one
+	Feed string
+
+	Posts []models.Post
+	// NextPage is the path of the page of older posts, empty on the last
+	// page
+	NextPage string
+
+	Post   models.Post
+	Author models.User
+
+	Status  int
+	Message string
+}
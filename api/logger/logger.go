@@ -0,0 +1,266 @@
+// Package logger writes leveled, structured log lines, either as logfmt
+// style text:
+//
+//	time=2020-01-02T15:04:05Z level=info msg="Listening" addr=:8080
+//
+// or as one JSON object per line. Messages take key value pairs after the
+// message, e.g. logger.Info("Published scheduled posts", "count", 3).
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a message. Messages below the level of a logger
+// are dropped.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return "level(" + strconv.Itoa(int(l)) + ")"
+	}
+
+	return levelNames[l]
+}
+
+// ParseLevel reads debug, info, warn or error
+func ParseLevel(name string) (Level, error) {
+	for i, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return Level(i), nil
+		}
+	}
+
+	return LevelInfo, fmt.Errorf("%q is not debug, info, warn or error", name)
+}
+
+// ValidFormat reports whether format is text or json
+func ValidFormat(format string) bool {
+	return format == FormatText || format == FormatJSON
+}
+
+// Logger writes messages at or above its level to its writer. Loggers made
+// with With share the writer of their parent.
+type Logger struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	format string
+	level  Level
+	// fields are key value pairs added to every message
+	fields []interface{}
+}
+
+// New returns a logger writing to out in the text or json format
+func New(out io.Writer, format string, level Level) *Logger {
+	return &Logger{mu: &sync.Mutex{}, out: out, format: format, level: level}
+}
+
+// With returns a logger adding the key value pairs to every message
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	child := *l
+	child.fields = append(append([]interface{}{}, l.fields...), keyvals...)
+	return &child
+}
+
+// Enabled reports whether messages at level are written
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.log(LevelDebug, msg, keyvals)
+}
+
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.log(LevelInfo, msg, keyvals)
+}
+
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.log(LevelWarn, msg, keyvals)
+}
+
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.log(LevelError, msg, keyvals)
+}
+
+// Fatal writes an error message and exits
+func (l *Logger) Fatal(msg string, keyvals ...interface{}) {
+	l.log(LevelError, msg, keyvals)
+	os.Exit(1)
+}
+
+func (l *Logger) log(level Level, msg string, keyvals []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	all := append([]interface{}{"time", time.Now().UTC().Format(time.RFC3339Nano), "level", level.String(), "msg", msg}, l.fields...)
+	all = append(all, keyvals...)
+
+	// a key without a value is kept as the value of a missing key
+	if len(all)%2 == 1 {
+		all = append(all[:len(all)-1], "!BADKEY", all[len(all)-1])
+	}
+
+	buf := bytes.Buffer{}
+	if l.format == FormatJSON {
+		writeJSON(&buf, all)
+	} else {
+		writeText(&buf, all)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(buf.Bytes())
+}
+
+// value turns errors and Stringers like time.Duration into strings, which
+// the json format would not show well
+func value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	return v
+}
+
+func writeText(buf *bytes.Buffer, keyvals []interface{}) {
+	for i := 0; i < len(keyvals); i += 2 {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+
+		buf.WriteString(fmt.Sprint(keyvals[i]))
+		buf.WriteByte('=')
+
+		s := fmt.Sprint(value(keyvals[i+1]))
+		if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+			s = strconv.Quote(s)
+		}
+		buf.WriteString(s)
+	}
+
+	buf.WriteByte('\n')
+}
+
+func writeJSON(buf *bytes.Buffer, keyvals []interface{}) {
+	buf.WriteByte('{')
+
+	for i := 0; i < len(keyvals); i += 2 {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(fmt.Sprint(keyvals[i]))
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		data, err := json.Marshal(value(keyvals[i+1]))
+		if err != nil {
+			data, _ = json.Marshal(fmt.Sprint(keyvals[i+1]))
+		}
+		buf.Write(data)
+	}
+
+	buf.WriteString("}\n")
+}
+
+var (
+	defaultMu sync.RWMutex
+	std       = New(os.Stderr, FormatText, LevelInfo)
+)
+
+// Default returns the logger set with SetDefault, text at info level on
+// standard error until then
+func Default() *Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return std
+}
+
+func SetDefault(l *Logger) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	std = l
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying l, e.g. a logger with the request ID
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger of ctx, the default logger when it has none
+func FromContext(ctx context.Context) *Logger {
+	l, ok := ctx.Value(contextKey{}).(*Logger)
+	if !ok {
+		return Default()
+	}
+
+	return l
+}
+
+// SQLLogger writes what gorm logs, see gorm.DB.SetLogger. Queries are
+// only logged when the log mode of the database is on.
+type SQLLogger struct {
+	Logger *Logger
+}
+
+func (s SQLLogger) Print(values ...interface{}) {
+	l := s.Logger
+	if l == nil {
+		l = Default()
+	}
+
+	if len(values) < 2 {
+		l.Info("gorm", "values", fmt.Sprint(values...))
+		return
+	}
+
+	switch values[0] {
+	case "sql":
+		if len(values) < 6 {
+			break
+		}
+		// only the number of bound values, they may be passwords or tokens
+		l.Info("SQL", "source", values[1], "duration", values[2], "query", values[3], "vars", len(toSlice(values[4])), "rows", values[5])
+		return
+	case "error", "log":
+		l.Error("Database error", "source", values[1], "error", fmt.Sprint(values[2:]...))
+		return
+	}
+
+	l.Info("gorm", "source", values[1], "values", fmt.Sprint(values[2:]...))
+}
+
+func toSlice(v interface{}) []interface{} {
+	vars, _ := v.([]interface{})
+	return vars
+}
@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// unmatchedRoute labels the requests no route of the router matched, so
+// unknown paths cannot add labels without bound
+const unmatchedRoute = "unmatched"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Requests served by method, route and status.",
+	}, []string{"method", "route", "status"})
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time to serve requests by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// routeKey holds, in the context of a request, where recordRoute stores
+// the template of the route mux matched
+type routeKey struct{}
+
+// SetMiddlewareMetrics serves router, counting its requests and timing
+// them. They are labelled with the template of the route that matched
+// them, e.g. /api/posts/{id}, not with their path.
+func SetMiddlewareMetrics(router *mux.Router) http.Handler {
+	router.Use(recordRoute)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		route := unmatchedRoute
+		router.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), routeKey{}, &route)))
+
+		httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(recorder.status)).Inc()
+		httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// recordRoute runs once mux matched a route, so requests no route matched
+// keep the unmatched label
+func recordRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, ok := r.Context().Value(routeKey{}).(*string)
+		if current := mux.CurrentRoute(r); ok && current != nil {
+			template, err := current.GetPathTemplate()
+			if err == nil {
+				*route = template
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -16,15 +16,50 @@ func SetMiddlewareJSON(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// SetMiddlewareAuthentication rejects requests without a valid token and
+// stores the caller in the request context, see auth.FromContext.
 func SetMiddlewareAuthentication(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		err := auth.ValidateToken(r)
+		principal, err := auth.ParseToken(r)
 
 		if err != nil {
 			responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
 			return
 		}
 
+		next(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+	}
+}
+
+// SetMiddlewareOptionalAuthentication stores the caller in the request
+// context when a token is given and lets anonymous requests through.
+func SetMiddlewareOptionalAuthentication(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if auth.ExtractToken(r) == "" {
+			next(w, r)
+			return
+		}
+
+		SetMiddlewareAuthentication(next)(w, r)
+	}
+}
+
+// SetMiddlewarePermission only lets through callers whose roles grant the
+// permission. It must be wrapped by SetMiddlewareAuthentication.
+func SetMiddlewarePermission(permission string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.FromContext(r.Context())
+
+		if !ok {
+			responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+			return
+		}
+
+		if !principal.Can(permission) {
+			responses.ERROR(w, http.StatusForbidden, errors.New("Forbidden"))
+			return
+		}
+
 		next(w, r)
 	}
 }
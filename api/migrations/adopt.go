@@ -0,0 +1,88 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Initial is the migration creating the schema. It keeps the tables that
+// exist, so on a database AutoMigrate created before migrations the users
+// and posts tables need what later versions of the models added; those
+// adoptions run first, in the same migration.
+const Initial = "0001_initial"
+
+// adoption adds a column, index or foreign key an existing table lacks
+type adoption struct {
+	table string
+	name  string
+	// exists counts what statement adds, given table and name
+	exists    func(d dialect) string
+	statement string
+}
+
+func addColumn(table, name, definition string) adoption {
+	return adoption{
+		table:     table,
+		name:      name,
+		exists:    func(d dialect) string { return d.columnExists },
+		statement: fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, name, definition),
+	}
+}
+
+func addIndex(table, name, definition string) adoption {
+	return adoption{
+		table:     table,
+		name:      name,
+		exists:    func(d dialect) string { return d.indexExists },
+		statement: fmt.Sprintf("ALTER TABLE %s ADD %s", table, definition),
+	}
+}
+
+// addForeignKey references users from column, named the way gorm named
+// the foreign keys it added
+func addForeignKey(table, column string) adoption {
+	return adoption{
+		table:     table,
+		name:      column,
+		exists:    func(d dialect) string { return d.foreignKeyExists },
+		statement: fmt.Sprintf("ALTER TABLE %[1]s ADD CONSTRAINT %[1]s_%[2]s_users_id_foreign FOREIGN KEY (%[2]s) REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE", table, column),
+	}
+}
+
+// adopt returns the statements adding to the existing tables what they
+// lack, nothing on a new database
+func (m *Migrator) adopt(ctx context.Context, conn *sql.Conn, d dialect) ([]string, error) {
+	statements := []string{}
+	tables := map[string]bool{}
+
+	for _, a := range d.adoptions {
+		exists, ok := tables[a.table]
+		if !ok {
+			var count int
+			err := conn.QueryRowContext(ctx, d.tableExists, a.table).Scan(&count)
+			if err != nil {
+				return nil, err
+			}
+
+			exists = count > 0
+			tables[a.table] = exists
+		}
+
+		if !exists {
+			continue
+		}
+
+		var count int
+		err := conn.QueryRowContext(ctx, a.exists(d), a.table, a.name).Scan(&count)
+		if err != nil {
+			return nil, err
+		}
+
+		if count == 0 {
+			statements = append(statements, a.statement)
+		}
+	}
+
+	return statements, nil
+}
@@ -0,0 +1,159 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// dialect is what differs between the drivers when running migrations
+type dialect struct {
+	placeholder func(n int) string
+	// transactional drivers can roll back DDL
+	transactional bool
+	lock          func(ctx context.Context, conn *sql.Conn, timeout time.Duration) error
+	unlock        func(ctx context.Context, conn *sql.Conn) error
+	// tableExists counts the tables named by its one parameter
+	tableExists string
+	createTable string
+
+	// columnExists, indexExists and foreignKeyExists count the columns,
+	// indexes and foreign keys of a table, given the table and the column
+	// or index name
+	columnExists     string
+	indexExists      string
+	foreignKeyExists string
+	adoptions        []adoption
+}
+
+const createTable = `CREATE TABLE IF NOT EXISTS ` + Table + ` (
+  version bigint NOT NULL PRIMARY KEY,
+  name varchar(255) NOT NULL,
+  applied_at timestamp NOT NULL,
+  dirty boolean NOT NULL
+)`
+
+func dialectFor(driver string) (dialect, error) {
+	switch driver {
+	case "mysql":
+		return dialect{
+			placeholder: questionMark,
+			lock:        mysqlLock,
+			unlock:      mysqlUnlock,
+			tableExists: "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?",
+			createTable: createTable,
+
+			columnExists:     "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?",
+			indexExists:      "SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?",
+			foreignKeyExists: "SELECT COUNT(*) FROM information_schema.key_column_usage WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ? AND referenced_table_name IS NOT NULL",
+			// mysql declares the indexes of posts in CREATE TABLE
+			adoptions: []adoption{
+				addColumn("users", "role", "varchar(20) NOT NULL DEFAULT 'user'"),
+				addColumn("posts", "slug", "varchar(255)"),
+				addColumn("posts", "status", "varchar(20) NOT NULL DEFAULT 'published'"),
+				addColumn("posts", "publish_at", "datetime NULL"),
+				addIndex("posts", "uix_posts_slug", "UNIQUE KEY uix_posts_slug (slug)"),
+				addIndex("posts", "idx_posts_status", "KEY idx_posts_status (status)"),
+				addIndex("posts", "idx_posts_publish_at", "KEY idx_posts_publish_at (publish_at)"),
+				addIndex("posts", "idx_posts_fulltext", "FULLTEXT KEY idx_posts_fulltext (title, content)"),
+				addForeignKey("posts", "author_id"),
+			},
+		}, nil
+	case "postgres":
+		return dialect{
+			placeholder:   func(n int) string { return fmt.Sprintf("$%d", n) },
+			transactional: true,
+			lock:          postgresLock,
+			unlock:        postgresUnlock,
+			tableExists:   "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1",
+			createTable:   createTable,
+
+			columnExists: "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1 AND column_name = $2",
+			foreignKeyExists: `SELECT COUNT(*) FROM information_schema.table_constraints c
+  JOIN information_schema.key_column_usage k ON k.constraint_schema = c.constraint_schema AND k.constraint_name = c.constraint_name
+  WHERE c.constraint_type = 'FOREIGN KEY' AND c.table_schema = current_schema() AND c.table_name = $1 AND k.column_name = $2`,
+			// the indexes are created with IF NOT EXISTS
+			adoptions: []adoption{
+				addColumn("users", "role", "varchar(20) NOT NULL DEFAULT 'user'"),
+				addColumn("posts", "slug", "varchar(255)"),
+				addColumn("posts", "status", "varchar(20) NOT NULL DEFAULT 'published'"),
+				addColumn("posts", "publish_at", "timestamp with time zone"),
+				addForeignKey("posts", "author_id"),
+			},
+		}, nil
+	case "sqlite3":
+		// sqlite takes the database lock for every write transaction
+		return dialect{
+			placeholder:   questionMark,
+			transactional: true,
+			lock:          func(context.Context, *sql.Conn, time.Duration) error { return nil },
+			unlock:        func(context.Context, *sql.Conn) error { return nil },
+			tableExists:   "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
+			createTable:   createTable,
+
+			columnExists: "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?",
+			// sqlite cannot add a foreign key to an existing table
+			adoptions: []adoption{
+				addColumn("users", "role", "varchar(20) NOT NULL DEFAULT 'user'"),
+				addColumn("posts", "slug", "varchar(255)"),
+				addColumn("posts", "status", "varchar(20) NOT NULL DEFAULT 'published'"),
+				addColumn("posts", "publish_at", "datetime"),
+			},
+		}, nil
+	}
+
+	return dialect{}, fmt.Errorf("%w %q", ErrUnsupportedDriver, driver)
+}
+
+func questionMark(int) string {
+	return "?"
+}
+
+// mysqlLock takes a named lock, held until unlocked or the connection closes
+func mysqlLock(ctx context.Context, conn *sql.Conn, timeout time.Duration) error {
+	var got sql.NullInt64
+	err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, int(timeout.Seconds())).Scan(&got)
+	if err != nil {
+		return err
+	}
+
+	if !got.Valid || got.Int64 != 1 {
+		return fmt.Errorf("Timed out after %v waiting for the migration lock", timeout)
+	}
+
+	return nil
+}
+
+func mysqlUnlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", lockName)
+	return err
+}
+
+// postgresLock takes a session advisory lock, polling so it can give up
+func postgresLock(ctx context.Context, conn *sql.Conn, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		var got bool
+		err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockKey).Scan(&got)
+		if err != nil || got {
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Timed out after %v waiting for the migration lock", timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+func postgresUnlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lockKey)
+	return err
+}
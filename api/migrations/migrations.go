@@ -0,0 +1,145 @@
+// Package migrations versions the database schema with numbered SQL files,
+// one directory per driver:
+//
+//	mysql/0002_add_likes.up.sql
+//	mysql/0002_add_likes.down.sql
+//
+// Applied versions are recorded in the schema_migrations table. Statements
+// in a file are separated by a semicolon at the end of a line.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed mysql/*.sql postgres/*.sql sqlite3/*.sql
+var files embed.FS
+
+var (
+	ErrUnsupportedDriver = errors.New("Unsupported database driver")
+	ErrDirty             = errors.New("A migration failed half way, fix the schema by hand before migrating again")
+)
+
+// Migration is a numbered schema change and the SQL undoing it
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// ID names the migration the way its files are named, e.g. 0001_initial
+func (m Migration) ID() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// ForDriver returns the migrations of the driver, oldest first
+func ForDriver(driver string) ([]Migration, error) {
+	dir, err := fs.Sub(files, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := fs.ReadDir(dir, ".")
+	if err != nil || len(entries) == 0 {
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedDriver, driver)
+	}
+
+	return Load(dir)
+}
+
+// Load reads the migrations in the root of fsys, oldest first. Every
+// version needs both an up and a down file.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("Invalid migration file name %q", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("Invalid migration version in %q", entry.Name())
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("Migration %d has two names, %q and %q", version, m.Name, match[2])
+		}
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	all := []Migration{}
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			return nil, fmt.Errorf("Migration %s needs an up and a down file", m.ID())
+		}
+		all = append(all, *m)
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all, nil
+}
+
+// Statements splits the SQL of a migration into the statements to run one
+// by one. Lines that only hold a -- comment are dropped.
+func Statements(sql string) []string {
+	statements := []string{}
+	current := []string{}
+
+	for _, line := range strings.Split(sql, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" && len(current) == 0 || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current = append(current, strings.TrimRight(line, " \t\r"))
+
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(strings.Join(current, "\n")), ";"))
+			current = nil
+		}
+	}
+
+	if rest := strings.TrimSpace(strings.Join(current, "\n")); rest != "" {
+		statements = append(statements, rest)
+	}
+
+	return statements
+}
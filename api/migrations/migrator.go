@@ -0,0 +1,358 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	// Table records the applied migrations
+	Table = "schema_migrations"
+
+	// DefaultLockTimeout is how long a migrator waits for another replica
+	// to finish migrating
+	DefaultLockTimeout = time.Minute
+
+	lockName = "go-blog:" + Table
+	// lockKey is the postgres advisory lock id, any constant will do
+	lockKey = 847206413
+)
+
+// Status is a migration and whether it has been applied
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	// Dirty is set when the migration failed half way
+	Dirty bool
+}
+
+// Migrator applies and reverts the migrations of a driver. Every run holds
+// a database lock, so replicas starting together migrate one at a time.
+type Migrator struct {
+	DB         *sql.DB
+	Driver     string
+	Migrations []Migration
+
+	// DryRun writes the SQL that would run to Out, os.Stdout when nil,
+	// instead of running it
+	DryRun bool
+	Out    io.Writer
+
+	LockTimeout time.Duration
+}
+
+// New returns a migrator for the embedded migrations of the driver
+func New(db *sql.DB, driver string) (*Migrator, error) {
+	migrations, err := ForDriver(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{DB: db, Driver: driver, Migrations: migrations, LockTimeout: DefaultLockTimeout}, nil
+}
+
+// Up applies every pending migration, oldest first, and returns them
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	done := []Migration{}
+
+	err := m.run(ctx, func(conn *sql.Conn, d dialect) error {
+		status, err := m.pending(ctx, conn, d)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range status {
+			if s.Applied {
+				continue
+			}
+
+			statements := Statements(s.Migration.Up)
+			if s.ID() == Initial {
+				adopted, err := m.adopt(ctx, conn, d)
+				if err != nil {
+					return err
+				}
+				statements = append(adopted, statements...)
+			}
+
+			err = m.apply(ctx, conn, d, s.Migration, statements, true)
+			if err != nil {
+				return err
+			}
+			done = append(done, s.Migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and
+// returns them
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	done := []Migration{}
+
+	err := m.run(ctx, func(conn *sql.Conn, d dialect) error {
+		status, err := m.pending(ctx, conn, d)
+		if err != nil {
+			return err
+		}
+
+		for i := len(status) - 1; i >= 0 && len(done) < steps; i-- {
+			if !status[i].Applied {
+				continue
+			}
+
+			err = m.apply(ctx, conn, d, status[i].Migration, Statements(status[i].Migration.Down), false)
+			if err != nil {
+				return err
+			}
+			done = append(done, status[i].Migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Status lists every migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	d, err := dialectFor(m.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return m.status(ctx, conn, d)
+}
+
+// Name and Check make the migrator a health check of the server
+func (m *Migrator) Name() string {
+	return "migrations"
+}
+
+// Check fails while migrations are pending or one failed half way
+func (m *Migrator) Check(ctx context.Context) error {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, s := range status {
+		if s.Dirty {
+			return fmt.Errorf("%w: %s", ErrDirty, s.ID())
+		}
+
+		if !s.Applied {
+			pending++
+		}
+	}
+
+	if pending > 0 {
+		return fmt.Errorf("%d of %d migrations are not applied", pending, len(status))
+	}
+
+	return nil
+}
+
+// run calls fn holding the migration lock on a single connection. Dry runs
+// only read, so they neither lock nor create the table.
+func (m *Migrator) run(ctx context.Context, fn func(*sql.Conn, dialect) error) error {
+	d, err := dialectFor(m.Driver)
+	if err != nil {
+		return err
+	}
+
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.DryRun {
+		return fn(conn, d)
+	}
+
+	timeout := m.LockTimeout
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+
+	err = d.lock(ctx, conn, timeout)
+	if err != nil {
+		return err
+	}
+	defer d.unlock(context.Background(), conn)
+
+	_, err = conn.ExecContext(ctx, d.createTable)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn, d)
+}
+
+// pending returns the status of the migrations, refusing to go on when one
+// failed half way
+func (m *Migrator) pending(ctx context.Context, conn *sql.Conn, d dialect) ([]Status, error) {
+	status, err := m.status(ctx, conn, d)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range status {
+		if s.Dirty {
+			return nil, fmt.Errorf("%w: %s", ErrDirty, s.ID())
+		}
+	}
+
+	return status, nil
+}
+
+// status merges the migrations with the rows of the table. A missing table
+// means nothing has been applied.
+func (m *Migrator) status(ctx context.Context, conn *sql.Conn, d dialect) ([]Status, error) {
+	var count int
+	err := conn.QueryRowContext(ctx, d.tableExists, Table).Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]Status, len(m.Migrations))
+	for i, migration := range m.Migrations {
+		status[i] = Status{Migration: migration}
+	}
+
+	if count == 0 {
+		return status, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at, dirty FROM "+Table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]Status{}
+	for rows.Next() {
+		s := Status{Applied: true}
+		err = rows.Scan(&s.Version, &s.AppliedAt, &s.Dirty)
+		if err != nil {
+			return nil, err
+		}
+		applied[s.Version] = s
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range status {
+		s, ok := applied[status[i].Version]
+		if !ok {
+			continue
+		}
+
+		status[i].Applied = true
+		status[i].AppliedAt = s.AppliedAt
+		status[i].Dirty = s.Dirty
+	}
+
+	return status, nil
+}
+
+// apply runs the up or down statements of a migration and records it.
+// Drivers with transactional DDL run them in a transaction; on the others
+// the version is marked dirty until every statement succeeded.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, d dialect, migration Migration, statements []string, up bool) error {
+	direction := "down"
+	if up {
+		direction = "up"
+	}
+
+	if m.DryRun {
+		out := m.Out
+		if out == nil {
+			out = os.Stdout
+		}
+
+		fmt.Fprintf(out, "-- %s (%s)\n", migration.ID(), direction)
+		for _, statement := range statements {
+			fmt.Fprintf(out, "%s;\n", statement)
+		}
+		fmt.Fprintln(out)
+		return nil
+	}
+
+	insert := fmt.Sprintf("INSERT INTO %s (version, name, applied_at, dirty) VALUES (%s, %s, %s, %s)", Table, d.placeholder(1), d.placeholder(2), d.placeholder(3), d.placeholder(4))
+	update := fmt.Sprintf("UPDATE %s SET dirty = %s WHERE version = %s", Table, d.placeholder(1), d.placeholder(2))
+	remove := fmt.Sprintf("DELETE FROM %s WHERE version = %s", Table, d.placeholder(1))
+
+	if d.transactional {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		for i, statement := range statements {
+			_, err = tx.ExecContext(ctx, statement)
+			if err != nil {
+				tx.Rollback()
+				return fmt.Errorf("%s %s, statement %d: %v", migration.ID(), direction, i+1, err)
+			}
+		}
+
+		if up {
+			_, err = tx.ExecContext(ctx, insert, migration.Version, migration.Name, time.Now().UTC(), false)
+		} else {
+			_, err = tx.ExecContext(ctx, remove, migration.Version)
+		}
+
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		return tx.Commit()
+	}
+
+	var err error
+	if up {
+		_, err = conn.ExecContext(ctx, insert, migration.Version, migration.Name, time.Now().UTC(), true)
+	} else {
+		_, err = conn.ExecContext(ctx, update, true, migration.Version)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for i, statement := range statements {
+		_, err = conn.ExecContext(ctx, statement)
+		if err != nil {
+			return fmt.Errorf("%s %s, statement %d: %v", migration.ID(), direction, i+1, err)
+		}
+	}
+
+	if up {
+		_, err = conn.ExecContext(ctx, update, false, migration.Version)
+	} else {
+		_, err = conn.ExecContext(ctx, remove, migration.Version)
+	}
+
+	return err
+}
@@ -0,0 +1,138 @@
+-- The schema as AutoMigrate left it. Tables that already exist are kept;
+-- on databases created before migrations the migrator first adds the
+-- columns, indexes and foreign keys they lack, see adopt.go.
+
+CREATE TABLE IF NOT EXISTS users (
+  id int unsigned NOT NULL AUTO_INCREMENT,
+  username varchar(255) NOT NULL,
+  email varchar(100) NOT NULL,
+  password varchar(100) NOT NULL,
+  role varchar(20) NOT NULL DEFAULT 'user',
+  created_at datetime NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY (username),
+  UNIQUE KEY (email)
+);
+
+CREATE TABLE IF NOT EXISTS posts (
+  id bigint unsigned NOT NULL AUTO_INCREMENT,
+  title varchar(255) NOT NULL,
+  content text NOT NULL,
+  author_id int unsigned NOT NULL,
+  created_at datetime NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NULL DEFAULT CURRENT_TIMESTAMP,
+  slug varchar(255),
+  status varchar(20) NOT NULL DEFAULT 'published',
+  publish_at datetime NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY (title),
+  UNIQUE KEY uix_posts_slug (slug),
+  KEY idx_posts_status (status),
+  KEY idx_posts_publish_at (publish_at),
+  FULLTEXT KEY idx_posts_fulltext (title, content),
+  CONSTRAINT posts_author_id_users_id_foreign FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+-- post content used to be a varchar(255)
+ALTER TABLE posts MODIFY content text NOT NULL;
+
+CREATE TABLE IF NOT EXISTS tags (
+  id int unsigned NOT NULL AUTO_INCREMENT,
+  name varchar(50) NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY (name)
+);
+
+CREATE TABLE IF NOT EXISTS post_tags (
+  post_id bigint unsigned NOT NULL,
+  tag_id int unsigned NOT NULL,
+  PRIMARY KEY (post_id, tag_id),
+  CONSTRAINT post_tags_post_id_posts_id_foreign FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT post_tags_tag_id_tags_id_foreign FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS media (
+  id bigint unsigned NOT NULL AUTO_INCREMENT,
+  `key` varchar(255) NOT NULL,
+  filename varchar(255) NOT NULL,
+  content_type varchar(100) NOT NULL,
+  size bigint NOT NULL,
+  width int,
+  height int,
+  user_id int unsigned NOT NULL,
+  created_at datetime NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY (`key`),
+  KEY idx_media_user_id (user_id),
+  CONSTRAINT media_user_id_users_id_foreign FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS post_media (
+  post_id bigint unsigned NOT NULL,
+  media_id bigint unsigned NOT NULL,
+  PRIMARY KEY (post_id, media_id),
+  CONSTRAINT post_media_post_id_posts_id_foreign FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT post_media_media_id_media_id_foreign FOREIGN KEY (media_id) REFERENCES media (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS post_revisions (
+  id bigint unsigned NOT NULL AUTO_INCREMENT,
+  post_id bigint unsigned NOT NULL,
+  revision int NOT NULL,
+  title varchar(255) NOT NULL,
+  content text NOT NULL,
+  created_at datetime NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY idx_post_revision (post_id, revision),
+  CONSTRAINT post_revisions_post_id_posts_id_foreign FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS post_slugs (
+  id bigint unsigned NOT NULL AUTO_INCREMENT,
+  post_id bigint unsigned NOT NULL,
+  slug varchar(255) NOT NULL,
+  created_at datetime NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY uix_post_slugs_slug (slug),
+  KEY idx_post_slugs_post_id (post_id),
+  CONSTRAINT post_slugs_post_id_posts_id_foreign FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS comments (
+  id bigint unsigned NOT NULL AUTO_INCREMENT,
+  post_id bigint unsigned NOT NULL,
+  parent_id bigint unsigned,
+  content varchar(1000) NOT NULL,
+  author_id int unsigned NOT NULL,
+  created_at datetime NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  KEY idx_comments_post_id (post_id),
+  KEY idx_comments_parent_id (parent_id),
+  CONSTRAINT comments_post_id_posts_id_foreign FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT comments_author_id_users_id_foreign FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT comments_parent_id_comments_id_foreign FOREIGN KEY (parent_id) REFERENCES comments (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+  id bigint unsigned NOT NULL AUTO_INCREMENT,
+  user_id int unsigned NOT NULL,
+  token_hash varchar(64) NOT NULL,
+  expires_at datetime NOT NULL,
+  revoked_at datetime NULL,
+  replaced_by_id bigint unsigned,
+  created_at datetime NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY (token_hash),
+  KEY idx_refresh_tokens_user_id (user_id),
+  CONSTRAINT refresh_tokens_user_id_users_id_foreign FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+  jti varchar(64) NOT NULL,
+  expires_at datetime NOT NULL,
+  created_at datetime NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (jti),
+  KEY idx_revoked_tokens_expires_at (expires_at)
+);
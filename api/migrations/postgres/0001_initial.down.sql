@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS post_slugs;
+DROP TABLE IF EXISTS post_revisions;
+DROP TABLE IF EXISTS post_media;
+DROP TABLE IF EXISTS media;
+DROP TABLE IF EXISTS post_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,118 @@
+-- The schema as AutoMigrate left it. Tables that already exist are kept;
+-- on databases created before migrations the migrator first adds the
+-- columns, indexes and foreign keys they lack, see adopt.go.
+
+CREATE TABLE IF NOT EXISTS users (
+  id serial PRIMARY KEY,
+  username varchar(255) NOT NULL UNIQUE,
+  email varchar(100) NOT NULL UNIQUE,
+  password varchar(100) NOT NULL,
+  role varchar(20) NOT NULL DEFAULT 'user',
+  created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS posts (
+  id bigserial PRIMARY KEY,
+  title varchar(255) NOT NULL UNIQUE,
+  content text NOT NULL,
+  author_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP,
+  slug varchar(255),
+  status varchar(20) NOT NULL DEFAULT 'published',
+  publish_at timestamp with time zone
+);
+
+-- post content used to be a varchar(255)
+ALTER TABLE posts ALTER COLUMN content TYPE text;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uix_posts_slug ON posts (slug);
+CREATE INDEX IF NOT EXISTS idx_posts_status ON posts (status);
+CREATE INDEX IF NOT EXISTS idx_posts_publish_at ON posts (publish_at);
+CREATE INDEX IF NOT EXISTS idx_posts_search ON posts USING GIN (to_tsvector('english', title || ' ' || content));
+
+CREATE TABLE IF NOT EXISTS tags (
+  id serial PRIMARY KEY,
+  name varchar(50) NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS post_tags (
+  post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  tag_id integer NOT NULL REFERENCES tags (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS media (
+  id bigserial PRIMARY KEY,
+  key varchar(255) NOT NULL UNIQUE,
+  filename varchar(255) NOT NULL,
+  content_type varchar(100) NOT NULL,
+  size bigint NOT NULL,
+  width integer,
+  height integer,
+  user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_user_id ON media (user_id);
+
+CREATE TABLE IF NOT EXISTS post_media (
+  post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  media_id bigint NOT NULL REFERENCES media (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  PRIMARY KEY (post_id, media_id)
+);
+
+CREATE TABLE IF NOT EXISTS post_revisions (
+  id bigserial PRIMARY KEY,
+  post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  revision integer NOT NULL,
+  title varchar(255) NOT NULL,
+  content text NOT NULL,
+  created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_post_revision ON post_revisions (post_id, revision);
+
+CREATE TABLE IF NOT EXISTS post_slugs (
+  id bigserial PRIMARY KEY,
+  post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  slug varchar(255) NOT NULL,
+  created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uix_post_slugs_slug ON post_slugs (slug);
+CREATE INDEX IF NOT EXISTS idx_post_slugs_post_id ON post_slugs (post_id);
+
+CREATE TABLE IF NOT EXISTS comments (
+  id bigserial PRIMARY KEY,
+  post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  parent_id bigint REFERENCES comments (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  content varchar(1000) NOT NULL,
+  author_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_post_id ON comments (post_id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments (parent_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+  id bigserial PRIMARY KEY,
+  user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  token_hash varchar(64) NOT NULL UNIQUE,
+  expires_at timestamp with time zone NOT NULL,
+  revoked_at timestamp with time zone,
+  replaced_by_id bigint,
+  created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+  jti varchar(64) PRIMARY KEY,
+  expires_at timestamp with time zone NOT NULL,
+  created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
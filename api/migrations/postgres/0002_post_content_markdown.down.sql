@@ -0,0 +1,3 @@
+-- Escapes the content again. The column stays a text, shortening it could
+-- cut posts.
+UPDATE posts SET content = REPLACE(REPLACE(REPLACE(REPLACE(REPLACE(content, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;');
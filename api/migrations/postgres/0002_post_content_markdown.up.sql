@@ -0,0 +1,7 @@
+-- Databases created by AutoMigrate kept content as a varchar(255).
+ALTER TABLE posts ALTER COLUMN content TYPE text;
+
+-- Post content is Markdown now. It used to be stored HTML-escaped, as
+-- html.EscapeString escapes it; &amp; goes last so an escaped "&lt;" stays
+-- "&lt;".
+UPDATE posts SET content = REPLACE(REPLACE(REPLACE(REPLACE(REPLACE(content, '&lt;', '<'), '&gt;', '>'), '&#34;', '"'), '&#39;', ''''), '&amp;', '&');
@@ -0,0 +1,113 @@
+-- The schema of a new sqlite database, used for local development and
+-- tests. Search falls back to LIKE, so there is no full-text index.
+
+CREATE TABLE IF NOT EXISTS users (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  username varchar(255) NOT NULL UNIQUE,
+  email varchar(100) NOT NULL UNIQUE,
+  password varchar(100) NOT NULL,
+  role varchar(20) NOT NULL DEFAULT 'user',
+  created_at datetime DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS posts (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  title varchar(255) NOT NULL UNIQUE,
+  content text NOT NULL,
+  author_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  created_at datetime DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime DEFAULT CURRENT_TIMESTAMP,
+  slug varchar(255),
+  status varchar(20) NOT NULL DEFAULT 'published',
+  publish_at datetime
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uix_posts_slug ON posts (slug);
+CREATE INDEX IF NOT EXISTS idx_posts_status ON posts (status);
+CREATE INDEX IF NOT EXISTS idx_posts_publish_at ON posts (publish_at);
+
+CREATE TABLE IF NOT EXISTS tags (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  name varchar(50) NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS post_tags (
+  post_id integer NOT NULL REFERENCES posts (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  tag_id integer NOT NULL REFERENCES tags (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS media (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  key varchar(255) NOT NULL UNIQUE,
+  filename varchar(255) NOT NULL,
+  content_type varchar(100) NOT NULL,
+  size bigint NOT NULL,
+  width integer,
+  height integer,
+  user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  created_at datetime DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_user_id ON media (user_id);
+
+CREATE TABLE IF NOT EXISTS post_media (
+  post_id integer NOT NULL REFERENCES posts (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  media_id integer NOT NULL REFERENCES media (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  PRIMARY KEY (post_id, media_id)
+);
+
+CREATE TABLE IF NOT EXISTS post_revisions (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  post_id integer NOT NULL REFERENCES posts (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  revision integer NOT NULL,
+  title varchar(255) NOT NULL,
+  content text NOT NULL,
+  created_at datetime DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_post_revision ON post_revisions (post_id, revision);
+
+CREATE TABLE IF NOT EXISTS post_slugs (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  post_id integer NOT NULL REFERENCES posts (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  slug varchar(255) NOT NULL,
+  created_at datetime DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uix_post_slugs_slug ON post_slugs (slug);
+CREATE INDEX IF NOT EXISTS idx_post_slugs_post_id ON post_slugs (post_id);
+
+CREATE TABLE IF NOT EXISTS comments (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  post_id integer NOT NULL REFERENCES posts (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  parent_id integer REFERENCES comments (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  content varchar(1000) NOT NULL,
+  author_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  created_at datetime DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_post_id ON comments (post_id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments (parent_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE,
+  token_hash varchar(64) NOT NULL UNIQUE,
+  expires_at datetime NOT NULL,
+  revoked_at datetime,
+  replaced_by_id integer,
+  created_at datetime DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+  jti varchar(64) PRIMARY KEY,
+  expires_at datetime NOT NULL,
+  created_at datetime DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
@@ -0,0 +1,213 @@
+package models
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const MaxCommentLength = 1000
+
+// Comment is a reader's response to a post. Replies point at the comment
+// they answer through ParentID; top level comments have no parent.
+type Comment struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	PostID    uint64    `gorm:"not null;index" json:"postId"`
+	ParentID  *uint64   `gorm:"index" json:"parentId"`
+	Content   string    `gorm:"size:1000;not null" json:"content"`
+	Author    User      `json:"author"`
+	AuthorID  uint32    `gorm:"not null" json:"authorId"`
+	Replies   []Comment `gorm:"-" json:"replies"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updatedAt"`
+}
+
+func (c *Comment) Prepare() {
+	c.ID = 0
+	c.Content = html.EscapeString(strings.TrimSpace(c.Content))
+	c.Author = User{}
+	c.Replies = nil
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = time.Now()
+}
+
+func (c *Comment) Validate() error {
+
+	if c.Content == "" {
+		return errors.New("Required Content")
+	}
+
+	if len(c.Content) > MaxCommentLength {
+		return errors.New("Content is too long")
+	}
+
+	if c.PostID < 1 {
+		return errors.New("Required Post")
+	}
+
+	if c.AuthorID < 1 {
+		return errors.New("Required Author")
+	}
+
+	return nil
+}
+
+func (c *Comment) SaveComment(db *gorm.DB) (*Comment, error) {
+	var err error
+
+	// replies must stay on the same post as the comment they answer
+	if c.ParentID != nil {
+		parent := Comment{}
+		err = db.Model(&Comment{}).Where("id = ? AND post_id = ?", *c.ParentID, c.PostID).Take(&parent).Error
+		if gorm.IsRecordNotFoundError(err) {
+			return &Comment{}, errors.New("Parent comment not found")
+		}
+
+		if err != nil {
+			return &Comment{}, err
+		}
+	}
+
+	err = db.Model(&Comment{}).Create(&c).Error
+	if err != nil {
+		return &Comment{}, err
+	}
+
+	err = c.loadAuthor(db)
+	if err != nil {
+		return &Comment{}, err
+	}
+
+	return c, nil
+}
+
+func (c *Comment) GetCommentByID(db *gorm.DB, pid, cid uint64) (*Comment, error) {
+	var err error
+	err = db.Model(&Comment{}).Where("id = ? AND post_id = ?", cid, pid).Take(&c).Error
+
+	if err != nil {
+		return &Comment{}, err
+	}
+
+	err = c.loadAuthor(db)
+	if err != nil {
+		return &Comment{}, err
+	}
+
+	return c, nil
+}
+
+// GetCommentsByPostID returns the comments of a post as a tree of replies,
+// oldest first at every level.
+func (c *Comment) GetCommentsByPostID(db *gorm.DB, pid uint64) (*[]Comment, error) {
+	var err error
+	comments := []Comment{}
+	err = db.Model(&Comment{}).Where("post_id = ?", pid).Order("created_at asc").Order("id asc").Find(&comments).Error
+
+	if err != nil {
+		return &[]Comment{}, err
+	}
+
+	err = PreloadCommentAuthors(db, comments)
+	if err != nil {
+		return &[]Comment{}, err
+	}
+
+	tree := BuildCommentTree(comments)
+	return &tree, nil
+}
+
+func (c *Comment) UpdateComment(db *gorm.DB) (*Comment, error) {
+	var err error
+
+	err = db.Model(&Comment{}).Where("id = ?", c.ID).Updates(Comment{Content: c.Content, UpdatedAt: time.Now()}).Error
+	if err != nil {
+		return &Comment{}, err
+	}
+
+	return c.GetCommentByID(db, c.PostID, c.ID)
+}
+
+// DeleteComment removes the comment and every reply below it and returns
+// how many comments that was
+func (c *Comment) DeleteComment(db *gorm.DB, cid uint64) (int64, error) {
+	var count int64
+
+	// replies posted meanwhile go with the rest of the thread
+	err := db.Transaction(func(tx *gorm.DB) error {
+		ids := []uint64{cid}
+		frontier := []uint64{cid}
+
+		for len(frontier) > 0 {
+			children := []uint64{}
+			err := tx.Model(&Comment{}).Where("parent_id IN (?)", frontier).Pluck("id", &children).Error
+			if err != nil {
+				return err
+			}
+
+			ids = append(ids, children...)
+			frontier = children
+		}
+
+		// RowsAffected leaves out the replies removed by the ON DELETE
+		// CASCADE of parent_id, so the comments are counted first
+		err := tx.Model(&Comment{}).Where("id IN (?)", ids).Count(&count).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&Comment{}).Where("id IN (?)", ids).Delete(&Comment{}).Error
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (c *Comment) loadAuthor(db *gorm.DB) error {
+	comments := []Comment{*c}
+	err := PreloadCommentAuthors(db, comments)
+	if err != nil {
+		return err
+	}
+
+	c.Author = comments[0].Author
+	return nil
+}
+
+// BuildCommentTree nests replies below their parents, keeping the order of
+// comments. Replies whose parent is missing are promoted to the top level.
+func BuildCommentTree(comments []Comment) []Comment {
+	children := map[uint64][]int{}
+	byID := map[uint64]bool{}
+	for _, comment := range comments {
+		byID[comment.ID] = true
+	}
+
+	roots := []int{}
+	for i, comment := range comments {
+		if comment.ParentID != nil && byID[*comment.ParentID] {
+			children[*comment.ParentID] = append(children[*comment.ParentID], i)
+		} else {
+			roots = append(roots, i)
+		}
+	}
+
+	var build func(indexes []int) []Comment
+	build = func(indexes []int) []Comment {
+		tree := make([]Comment, 0, len(indexes))
+		for _, i := range indexes {
+			comment := comments[i]
+			comment.Replies = build(children[comment.ID])
+			tree = append(tree, comment)
+		}
+		return tree
+	}
+
+	return build(roots)
+}
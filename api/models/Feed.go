@@ -0,0 +1,32 @@
+package models
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// DefaultFeedLength is the number of posts in a feed
+const DefaultFeedLength = 20
+
+// GetFeedPosts returns the newest published posts, only those of one author
+// when uid is not 0. Posts published before publish_at existed are dated by
+// their creation.
+func GetFeedPosts(db *gorm.DB, uid uint32, limit int) (*[]Post, error) {
+	posts := []Post{}
+
+	query := visibleTo(db.Model(&Post{}), 0)
+	if uid != 0 {
+		query = query.Where("posts.author_id = ?", uid)
+	}
+
+	err := query.Order("COALESCE(posts.publish_at, posts.created_at) desc, posts.id desc").Limit(limit).Find(&posts).Error
+	if err != nil {
+		return &[]Post{}, err
+	}
+
+	err = PreloadPosts(db, posts)
+	if err != nil {
+		return &[]Post{}, err
+	}
+
+	return &posts, nil
+}
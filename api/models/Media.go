@@ -0,0 +1,195 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const MaxMediaSize = 10 << 20
+
+// AllowedMediaTypes maps the content types accepted for upload, as sniffed
+// from the file itself, to the extension they are stored with.
+var AllowedMediaTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+var ErrMediaNotFound = errors.New("Media not found")
+
+// Media is an uploaded image or attachment. The file itself lives in an
+// ObjectStore under Key; Width and Height are only known for images.
+type Media struct {
+	ID          uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	Key         string    `gorm:"size:255;not null;unique" json:"-"`
+	Filename    string    `gorm:"size:255;not null" json:"filename"`
+	ContentType string    `gorm:"size:100;not null" json:"contentType"`
+	Size        int64     `gorm:"not null" json:"size"`
+	Width       int       `json:"width,omitempty"`
+	Height      int       `json:"height,omitempty"`
+	UserID      uint32    `gorm:"not null;index" json:"userId"`
+	URL         string    `gorm:"-" json:"url"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"createdAt"`
+}
+
+// UnmarshalJSON accepts a bare media id as well as a media object, so posts
+// can reference uploads with "media": [1, 2].
+func (m *Media) UnmarshalJSON(data []byte) error {
+	var id uint64
+	if err := json.Unmarshal(data, &id); err == nil {
+		*m = Media{ID: id}
+		return nil
+	}
+
+	type mediaFields Media
+	fields := mediaFields{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	*m = Media(fields)
+	return nil
+}
+
+func (m *Media) Validate() error {
+	if m.Size > MaxMediaSize {
+		return fmt.Errorf("File is larger than %d MB", MaxMediaSize>>20)
+	}
+
+	if _, ok := AllowedMediaTypes[m.ContentType]; !ok {
+		return errors.New("Unsupported file type")
+	}
+
+	if m.UserID < 1 {
+		return errors.New("Required User")
+	}
+
+	return nil
+}
+
+// setURL points the media at the endpoint serving its content
+func (m *Media) setURL() {
+	m.URL = fmt.Sprintf("/api/media/%d/content", m.ID)
+}
+
+func (m *Media) SaveMedia(db *gorm.DB) (*Media, error) {
+	err := db.Model(&Media{}).Create(&m).Error
+	if err != nil {
+		return &Media{}, err
+	}
+
+	m.setURL()
+	return m, nil
+}
+
+func (m *Media) GetMediaByID(db *gorm.DB, mid uint64) (*Media, error) {
+	err := db.Model(&Media{}).Where("id = ?", mid).Take(&m).Error
+
+	if gorm.IsRecordNotFoundError(err) {
+		return &Media{}, ErrMediaNotFound
+	}
+
+	if err != nil {
+		return &Media{}, err
+	}
+
+	m.setURL()
+	return m, nil
+}
+
+// DeleteMedia also detaches the media from every post using it
+func (m *Media) DeleteMedia(db *gorm.DB, mid uint64) (int64, error) {
+	err := db.Exec("DELETE FROM post_media WHERE media_id = ?", mid).Error
+	if err != nil {
+		return 0, err
+	}
+
+	db = db.Model(&Media{}).Where("id = ?", mid).Delete(&Media{})
+
+	if db.Error != nil {
+		return 0, db.Error
+	}
+
+	return db.RowsAffected, nil
+}
+
+func mediaIDs(media []Media) []uint64 {
+	ids := make([]uint64, len(media))
+	for i, m := range media {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+// setMedia replaces the media attached to a saved post. Authors can only
+// attach their own uploads.
+func (p *Post) setMedia(db *gorm.DB, ids []uint64) error {
+	media := []Media{}
+
+	if len(ids) > 0 {
+		err := db.Model(&Media{}).Where("id IN (?) AND user_id = ?", ids, p.AuthorID).Find(&media).Error
+		if err != nil {
+			return err
+		}
+
+		found := map[uint64]bool{}
+		for _, m := range media {
+			found[m.ID] = true
+		}
+
+		for _, id := range ids {
+			if !found[id] {
+				return ErrMediaNotFound
+			}
+		}
+	}
+
+	return db.Model(p).Association("Media").Replace(media).Error
+}
+
+// PreloadMedia fills in the Media of every post with a single query
+func PreloadMedia(db *gorm.DB, posts []Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]uint64, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	rows := []struct {
+		PostID uint64
+		Media
+	}{}
+	err := db.Table("post_media").
+		Select("post_media.post_id, media.*").
+		Joins("JOIN media ON media.id = post_media.media_id").
+		Where("post_media.post_id IN (?)", ids).
+		Order("media.id asc").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	byPost := map[uint64][]Media{}
+	for _, row := range rows {
+		row.Media.setURL()
+		byPost[row.PostID] = append(byPost[row.PostID], row.Media)
+	}
+
+	for i := range posts {
+		posts[i].Media = byPost[posts[i].ID]
+		if posts[i].Media == nil {
+			posts[i].Media = []Media{}
+		}
+	}
+
+	return nil
+}
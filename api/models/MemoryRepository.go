@@ -0,0 +1,562 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/dmdinh22/go-blog/api/auth"
+)
+
+// memoryStore holds the posts and users of the in-memory repositories, which
+// share it so posts can be returned with their author
+type memoryStore struct {
+	mu sync.Mutex
+
+	users      map[uint32]User
+	lastUserID uint32
+
+	posts      map[uint64]Post
+	lastPostID uint64
+	// oldSlugs maps the slugs posts had before to the post
+	oldSlugs map[string]uint64
+
+	tags      map[string]Tag
+	lastTagID uint32
+}
+
+// MemoryPostRepository keeps posts in memory. It has no comments or media,
+// so comment counts are always 0 and attaching media fails with
+// ErrMediaNotFound.
+type MemoryPostRepository struct {
+	store *memoryStore
+}
+
+// MemoryUserRepository keeps users in memory
+type MemoryUserRepository struct {
+	store *memoryStore
+}
+
+// NewMemoryRepositories returns empty post and user repositories sharing
+// their data, e.g. to test the controllers without a database
+func NewMemoryRepositories() (*MemoryPostRepository, *MemoryUserRepository) {
+	store := &memoryStore{
+		users:    map[uint32]User{},
+		posts:    map[uint64]Post{},
+		oldSlugs: map[string]uint64{},
+		tags:     map[string]Tag{},
+	}
+
+	return &MemoryPostRepository{store: store}, &MemoryUserRepository{store: store}
+}
+
+func (r *MemoryPostRepository) Create(post *Post) (*Post, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(post.Media) > 0 {
+		return &Post{}, ErrMediaNotFound
+	}
+
+	if _, ok := s.users[post.AuthorID]; !ok {
+		return &Post{}, errors.New("author_id does not reference a user")
+	}
+
+	err := s.checkTitle(post.Title, 0)
+	if err != nil {
+		return &Post{}, err
+	}
+
+	if post.Status == "" {
+		post.Status = PostStatusPublished
+	}
+
+	if post.Status == PostStatusPublished && post.PublishAt == nil {
+		now := time.Now()
+		post.PublishAt = &now
+	}
+
+	if post.CreatedAt.IsZero() {
+		post.CreatedAt = time.Now()
+		post.UpdatedAt = post.CreatedAt
+	}
+
+	s.lastPostID++
+	post.ID = s.lastPostID
+	post.Slug = s.uniqueSlug(post.Title, post.ID)
+	post.Tags = s.findOrCreateTags(post.Tags)
+	post.Media = []Media{}
+
+	s.posts[post.ID] = s.copyPost(*post)
+
+	return s.loadPost(post.ID)
+}
+
+func (r *MemoryPostRepository) Find(q PostQuery) (*[]Post, string, error) {
+	err := q.Validate()
+	if err != nil {
+		return &[]Post{}, "", err
+	}
+
+	var c postCursor
+	if q.Cursor != "" {
+		c, err = decodeCursor(q.Cursor)
+		if err != nil {
+			return &[]Post{}, "", err
+		}
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	posts := []Post{}
+	for _, post := range s.posts {
+		if q.matches(post) && (q.Cursor == "" || q.after(post, c)) {
+			posts = append(posts, post)
+		}
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		newer := posts[i].CreatedAt.After(posts[j].CreatedAt) ||
+			posts[i].CreatedAt.Equal(posts[j].CreatedAt) && posts[i].ID > posts[j].ID
+		if q.Sort == SortOldest {
+			return !newer
+		}
+		return newer
+	})
+
+	nextCursor := ""
+	if len(posts) > q.Limit {
+		posts = posts[:q.Limit]
+		nextCursor = encodeCursor(posts[len(posts)-1])
+	}
+
+	for i := range posts {
+		loaded, err := s.loadPost(posts[i].ID)
+		if err != nil {
+			return &[]Post{}, "", err
+		}
+		posts[i] = *loaded
+	}
+
+	return &posts, nextCursor, nil
+}
+
+// matches is the in-memory version of the filters of scope
+func (q PostQuery) matches(post Post) bool {
+	if !post.VisibleTo(q.ViewerID) {
+		return false
+	}
+
+	if q.Status != "" && post.Status != q.Status {
+		return false
+	}
+
+	if q.AuthorID != 0 && post.AuthorID != q.AuthorID {
+		return false
+	}
+
+	if !q.Since.IsZero() && post.CreatedAt.Before(q.Since) {
+		return false
+	}
+
+	if !q.Until.IsZero() && !post.CreatedAt.Before(q.Until) {
+		return false
+	}
+
+	if len(q.Tags) == 0 {
+		return true
+	}
+
+	carried := 0
+	for _, name := range q.Tags {
+		for _, tag := range post.Tags {
+			if tag.Name == name {
+				carried++
+				break
+			}
+		}
+	}
+
+	if q.TagMode == TagModeAny {
+		return carried > 0
+	}
+
+	return carried == len(q.Tags)
+}
+
+// after reports whether the post comes after the cursor in the sort order
+func (q PostQuery) after(post Post, c postCursor) bool {
+	if post.CreatedAt.Equal(c.CreatedAt) {
+		if q.Sort == SortOldest {
+			return post.ID > c.ID
+		}
+		return post.ID < c.ID
+	}
+
+	if q.Sort == SortOldest {
+		return post.CreatedAt.After(c.CreatedAt)
+	}
+
+	return post.CreatedAt.Before(c.CreatedAt)
+}
+
+func (r *MemoryPostRepository) GetByID(pid uint64) (*Post, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.loadPost(pid)
+}
+
+func (r *MemoryPostRepository) GetBySlug(slug string) (*Post, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, post := range s.posts {
+		if post.Slug == slug {
+			return s.loadPost(post.ID)
+		}
+	}
+
+	return &Post{}, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryPostRepository) GetByOldSlug(slug string) (*Post, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pid, ok := s.oldSlugs[slug]
+	if !ok {
+		return &Post{}, gorm.ErrRecordNotFound
+	}
+
+	return s.loadPost(pid)
+}
+
+func (r *MemoryPostRepository) Update(post *Post) (*Post, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.posts[post.ID]
+	if !ok {
+		return &Post{}, gorm.ErrRecordNotFound
+	}
+
+	if len(post.Media) > 0 {
+		return &Post{}, ErrMediaNotFound
+	}
+
+	err := s.checkTitle(post.Title, post.ID)
+	if err != nil {
+		return &Post{}, err
+	}
+
+	updated := s.copyPost(current)
+	updated.Title = post.Title
+	updated.Content = post.Content
+	updated.UpdatedAt = time.Now()
+
+	// an empty status keeps the current one, see Validate
+	if post.Status != "" {
+		updated.Status = post.Status
+	}
+
+	if post.PublishAt != nil {
+		updated.PublishAt = post.PublishAt
+	}
+
+	// keep the original publication time when a published post is
+	// published again
+	if post.Status == PostStatusPublished && post.PublishAt == nil && current.Status != PostStatusPublished {
+		now := time.Now()
+		updated.PublishAt = &now
+	}
+
+	if slugBase(updated.Title) != slugBase(current.Title) {
+		updated.Slug = s.uniqueSlug(updated.Title, updated.ID)
+
+		if updated.Slug != current.Slug {
+			s.oldSlugs[current.Slug] = updated.ID
+			// a title changed back takes its old slug out of the history
+			delete(s.oldSlugs, updated.Slug)
+		}
+	}
+
+	if post.Tags != nil {
+		updated.Tags = s.findOrCreateTags(post.Tags)
+	}
+
+	s.posts[updated.ID] = updated
+
+	return s.loadPost(updated.ID)
+}
+
+func (r *MemoryPostRepository) Delete(pid uint64, uid uint32) (int64, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, ok := s.posts[pid]
+	if !ok || post.AuthorID != uid {
+		return 0, errors.New("Post not found")
+	}
+
+	delete(s.posts, pid)
+
+	for slug, id := range s.oldSlugs {
+		if id == pid {
+			delete(s.oldSlugs, slug)
+		}
+	}
+
+	return 1, nil
+}
+
+// loadPost returns a copy of the post with its author, like preload
+func (s *memoryStore) loadPost(pid uint64) (*Post, error) {
+	post, ok := s.posts[pid]
+	if !ok {
+		return &Post{}, gorm.ErrRecordNotFound
+	}
+
+	author, ok := s.users[post.AuthorID]
+	if !ok {
+		return &Post{}, gorm.ErrRecordNotFound
+	}
+
+	loaded := s.copyPost(post)
+	loaded.Author = author
+	return &loaded, nil
+}
+
+// copyPost copies the slices of post so callers cannot change the store
+func (s *memoryStore) copyPost(post Post) Post {
+	post.Tags = append([]Tag{}, post.Tags...)
+	post.Media = append([]Media{}, post.Media...)
+	post.ContentHTML, post.ContentText = "", ""
+	return post
+}
+
+// checkTitle fails the way the unique index on the title column does
+func (s *memoryStore) checkTitle(title string, pid uint64) error {
+	for _, post := range s.posts {
+		if post.Title == title && post.ID != pid {
+			return fmt.Errorf("Duplicate entry %q for key 'title'", title)
+		}
+	}
+
+	return nil
+}
+
+// uniqueSlug is the in-memory version of uniqueSlug
+func (s *memoryStore) uniqueSlug(title string, pid uint64) string {
+	base := slugBase(title)
+	taken := map[string]bool{}
+
+	for _, post := range s.posts {
+		if post.ID != pid {
+			taken[post.Slug] = true
+		}
+	}
+
+	for slug, id := range s.oldSlugs {
+		if id != pid {
+			taken[slug] = true
+		}
+	}
+
+	candidate := base
+	for n := 2; taken[candidate]; n++ {
+		candidate = fmt.Sprintf("%s-%d", base, n)
+	}
+
+	return candidate
+}
+
+// findOrCreateTags returns the stored tags named like tags, sorted by name
+func (s *memoryStore) findOrCreateTags(tags []Tag) []Tag {
+	found := []Tag{}
+
+	for _, name := range tagNames(tags) {
+		tag, ok := s.tags[name]
+		if !ok {
+			s.lastTagID++
+			tag = Tag{ID: s.lastTagID, Name: name}
+			s.tags[name] = tag
+		}
+		found = append(found, tag)
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].Name < found[j].Name })
+	return found
+}
+
+func (r *MemoryUserRepository) Create(user *User) (*User, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.checkUnique(user, 0)
+	if err != nil {
+		return &User{}, err
+	}
+
+	if user.Role == "" {
+		user.Role = auth.RoleUser
+	}
+
+	err = user.BeforeSave()
+	if err != nil {
+		return &User{}, err
+	}
+
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+		user.UpdatedAt = user.CreatedAt
+	}
+
+	s.lastUserID++
+	user.ID = s.lastUserID
+	s.users[user.ID] = *user
+
+	return user, nil
+}
+
+func (r *MemoryUserRepository) FindAll() (*[]User, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := []User{}
+	for _, user := range s.users {
+		users = append(users, user)
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	if len(users) > 100 {
+		users = users[:100]
+	}
+
+	return &users, nil
+}
+
+func (r *MemoryUserRepository) GetByID(uid uint32) (*User, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[uid]
+	if !ok {
+		return &User{}, gorm.ErrRecordNotFound
+	}
+
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) GetByEmail(email string) (*User, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+
+	return &User{}, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryUserRepository) Update(uid uint32, user *User) (*User, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.users[uid]
+	if !ok {
+		return &User{}, gorm.ErrRecordNotFound
+	}
+
+	err := s.checkUnique(user, uid)
+	if err != nil {
+		return &User{}, err
+	}
+
+	err = user.BeforeSave()
+	if err != nil {
+		return &User{}, err
+	}
+
+	current.Username = user.Username
+	current.Email = user.Email
+	current.Password = user.Password
+	current.UpdatedAt = time.Now()
+	s.users[uid] = current
+
+	return &current, nil
+}
+
+func (r *MemoryUserRepository) UpdateRole(uid uint32, role string) (*User, error) {
+	if !auth.ValidRole(role) {
+		return &User{}, errors.New("Invalid Role.")
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[uid]
+	if !ok {
+		return &User{}, gorm.ErrRecordNotFound
+	}
+
+	user.Role = role
+	user.UpdatedAt = time.Now()
+	s.users[uid] = user
+
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) Delete(uid uint32) (int64, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[uid]; !ok {
+		return 0, gorm.ErrRecordNotFound
+	}
+
+	delete(s.users, uid)
+	return 1, nil
+}
+
+// checkUnique fails the way the unique indexes on the username and email
+// columns do
+func (s *memoryStore) checkUnique(user *User, uid uint32) error {
+	for _, other := range s.users {
+		if other.ID == uid {
+			continue
+		}
+
+		if other.Username == user.Username {
+			return fmt.Errorf("Duplicate entry %q for key 'username'", user.Username)
+		}
+
+		if other.Email == user.Email {
+			return fmt.Errorf("Duplicate entry %q for key 'email'", user.Email)
+		}
+	}
+
+	return nil
+}
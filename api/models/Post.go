@@ -2,28 +2,51 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"html"
 	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
+
+	"github.com/dmdinh22/go-blog/api/render"
 )
 
 type Post struct {
 	ID        uint64    `gorm:"primary_key;auto_increment" 	json:"id"`
 	Title     string    `gorm:"size:255;not null;unique" 		json:"title"`
-	Content   string    `gorm:"size:255;not null;" 					json:"content"`
+	Content   string    `gorm:"type:text;not null;" 				json:"content"`
 	Author    User      `																		json:"author"`
 	AuthorID  uint32    `gorm:"not null" 										json:"authorId"`
 	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" 	json:"createdAt"`
 	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" 	json:"updatedAt"`
+
+	// Slug is unique and made from the title, see BeforeCreate
+	Slug string `gorm:"size:255;unique_index" json:"slug"`
+
+	Status    string     `gorm:"size:20;not null;default:'published';index" json:"status"`
+	PublishAt *time.Time `gorm:"index" json:"publishAt"`
+
+	// Content is Markdown. These hold it rendered in the format asked for,
+	// see Render.
+	ContentHTML string `gorm:"-" json:"contentHtml,omitempty"`
+	ContentText string `gorm:"-" json:"contentText,omitempty"`
+
+	CommentCount int     `gorm:"-" json:"commentCount"`
+	Tags         []Tag   `gorm:"many2many:post_tags;" json:"tags"`
+	Media        []Media `gorm:"many2many:post_media;" json:"media"`
 }
 
+// MaxPostContentLength is the size of a TEXT column in bytes
+const MaxPostContentLength = 65535
+
 func (p *Post) Prepare() {
 	p.ID = 0
 	p.Title = html.EscapeString(strings.TrimSpace(p.Title))
-	p.Content = html.EscapeString(strings.TrimSpace(p.Content))
+	p.Content = strings.TrimSpace(p.Content)
 	p.Author = User{}
+	p.Slug = ""
+	p.Status = strings.ToLower(strings.TrimSpace(p.Status))
 	p.CreatedAt = time.Now()
 	p.UpdatedAt = time.Now()
 }
@@ -38,23 +61,86 @@ func (p *Post) Validate() error {
 		return errors.New("Required Content")
 	}
 
+	if len(p.Content) > MaxPostContentLength {
+		return errors.New("Content is too long")
+	}
+
 	if p.AuthorID < 1 {
 		return errors.New("Required Author")
 	}
 
+	// an empty status keeps the status of an existing post, new posts are
+	// published
+	if p.Status != "" && !ValidPostStatus(p.Status) {
+		return errors.New("Status must be draft, scheduled, published or archived")
+	}
+
+	if p.Status == PostStatusScheduled && p.PublishAt == nil {
+		return errors.New("Scheduled posts require publishAt")
+	}
+
+	// a nil Tags leaves the tags of an existing post untouched
+	if p.Tags != nil {
+		names, err := NormalizeTagNames(tagNames(p.Tags))
+		if err != nil {
+			return err
+		}
+
+		if len(names) > MaxTagsPerPost {
+			return fmt.Errorf("A post can have at most %d tags", MaxTagsPerPost)
+		}
+
+		p.Tags = make([]Tag, len(names))
+		for i, name := range names {
+			p.Tags[i] = Tag{Name: name}
+		}
+	}
+
 	return nil
 }
 
 func (p *Post) CreatePost(db *gorm.DB) (*Post, error) {
 	var err error
-	err = db.Debug().Model(&Post{}).Create(&p).Error
+
+	// tags and media are saved through setTags and setMedia so existing
+	// rows are reused rather than saved again
+	tags, media := p.Tags, p.Media
+	p.Tags, p.Media = nil, nil
+
+	if p.Status == "" {
+		p.Status = PostStatusPublished
+	}
+
+	if p.Status == PostStatusPublished && p.PublishAt == nil {
+		now := time.Now()
+		p.PublishAt = &now
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Post{}).Create(&p).Error
+		if err != nil {
+			return err
+		}
+
+		err = p.recordRevision(tx)
+		if err != nil {
+			return err
+		}
+
+		err = p.setMedia(tx, mediaIDs(media))
+		if err != nil {
+			return err
+		}
+
+		return p.setTags(tx, tagNames(tags))
+	})
 
 	if err != nil {
 		return &Post{}, err
 	}
 
 	if p.ID != 0 {
-		err = db.Debug().Model(&User{}).Where("id = ?", p.AuthorID).Take(&p.Author).Error
+		err = p.preload(db)
 		if err != nil {
 			return &Post{}, err
 		}
@@ -66,19 +152,15 @@ func (p *Post) CreatePost(db *gorm.DB) (*Post, error) {
 func (p *Post) GetAllPosts(db *gorm.DB) (*[]Post, error) {
 	var err error
 	posts := []Post{}
-	err = db.Debug().Model(&Post{}).Limit(100).Find(&posts).Error
+	err = db.Model(&Post{}).Limit(100).Find(&posts).Error
 
 	if err != nil {
 		return &[]Post{}, err
 	}
 
-	if len(posts) > 0 {
-		for i, _ := range posts {
-			err := db.Debug().Model(&User{}).Where("id = ?", posts[i].AuthorID).Take(&posts[i].Author).Error
-			if err != nil {
-				return &[]Post{}, err
-			}
-		}
+	err = PreloadPosts(db, posts)
+	if err != nil {
+		return &[]Post{}, err
 	}
 
 	return &posts, nil
@@ -86,14 +168,14 @@ func (p *Post) GetAllPosts(db *gorm.DB) (*[]Post, error) {
 
 func (p *Post) GetPostByID(db *gorm.DB, pid uint64) (*Post, error) {
 	var err error
-	err = db.Debug().Model(&Post{}).Where("id = ?", pid).Take(&p).Error
+	err = db.Model(&Post{}).Where("id = ?", pid).Take(&p).Error
 
 	if err != nil {
 		return &Post{}, err
 	}
 
 	if p.ID != 0 {
-		err = db.Debug().Model(&User{}).Where("id = ?", p.AuthorID).Take(&p.Author).Error
+		err = p.preload(db)
 		if err != nil {
 			return &Post{}, err
 		}
@@ -106,31 +188,162 @@ func (p *Post) UpdatePost(db *gorm.DB) (*Post, error) {
 
 	var err error
 
-	err = db.Debug().Model(&Post{}).Where("id = ?", p.ID).Updates(Post{Title: p.Title, Content: p.Content, UpdatedAt: time.Now()}).Error
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// writing the row first locks it, so concurrent updates of the post
+		// number their revisions one after the other
+		err := tx.Model(&Post{}).Where("id = ?", p.ID).UpdateColumn("updated_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		err = ensureFirstRevision(tx, p.ID)
+		if err != nil {
+			return err
+		}
+
+		current := Post{}
+		err = tx.Model(&Post{}).Where("id = ?", p.ID).Take(&current).Error
+		if err != nil {
+			return err
+		}
+
+		// keep the original publication time when a published post is
+		// published again
+		if p.Status == PostStatusPublished && p.PublishAt == nil {
+			if current.Status == PostStatusPublished && current.PublishAt != nil {
+				p.PublishAt = current.PublishAt
+			} else {
+				now := time.Now()
+				p.PublishAt = &now
+			}
+		}
+
+		err = tx.Model(&Post{}).Where("id = ?", p.ID).Updates(Post{Title: p.Title, Content: p.Content, Status: p.Status, PublishAt: p.PublishAt, UpdatedAt: time.Now()}).Error
+		if err != nil {
+			return err
+		}
+
+		err = p.updateSlug(tx, &current)
+		if err != nil {
+			return err
+		}
+
+		// every update is kept as a revision
+		err = p.recordRevision(tx)
+		if err != nil {
+			return err
+		}
+
+		// nil tags or media leave them as they are
+		if p.Media != nil {
+			err = p.setMedia(tx, mediaIDs(p.Media))
+			if err != nil {
+				return err
+			}
+		}
+
+		if p.Tags == nil {
+			return nil
+		}
+
+		return p.setTags(tx, tagNames(p.Tags))
+	})
 	if err != nil {
 		return &Post{}, err
 	}
 
-	if p.ID != 0 {
-		err = db.Debug().Model(&User{}).Where("id = ?", p.AuthorID).Take(&p.Author).Error
+	return p.GetPostByID(db, p.ID)
+}
+
+// Render fills in ContentHTML or ContentText from the Markdown content. The
+// markdown format leaves both empty.
+func (p *Post) Render(format string) error {
+	var err error
+	p.ContentHTML, p.ContentText = "", ""
+
+	switch format {
+	case render.FormatHTML:
+		p.ContentHTML, err = render.HTML(p.Content)
+	case render.FormatPlain:
+		p.ContentText, err = render.Plain(p.Content)
+	case render.FormatMarkdown:
+	default:
+		err = render.ErrInvalidFormat
+	}
+
+	return err
+}
+
+// RenderPosts renders every post in the given format
+func RenderPosts(posts []Post, format string) error {
+	for i := range posts {
+		err := posts[i].Render(format)
 		if err != nil {
-			return &Post{}, err
+			return err
 		}
 	}
 
-	return p, nil
+	return nil
+}
+
+// preload fills in the author, comment count, tags and media of a single post
+func (p *Post) preload(db *gorm.DB) error {
+	posts := []Post{*p}
+	err := PreloadPosts(db, posts)
+	if err != nil {
+		return err
+	}
+
+	*p = posts[0]
+	return nil
 }
 
 func (p *Post) DeletePost(db *gorm.DB, pid uint64, uid uint32) (int64, error) {
+	var rowsAffected int64
+
+	// the post and everything attached to it go together or not at all
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Post{}).Where("id = ? and author_id = ?", pid, uid).Take(&Post{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&Post{ID: pid}).Association("Tags").Clear().Error
+		if err != nil {
+			return err
+		}
 
-	db = db.Debug().Model(&Post{}).Where("id = ? and author_id = ?", pid, uid).Take(&Post{}).Delete(&Post{})
+		err = tx.Model(&Post{ID: pid}).Association("Media").Clear().Error
+		if err != nil {
+			return err
+		}
 
-	if db.Error != nil {
-		if gorm.IsRecordNotFoundError(db.Error) {
+		err = tx.Where("post_id = ?", pid).Delete(&PostSlug{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("post_id = ?", pid).Delete(&Comment{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("post_id = ?", pid).Delete(&PostRevision{}).Error
+		if err != nil {
+			return err
+		}
+
+		deleted := tx.Where("id = ? and author_id = ?", pid, uid).Delete(&Post{})
+		rowsAffected = deleted.RowsAffected
+		return deleted.Error
+	})
+
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
 			return 0, errors.New("Post not found")
 		}
-		return 0, db.Error
+		return 0, err
 	}
 
-	return db.RowsAffected, nil
+	return rowsAffected, nil
 }
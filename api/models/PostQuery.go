@@ -0,0 +1,207 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	DefaultPostLimit = 20
+	MaxPostLimit     = 100
+
+	SortNewest = "-created_at"
+	SortOldest = "created_at"
+)
+
+var ErrInvalidCursor = errors.New("Invalid cursor")
+
+// PostQuery describes a page of the post listing. The zero value returns the
+// newest DefaultPostLimit posts.
+type PostQuery struct {
+	Limit    int
+	Cursor   string
+	AuthorID uint32
+	Since    time.Time
+	Until    time.Time
+	Sort     string
+	Tags     []string
+	TagMode  string
+	Status   string
+
+	// ViewerID is the user asking, 0 when anonymous. Only published posts
+	// and the viewer's own posts are listed.
+	ViewerID uint32
+}
+
+// Validate normalises the limit and sort order and rejects unknown values.
+func (q *PostQuery) Validate() error {
+	if q.Limit == 0 {
+		q.Limit = DefaultPostLimit
+	}
+
+	if q.Limit < 0 || q.Limit > MaxPostLimit {
+		return fmt.Errorf("Limit must be between 1 and %d", MaxPostLimit)
+	}
+
+	switch q.Sort {
+	case "":
+		q.Sort = SortNewest
+	case SortNewest, SortOldest:
+	default:
+		return errors.New("Sort must be created_at or -created_at")
+	}
+
+	if q.Status != "" && !ValidPostStatus(q.Status) {
+		return errors.New("Status must be draft, scheduled, published or archived")
+	}
+
+	switch q.TagMode {
+	case "":
+		q.TagMode = TagModeAll
+	case TagModeAll, TagModeAny:
+	default:
+		return errors.New("Tag mode must be all or any")
+	}
+
+	tags, err := NormalizeTagNames(q.Tags)
+	if err != nil {
+		return err
+	}
+	q.Tags = tags
+
+	if !q.Since.IsZero() && !q.Until.IsZero() && q.Until.Before(q.Since) {
+		return errors.New("Until must be after since")
+	}
+
+	return nil
+}
+
+// postCursor is the position of the last post on a page. It is handed to
+// clients base64 encoded so they treat it as opaque.
+type postCursor struct {
+	CreatedAt time.Time
+	ID        uint64
+}
+
+func encodeCursor(p Post) string {
+	raw := fmt.Sprintf("%d:%d", p.CreatedAt.UnixNano(), p.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (postCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return postCursor{}, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 2 {
+		return postCursor{}, ErrInvalidCursor
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return postCursor{}, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return postCursor{}, ErrInvalidCursor
+	}
+
+	return postCursor{CreatedAt: time.Unix(0, nanos), ID: id}, nil
+}
+
+// scope applies the filters, ordering and cursor position of the query.
+func (q PostQuery) scope(db *gorm.DB) (*gorm.DB, error) {
+	db = visibleTo(db, q.ViewerID)
+
+	if q.Status != "" {
+		db = db.Where("status = ?", q.Status)
+	}
+
+	if q.AuthorID != 0 {
+		db = db.Where("author_id = ?", q.AuthorID)
+	}
+
+	if !q.Since.IsZero() {
+		db = db.Where("created_at >= ?", q.Since)
+	}
+
+	if !q.Until.IsZero() {
+		db = db.Where("created_at < ?", q.Until)
+	}
+
+	if len(q.Tags) > 0 {
+		// posts carrying any of the tags, or all of them when every tag
+		// has to match
+		tagged := db.New().Table("post_tags").
+			Select("post_tags.post_id").
+			Joins("JOIN tags ON tags.id = post_tags.tag_id").
+			Where("tags.name IN (?)", q.Tags)
+
+		if q.TagMode == TagModeAll {
+			tagged = tagged.Group("post_tags.post_id").Having("COUNT(DISTINCT tags.id) = ?", len(q.Tags))
+		}
+
+		db = db.Where("id IN (?)", tagged.QueryExpr())
+	}
+
+	cmp, order := "<", "desc"
+	if q.Sort == SortOldest {
+		cmp, order = ">", "asc"
+	}
+
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		db = db.Where(fmt.Sprintf("created_at %s ? OR (created_at = ? AND id %s ?)", cmp, cmp), c.CreatedAt, c.CreatedAt, c.ID)
+	}
+
+	return db.Order("created_at " + order).Order("id " + order), nil
+}
+
+// FindPosts returns one page of posts matching q along with the cursor of the
+// next page, which is empty once the last page has been reached.
+func (p *Post) FindPosts(db *gorm.DB, q PostQuery) (*[]Post, string, error) {
+	var err error
+
+	err = q.Validate()
+	if err != nil {
+		return &[]Post{}, "", err
+	}
+
+	query, err := q.scope(db.Model(&Post{}))
+	if err != nil {
+		return &[]Post{}, "", err
+	}
+
+	// fetch one extra row to find out whether there is another page
+	posts := []Post{}
+	err = query.Limit(q.Limit + 1).Find(&posts).Error
+	if err != nil {
+		return &[]Post{}, "", err
+	}
+
+	nextCursor := ""
+	if len(posts) > q.Limit {
+		posts = posts[:q.Limit]
+		nextCursor = encodeCursor(posts[len(posts)-1])
+	}
+
+	err = PreloadPosts(db, posts)
+	if err != nil {
+		return &[]Post{}, "", err
+	}
+
+	return &posts, nextCursor, nil
+}
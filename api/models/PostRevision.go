@@ -0,0 +1,153 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/dmdinh22/go-blog/api/utils/diff"
+)
+
+var ErrRevisionNotFound = errors.New("Revision not found")
+
+// PostRevision is the title and content of a post as it was after a save.
+// Revisions are numbered from 1 per post.
+type PostRevision struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	PostID    uint64    `gorm:"not null;unique_index:idx_post_revision" json:"postId"`
+	Revision  int       `gorm:"not null;unique_index:idx_post_revision" json:"revision"`
+	Title     string    `gorm:"size:255;not null" json:"title"`
+	Content   string    `gorm:"type:text;not null" json:"content"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"createdAt"`
+}
+
+// RevisionDiff is the unified diff between two revisions of a post
+type RevisionDiff struct {
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Diff string `json:"diff"`
+}
+
+// recordRevision stores the current title and content of the post as its
+// next revision.
+func (p *Post) recordRevision(db *gorm.DB) error {
+	last, err := p.LatestRevision(db, p.ID)
+	if err != nil {
+		return err
+	}
+
+	revision := PostRevision{
+		PostID:    p.ID,
+		Revision:  last + 1,
+		Title:     p.Title,
+		Content:   p.Content,
+		CreatedAt: time.Now(),
+	}
+
+	return db.Model(&PostRevision{}).Create(&revision).Error
+}
+
+// ensureFirstRevision records the saved state of a post that predates
+// revision history, so its original text survives the first update.
+func ensureFirstRevision(db *gorm.DB, pid uint64) error {
+	var count int
+	err := db.Model(&PostRevision{}).Where("post_id = ?", pid).Count(&count).Error
+	if err != nil || count > 0 {
+		return err
+	}
+
+	current := Post{}
+	err = db.Model(&Post{}).Where("id = ?", pid).Take(&current).Error
+	if err != nil {
+		return err
+	}
+
+	return current.recordRevision(db)
+}
+
+// GetRevisions returns the revisions of a post, newest first
+func (p *Post) GetRevisions(db *gorm.DB, pid uint64) (*[]PostRevision, error) {
+	revisions := []PostRevision{}
+	err := db.Model(&PostRevision{}).Where("post_id = ?", pid).Order("revision desc").Find(&revisions).Error
+
+	if err != nil {
+		return &[]PostRevision{}, err
+	}
+
+	return &revisions, nil
+}
+
+// GetRevision returns one revision of a post
+func (p *Post) GetRevision(db *gorm.DB, pid uint64, revision int) (*PostRevision, error) {
+	result := PostRevision{}
+	err := db.Model(&PostRevision{}).Where("post_id = ? AND revision = ?", pid, revision).Take(&result).Error
+
+	if gorm.IsRecordNotFoundError(err) {
+		return &PostRevision{}, ErrRevisionNotFound
+	}
+
+	if err != nil {
+		return &PostRevision{}, err
+	}
+
+	return &result, nil
+}
+
+// DiffRevisions compares two revisions of a post. The title is diffed as the
+// first line of the text. Revision 0 is the empty text, so the first
+// revision can be compared with what came before it.
+func (p *Post) DiffRevisions(db *gorm.DB, pid uint64, from, to int) (*RevisionDiff, error) {
+	a, err := p.revisionText(db, pid, from)
+	if err != nil {
+		return &RevisionDiff{}, err
+	}
+
+	b, err := p.revisionText(db, pid, to)
+	if err != nil {
+		return &RevisionDiff{}, err
+	}
+
+	text, err := diff.Unified(
+		fmt.Sprintf("revision %d", from),
+		fmt.Sprintf("revision %d", to),
+		a,
+		b,
+		diff.DefaultContext,
+	)
+	if err != nil {
+		return &RevisionDiff{}, err
+	}
+
+	return &RevisionDiff{From: from, To: to, Diff: text}, nil
+}
+
+// revisionText is the title and content of a revision as diffed, empty for
+// revision 0
+func (p *Post) revisionText(db *gorm.DB, pid uint64, revision int) (string, error) {
+	if revision == 0 {
+		return "", nil
+	}
+
+	r, err := p.GetRevision(db, pid, revision)
+	if err != nil {
+		return "", err
+	}
+
+	return r.Title + "\n\n" + r.Content, nil
+}
+
+// LatestRevision returns the number of the newest revision of a post, 0
+// when it has none.
+func (p *Post) LatestRevision(db *gorm.DB, pid uint64) (int, error) {
+	last := 0
+	row := db.Model(&PostRevision{}).Where("post_id = ?", pid).Select("COALESCE(MAX(revision), 0)").Row()
+
+	err := row.Scan(&last)
+	if err != nil {
+		return 0, err
+	}
+
+	return last, nil
+}
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"fmt"
+	"html"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/dmdinh22/go-blog/api/utils/slug"
+)
+
+// PostSlug is a slug a post had before its title changed. Old slugs stay
+// reserved for their post so links to them keep working.
+type PostSlug struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	PostID    uint64    `gorm:"not null;index" json:"postId"`
+	Slug      string    `gorm:"size:255;not null;unique_index" json:"slug"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"createdAt"`
+}
+
+// slugBase is the slug of a title before any collision suffix. Titles are
+// stored html escaped.
+func slugBase(title string) string {
+	base := slug.Make(html.UnescapeString(title))
+	if base == "" {
+		base = "post"
+	}
+	return base
+}
+
+// uniqueSlug returns the slug for title that no other post uses, now or in
+// its history, adding -2, -3, ... on collisions. pid is the post the slug is
+// for, 0 for a new post.
+func uniqueSlug(db *gorm.DB, title string, pid uint64) (string, error) {
+	base := slugBase(title)
+	taken := map[string]bool{}
+
+	current := []string{}
+	err := db.Model(&Post{}).Where("(slug = ? OR slug LIKE ?) AND id <> ?", base, base+"-%", pid).Pluck("slug", &current).Error
+	if err != nil {
+		return "", err
+	}
+
+	old := []string{}
+	err = db.Model(&PostSlug{}).Where("(slug = ? OR slug LIKE ?) AND post_id <> ?", base, base+"-%", pid).Pluck("slug", &old).Error
+	if err != nil {
+		return "", err
+	}
+
+	for _, s := range append(current, old...) {
+		taken[s] = true
+	}
+
+	candidate := base
+	for n := 2; taken[candidate]; n++ {
+		candidate = fmt.Sprintf("%s-%d", base, n)
+	}
+
+	return candidate, nil
+}
+
+// BeforeCreate gives every new post a slug made from its title
+func (p *Post) BeforeCreate(db *gorm.DB) error {
+	if p.Slug != "" {
+		return nil
+	}
+
+	s, err := uniqueSlug(db, p.Title, 0)
+	if err != nil {
+		return err
+	}
+
+	p.Slug = s
+	return nil
+}
+
+// updateSlug moves the post to a slug made from its new title when the
+// title changes, keeping the slug it had in the history.
+func (p *Post) updateSlug(db *gorm.DB, current *Post) error {
+	if current.Slug != "" && slugBase(p.Title) == slugBase(current.Title) {
+		p.Slug = current.Slug
+		return nil
+	}
+
+	s, err := uniqueSlug(db, p.Title, p.ID)
+	if err != nil {
+		return err
+	}
+
+	p.Slug = s
+	if s == current.Slug {
+		return nil
+	}
+
+	if current.Slug != "" {
+		err = db.Model(&PostSlug{}).Create(&PostSlug{PostID: p.ID, Slug: current.Slug, CreatedAt: time.Now()}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	// a title changed back takes its old slug out of the history
+	err = db.Where("post_id = ? AND slug = ?", p.ID, s).Delete(&PostSlug{}).Error
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&Post{}).Where("id = ?", p.ID).UpdateColumn("slug", s).Error
+}
+
+// GetPostBySlug returns the post whose current slug is s
+func (p *Post) GetPostBySlug(db *gorm.DB, s string) (*Post, error) {
+	err := db.Model(&Post{}).Where("slug = ?", s).Take(&p).Error
+
+	if err != nil {
+		return &Post{}, err
+	}
+
+	err = p.preload(db)
+	if err != nil {
+		return &Post{}, err
+	}
+
+	return p, nil
+}
+
+// GetPostByOldSlug returns the post that used to have slug s
+func (p *Post) GetPostByOldSlug(db *gorm.DB, s string) (*Post, error) {
+	old := PostSlug{}
+	err := db.Model(&PostSlug{}).Where("slug = ?", s).Take(&old).Error
+
+	if err != nil {
+		return &Post{}, err
+	}
+
+	return p.GetPostByID(db, old.PostID)
+}
+
+// GetOldSlugs returns every slug posts had before their titles changed
+func GetOldSlugs(db *gorm.DB) (*[]PostSlug, error) {
+	slugs := []PostSlug{}
+	err := db.Model(&PostSlug{}).Order("id asc").Find(&slugs).Error
+
+	if err != nil {
+		return &[]PostSlug{}, err
+	}
+
+	return &slugs, nil
+}
+
+// BackfillSlugs gives posts created before slugs existed one, oldest first.
+// It returns the number of posts updated.
+func BackfillSlugs(db *gorm.DB) (int, error) {
+	posts := []Post{}
+	err := db.Model(&Post{}).Where("slug IS NULL OR slug = ''").Order("id asc").Find(&posts).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for i, post := range posts {
+		s, err := uniqueSlug(db, post.Title, post.ID)
+		if err != nil {
+			return i, err
+		}
+
+		err = db.Model(&Post{}).Where("id = ?", post.ID).UpdateColumn("slug", s).Error
+		if err != nil {
+			return i, err
+		}
+	}
+
+	return len(posts), nil
+}
+
+// PostsWithoutSlug counts the posts BackfillSlugs would give a slug, every
+// post while the migrations have yet to add the column
+func PostsWithoutSlug(db *gorm.DB) (int, error) {
+	var count int
+	if !db.HasTable(&Post{}) {
+		return 0, nil
+	}
+
+	query := db.Model(&Post{})
+	if db.Dialect().HasColumn("posts", "slug") {
+		query = query.Where("slug IS NULL OR slug = ''")
+	}
+
+	err := query.Count(&count).Error
+	return count, err
+}
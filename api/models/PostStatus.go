@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// A post starts as a draft or is published straight away. Scheduled posts
+// are published by the scheduler once PublishAt has passed, and archived
+// posts are hidden again without being deleted.
+const (
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+	PostStatusArchived  = "archived"
+)
+
+func ValidPostStatus(status string) bool {
+	switch status {
+	case PostStatusDraft, PostStatusScheduled, PostStatusPublished, PostStatusArchived:
+		return true
+	}
+	return false
+}
+
+// VisibleTo reports whether the user may read the post. Anyone may read
+// published posts; the others are only visible to their author. A uid of 0
+// is an anonymous reader.
+func (p *Post) VisibleTo(uid uint32) bool {
+	return p.Status == PostStatusPublished || (uid != 0 && uid == p.AuthorID)
+}
+
+// visibleTo limits a query on posts to the ones the user may read
+func visibleTo(db *gorm.DB, uid uint32) *gorm.DB {
+	if uid == 0 {
+		return db.Where("posts.status = ?", PostStatusPublished)
+	}
+
+	return db.Where("posts.status = ? OR posts.author_id = ?", PostStatusPublished, uid)
+}
+
+// PublishDuePosts publishes the scheduled posts whose PublishAt is not after
+// now and returns how many there were.
+func PublishDuePosts(db *gorm.DB, now time.Time) (int64, error) {
+	db = db.Model(&Post{}).
+		Where("status = ? AND publish_at <= ?", PostStatusScheduled, now).
+		UpdateColumns(map[string]interface{}{
+			"status":     PostStatusPublished,
+			"updated_at": now,
+		})
+
+	if db.Error != nil {
+		return 0, db.Error
+	}
+
+	return db.RowsAffected, nil
+}
+
+// NextScheduledPublish returns when the next scheduled post is due, or nil
+// when nothing is scheduled.
+func NextScheduledPublish(db *gorm.DB) (*time.Time, error) {
+	post := Post{}
+	err := db.Model(&Post{}).
+		Where("status = ? AND publish_at IS NOT NULL", PostStatusScheduled).
+		Order("publish_at asc").
+		Take(&post).Error
+
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return post.PublishAt, nil
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// PreloadPosts fills in everything a post response carries besides its own
+// columns. The number of queries does not depend on the number of posts.
+func PreloadPosts(db *gorm.DB, posts []Post) error {
+	err := PreloadAuthors(db, posts)
+	if err != nil {
+		return err
+	}
+
+	err = PreloadCommentCounts(db, posts)
+	if err != nil {
+		return err
+	}
+
+	err = PreloadTags(db, posts)
+	if err != nil {
+		return err
+	}
+
+	return PreloadMedia(db, posts)
+}
+
+// PreloadAuthors fills in the Author of every post using a single IN query,
+// however many posts there are.
+func PreloadAuthors(db *gorm.DB, posts []Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]uint32, len(posts))
+	for i, post := range posts {
+		ids[i] = post.AuthorID
+	}
+
+	byID, err := usersByID(db, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		author, ok := byID[posts[i].AuthorID]
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		posts[i].Author = author
+	}
+
+	return nil
+}
+
+// PreloadCommentAuthors is PreloadAuthors for comments
+func PreloadCommentAuthors(db *gorm.DB, comments []Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+
+	ids := make([]uint32, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.AuthorID
+	}
+
+	byID, err := usersByID(db, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range comments {
+		author, ok := byID[comments[i].AuthorID]
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		comments[i].Author = author
+	}
+
+	return nil
+}
+
+// PreloadCommentCounts sets CommentCount on every post with one grouped query
+func PreloadCommentCounts(db *gorm.DB, posts []Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]uint64, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	counts := []struct {
+		PostID uint64
+		Count  int
+	}{}
+	err := db.Model(&Comment{}).Select("post_id, count(*) as count").Where("post_id IN (?)", ids).Group("post_id").Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	byPost := make(map[uint64]int, len(counts))
+	for _, c := range counts {
+		byPost[c.PostID] = c.Count
+	}
+
+	for i := range posts {
+		posts[i].CommentCount = byPost[posts[i].ID]
+	}
+
+	return nil
+}
+
+func usersByID(db *gorm.DB, ids []uint32) (map[uint32]User, error) {
+	unique := []uint32{}
+	seen := map[uint32]bool{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	users := []User{}
+	err := db.Model(&User{}).Where("id IN (?)", unique).Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint32]User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	return byID, nil
+}
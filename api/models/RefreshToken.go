@@ -0,0 +1,109 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("Invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("Refresh token has already been used")
+)
+
+// RefreshToken is a long lived credential exchanged for new access tokens.
+// Tokens rotate on every use: the presented token is revoked and points at
+// its replacement.
+type RefreshToken struct {
+	ID           uint64     `gorm:"primary_key;auto_increment" json:"id"`
+	UserID       uint32     `gorm:"not null;index" json:"userId"`
+	TokenHash    string     `gorm:"size:64;not null;unique" json:"-"`
+	ExpiresAt    time.Time  `gorm:"not null" json:"expiresAt"`
+	RevokedAt    *time.Time `json:"revokedAt"`
+	ReplacedByID *uint64    `json:"replacedById"`
+	CreatedAt    time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"createdAt"`
+}
+
+func (rt *RefreshToken) CreateRefreshToken(db *gorm.DB) (*RefreshToken, error) {
+	var err error
+	err = db.Model(&RefreshToken{}).Create(&rt).Error
+
+	if err != nil {
+		return &RefreshToken{}, err
+	}
+
+	return rt, nil
+}
+
+// RotateRefreshToken revokes the token with oldHash and stores rt as its
+// replacement. Presenting a token that was already revoked is treated as
+// theft, and every refresh token of that user is revoked.
+func (rt *RefreshToken) RotateRefreshToken(db *gorm.DB, oldHash string) (*RefreshToken, error) {
+	current := RefreshToken{}
+	err := db.Model(&RefreshToken{}).Where("token_hash = ?", oldHash).Take(&current).Error
+
+	if gorm.IsRecordNotFoundError(err) {
+		return &RefreshToken{}, ErrRefreshTokenInvalid
+	}
+
+	if err != nil {
+		return &RefreshToken{}, err
+	}
+
+	if current.RevokedAt != nil {
+		err = RevokeUserRefreshTokens(db, current.UserID)
+		if err != nil {
+			return &RefreshToken{}, err
+		}
+
+		return &RefreshToken{}, ErrRefreshTokenReused
+	}
+
+	if current.ExpiresAt.Before(time.Now()) {
+		return &RefreshToken{}, ErrRefreshTokenInvalid
+	}
+
+	rt.UserID = current.UserID
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&RefreshToken{}).Create(rt).Error
+		if err != nil {
+			return err
+		}
+
+		// only one of two concurrent refreshes of the same token may win
+		result := tx.Model(&RefreshToken{}).Where("id = ? AND revoked_at IS NULL", current.ID).Updates(map[string]interface{}{
+			"revoked_at":     time.Now(),
+			"replaced_by_id": rt.ID,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected != 1 {
+			return ErrRefreshTokenReused
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return &RefreshToken{}, err
+	}
+
+	return rt, nil
+}
+
+// RevokeRefreshToken revokes a single token belonging to uid
+func RevokeRefreshToken(db *gorm.DB, hash string, uid uint32) error {
+	return db.Model(&RefreshToken{}).
+		Where("token_hash = ? AND user_id = ? AND revoked_at IS NULL", hash, uid).
+		Update("revoked_at", time.Now()).Error
+}
+
+func RevokeUserRefreshTokens(db *gorm.DB, uid uint32) error {
+	return db.Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", uid).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// PostRepository stores posts. Lookups of missing posts fail with
+// gorm.ErrRecordNotFound whatever the implementation.
+type PostRepository interface {
+	Create(post *Post) (*Post, error)
+	// Find returns a page of posts and the cursor of the next one, see
+	// PostQuery
+	Find(q PostQuery) (*[]Post, string, error)
+	GetByID(pid uint64) (*Post, error)
+	GetBySlug(slug string) (*Post, error)
+	// GetByOldSlug returns the post that had slug before its title changed
+	GetByOldSlug(slug string) (*Post, error)
+	// Update saves the post with the ID of post
+	Update(post *Post) (*Post, error)
+	// Delete removes the post when uid is its author
+	Delete(pid uint64, uid uint32) (int64, error)
+}
+
+// UserRepository stores users. Lookups of missing users fail with
+// gorm.ErrRecordNotFound whatever the implementation.
+type UserRepository interface {
+	Create(user *User) (*User, error)
+	FindAll() (*[]User, error)
+	GetByID(uid uint32) (*User, error)
+	GetByEmail(email string) (*User, error)
+	// Update saves the username, email and password of user as user uid
+	Update(uid uint32, user *User) (*User, error)
+	UpdateRole(uid uint32, role string) (*User, error)
+	Delete(uid uint32) (int64, error)
+}
+
+// GormPostRepository keeps posts in the database
+type GormPostRepository struct {
+	DB *gorm.DB
+}
+
+func NewGormPostRepository(db *gorm.DB) *GormPostRepository {
+	return &GormPostRepository{DB: db}
+}
+
+func (r *GormPostRepository) Create(post *Post) (*Post, error) {
+	return post.CreatePost(r.DB)
+}
+
+func (r *GormPostRepository) Find(q PostQuery) (*[]Post, string, error) {
+	post := Post{}
+	return post.FindPosts(r.DB, q)
+}
+
+func (r *GormPostRepository) GetByID(pid uint64) (*Post, error) {
+	post := Post{}
+	return post.GetPostByID(r.DB, pid)
+}
+
+func (r *GormPostRepository) GetBySlug(slug string) (*Post, error) {
+	post := Post{}
+	return post.GetPostBySlug(r.DB, slug)
+}
+
+func (r *GormPostRepository) GetByOldSlug(slug string) (*Post, error) {
+	post := Post{}
+	return post.GetPostByOldSlug(r.DB, slug)
+}
+
+func (r *GormPostRepository) Update(post *Post) (*Post, error) {
+	return post.UpdatePost(r.DB)
+}
+
+func (r *GormPostRepository) Delete(pid uint64, uid uint32) (int64, error) {
+	post := Post{}
+	return post.DeletePost(r.DB, pid, uid)
+}
+
+// GormUserRepository keeps users in the database
+type GormUserRepository struct {
+	DB *gorm.DB
+}
+
+func NewGormUserRepository(db *gorm.DB) *GormUserRepository {
+	return &GormUserRepository{DB: db}
+}
+
+func (r *GormUserRepository) Create(user *User) (*User, error) {
+	return user.CreateUser(r.DB)
+}
+
+func (r *GormUserRepository) FindAll() (*[]User, error) {
+	user := User{}
+	return user.GetAllUsers(r.DB)
+}
+
+func (r *GormUserRepository) GetByID(uid uint32) (*User, error) {
+	user := User{}
+	return user.GetUserById(r.DB, uid)
+}
+
+func (r *GormUserRepository) GetByEmail(email string) (*User, error) {
+	user := User{}
+	return user.GetUserByEmail(r.DB, email)
+}
+
+func (r *GormUserRepository) Update(uid uint32, user *User) (*User, error) {
+	return user.UpdateUser(r.DB, uid)
+}
+
+func (r *GormUserRepository) UpdateRole(uid uint32, role string) (*User, error) {
+	user := User{}
+	return user.UpdateUserRole(r.DB, uid, role)
+}
+
+func (r *GormUserRepository) Delete(uid uint32) (int64, error) {
+	user := User{}
+	return user.DeleteUser(r.DB, uid)
+}
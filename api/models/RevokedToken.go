@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// RevokedToken is a denylist entry for an access token revoked on logout
+type RevokedToken struct {
+	JTI       string    `gorm:"primary_key;size:64" json:"jti"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expiresAt"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"createdAt"`
+}
+
+// TokenDenylist is an auth.Denylist persisted in the revoked_tokens table so
+// every replica sees the same revocations.
+type TokenDenylist struct {
+	DB *gorm.DB
+}
+
+func NewTokenDenylist(db *gorm.DB) *TokenDenylist {
+	return &TokenDenylist{DB: db}
+}
+
+func (d *TokenDenylist) Revoke(jti string, expiresAt time.Time) error {
+	// entries are useless once the token itself has expired
+	err := d.DB.Where("expires_at < ?", time.Now()).Delete(&RevokedToken{}).Error
+	if err != nil {
+		return err
+	}
+
+	return d.DB.Create(&RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+}
+
+func (d *TokenDenylist) IsRevoked(jti string) (bool, error) {
+	var count int
+	err := d.DB.Model(&RevokedToken{}).Where("jti = ? AND expires_at > ?", jti, time.Now()).Count(&count).Error
+
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
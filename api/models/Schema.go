@@ -0,0 +1,27 @@
+package models
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/dmdinh22/go-blog/api/migrations"
+)
+
+// Migrate applies the pending schema migrations, then fills in what the
+// migrations cannot do in SQL.
+func Migrate(db *gorm.DB) error {
+	migrator, err := migrations.New(db.DB(), db.Dialect().GetName())
+	if err != nil {
+		return err
+	}
+
+	_, err = migrator.Up(context.Background())
+	if err != nil {
+		return err
+	}
+
+	// posts created before slugs existed get one
+	_, err = BackfillSlugs(db)
+	return err
+}
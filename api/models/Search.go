@@ -0,0 +1,181 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/jinzhu/gorm"
+)
+
+// PostSearcher ranks published posts by how well their title and content
+// match a free text query.
+type PostSearcher interface {
+	SearchPosts(db *gorm.DB, query string, limit int) (*[]Post, error)
+}
+
+// NewPostSearcher returns the searcher using the native full-text support of
+// the given database driver, falling back to ranking posts in Go.
+func NewPostSearcher(driver string) PostSearcher {
+	switch driver {
+	case "mysql":
+		return MySQLSearcher{}
+	case "postgres":
+		return PostgresSearcher{}
+	default:
+		return TextSearcher{}
+	}
+}
+
+// ValidateSearch rejects empty queries and out of range limits
+func ValidateSearch(query string, limit int) error {
+	if strings.TrimSpace(query) == "" {
+		return errors.New("Required Query")
+	}
+
+	if limit < 1 || limit > MaxPostLimit {
+		return fmt.Errorf("Limit must be between 1 and %d", MaxPostLimit)
+	}
+
+	return nil
+}
+
+// MySQLSearcher uses a FULLTEXT index with MATCH ... AGAINST
+type MySQLSearcher struct{}
+
+const mysqlMatch = "MATCH (title, content) AGAINST (? IN NATURAL LANGUAGE MODE)"
+
+func (MySQLSearcher) SearchPosts(db *gorm.DB, query string, limit int) (*[]Post, error) {
+	err := ValidateSearch(query, limit)
+	if err != nil {
+		return &[]Post{}, err
+	}
+
+	posts := []Post{}
+	err = visibleTo(db.Model(&Post{}), 0).
+		Where(mysqlMatch, query).
+		Order(gorm.Expr(mysqlMatch+" DESC", query)).Order("id desc").
+		Limit(limit).Find(&posts).Error
+	if err != nil {
+		return &[]Post{}, err
+	}
+
+	return searchResults(db, posts)
+}
+
+// PostgresSearcher uses a GIN index over the tsvector of title and content
+type PostgresSearcher struct{}
+
+const postgresDocument = "to_tsvector('english', title || ' ' || content)"
+
+func (PostgresSearcher) SearchPosts(db *gorm.DB, query string, limit int) (*[]Post, error) {
+	err := ValidateSearch(query, limit)
+	if err != nil {
+		return &[]Post{}, err
+	}
+
+	posts := []Post{}
+	err = visibleTo(db.Model(&Post{}), 0).
+		Where(postgresDocument+" @@ plainto_tsquery('english', ?)", query).
+		Order(gorm.Expr("ts_rank("+postgresDocument+", plainto_tsquery('english', ?)) DESC", query)).Order("id desc").
+		Limit(limit).Find(&posts).Error
+	if err != nil {
+		return &[]Post{}, err
+	}
+
+	return searchResults(db, posts)
+}
+
+// TextSearcher ranks posts in Go and needs no index. It narrows the posts
+// down with LIKE, so it suits tests and small databases only.
+type TextSearcher struct{}
+
+func (TextSearcher) SearchPosts(db *gorm.DB, query string, limit int) (*[]Post, error) {
+	err := ValidateSearch(query, limit)
+	if err != nil {
+		return &[]Post{}, err
+	}
+
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return &[]Post{}, nil
+	}
+
+	conditions := []string{}
+	args := []interface{}{}
+	for _, term := range terms {
+		conditions = append(conditions, "LOWER(title) LIKE ? OR LOWER(content) LIKE ?")
+		args = append(args, "%"+term+"%", "%"+term+"%")
+	}
+
+	posts := []Post{}
+	err = visibleTo(db.Model(&Post{}), 0).Where(strings.Join(conditions, " OR "), args...).Find(&posts).Error
+	if err != nil {
+		return &[]Post{}, err
+	}
+
+	scores := make(map[uint64]int, len(posts))
+	matches := []Post{}
+	for _, post := range posts {
+		score := scorePost(post, terms)
+		if score > 0 {
+			scores[post.ID] = score
+			matches = append(matches, post)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if scores[matches[i].ID] != scores[matches[j].ID] {
+			return scores[matches[i].ID] > scores[matches[j].ID]
+		}
+		return matches[i].ID > matches[j].ID
+	})
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	return searchResults(db, matches)
+}
+
+func searchResults(db *gorm.DB, posts []Post) (*[]Post, error) {
+	err := PreloadPosts(db, posts)
+	if err != nil {
+		return &[]Post{}, err
+	}
+
+	return &posts, nil
+}
+
+// searchTerms splits a query into lowercase words
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// scorePost counts the query words in a post, a word in the title weighing
+// as much as three in the content.
+func scorePost(post Post, terms []string) int {
+	score := 0
+	title := searchTerms(post.Title)
+	content := searchTerms(post.Content)
+
+	for _, term := range terms {
+		for _, word := range title {
+			if word == term {
+				score += 3
+			}
+		}
+
+		for _, word := range content {
+			if word == term {
+				score++
+			}
+		}
+	}
+
+	return score
+}
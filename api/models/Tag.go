@@ -0,0 +1,188 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	MaxTagsPerPost   = 10
+	MaxTagNameLength = 50
+
+	TagModeAll = "all"
+	TagModeAny = "any"
+)
+
+// Tag labels posts. Tags are created on first use and shared between posts
+// through the post_tags join table.
+type Tag struct {
+	ID   uint32 `gorm:"primary_key;auto_increment" json:"id"`
+	Name string `gorm:"size:50;not null;unique" json:"name"`
+}
+
+// TagCount is a tag with the number of posts carrying it
+type TagCount struct {
+	ID        uint32 `json:"id"`
+	Name      string `json:"name"`
+	PostCount int    `json:"postCount"`
+}
+
+// UnmarshalJSON accepts either a bare tag name or a tag object, so clients
+// can send "tags": ["go", "databases"] when creating posts.
+func (t *Tag) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = Tag{Name: name}
+		return nil
+	}
+
+	type tagFields Tag
+	fields := tagFields{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	*t = Tag(fields)
+	return nil
+}
+
+// NormalizeTagNames lowercases and trims tag names and drops duplicates
+func NormalizeTagNames(names []string) ([]string, error) {
+	normalized := []string{}
+	seen := map[string]bool{}
+
+	for _, name := range names {
+		name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+
+		if name == "" {
+			return nil, errors.New("Tag names cannot be empty")
+		}
+
+		if len(name) > MaxTagNameLength {
+			return nil, fmt.Errorf("Tag names must be at most %d characters", MaxTagNameLength)
+		}
+
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+
+	return normalized, nil
+}
+
+func tagNames(tags []Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+// FindOrCreateTags returns the tags with the given names, creating the ones
+// that do not exist yet.
+func FindOrCreateTags(db *gorm.DB, names []string) ([]Tag, error) {
+	tags := []Tag{}
+	if len(names) == 0 {
+		return tags, nil
+	}
+
+	err := db.Model(&Tag{}).Where("name IN (?)", names).Find(&tags).Error
+	if err != nil {
+		return []Tag{}, err
+	}
+
+	existing := map[string]bool{}
+	for _, tag := range tags {
+		existing[tag.Name] = true
+	}
+
+	for _, name := range names {
+		if existing[name] {
+			continue
+		}
+
+		tag := Tag{Name: name}
+		err = db.Model(&Tag{}).Create(&tag).Error
+		if err != nil {
+			return []Tag{}, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+// GetTagsWithPostCounts lists every tag with its number of published posts,
+// most used first.
+func GetTagsWithPostCounts(db *gorm.DB) (*[]TagCount, error) {
+	counts := []TagCount{}
+	err := db.Table("tags").
+		Select("tags.id, tags.name, COUNT(posts.id) AS post_count").
+		Joins("LEFT JOIN post_tags ON post_tags.tag_id = tags.id").
+		Joins("LEFT JOIN posts ON posts.id = post_tags.post_id AND posts.status = ?", PostStatusPublished).
+		Group("tags.id, tags.name").
+		Order("post_count desc").Order("tags.name asc").
+		Scan(&counts).Error
+
+	if err != nil {
+		return &[]TagCount{}, err
+	}
+
+	return &counts, nil
+}
+
+// PreloadTags fills in the Tags of every post with a single query
+func PreloadTags(db *gorm.DB, posts []Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]uint64, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	rows := []struct {
+		PostID uint64
+		ID     uint32
+		Name   string
+	}{}
+	err := db.Table("post_tags").
+		Select("post_tags.post_id, tags.id, tags.name").
+		Joins("JOIN tags ON tags.id = post_tags.tag_id").
+		Where("post_tags.post_id IN (?)", ids).
+		Order("tags.name asc").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	byPost := map[uint64][]Tag{}
+	for _, row := range rows {
+		byPost[row.PostID] = append(byPost[row.PostID], Tag{ID: row.ID, Name: row.Name})
+	}
+
+	for i := range posts {
+		posts[i].Tags = byPost[posts[i].ID]
+		if posts[i].Tags == nil {
+			posts[i].Tags = []Tag{}
+		}
+	}
+
+	return nil
+}
+
+// setTags replaces the tags of a saved post
+func (p *Post) setTags(db *gorm.DB, names []string) error {
+	tags, err := FindOrCreateTags(db, names)
+	if err != nil {
+		return err
+	}
+
+	return db.Model(p).Association("Tags").Replace(tags).Error
+}
@@ -3,11 +3,11 @@ package models
 import (
 	"errors"
 	"html"
-	"log"
 	"strings"
 	"time"
 
 	"github.com/badoux/checkmail"
+	"github.com/dmdinh22/go-blog/api/auth"
 	"github.com/jinzhu/gorm"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -17,6 +17,7 @@ type User struct {
 	Username  string    `gorm:"size:255;not null;unique" 		json:"username"`
 	Email     string    `gorm:"size:100;not null;unique" 		json:"email"`
 	Password  string    `gorm:"size:100;not null;" 					json:"password"`
+	Role      string    `gorm:"size:20;not null;default:'user'" json:"role"`
 	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"	 	json:"createdAt"`
 	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" 	json:"updatedAt"`
 }
@@ -49,6 +50,7 @@ func (u *User) Prepare() {
 	u.ID = 0
 	u.Username = html.EscapeString(strings.TrimSpace(u.Username))
 	u.Email = html.EscapeString(strings.TrimSpace(u.Email))
+	u.Role = "" // roles are only granted by admins
 	u.CreatedAt = time.Now()
 	u.UpdatedAt = time.Now()
 }
@@ -110,7 +112,12 @@ func (u *User) Validate(action string) error {
 
 func (u *User) CreateUser(db *gorm.DB) (*User, error) {
 	var err error
-	err = db.Debug().Create(&u).Error
+
+	if u.Role == "" {
+		u.Role = auth.RoleUser
+	}
+
+	err = db.Create(&u).Error
 
 	if err != nil {
 		return &User{}, err
@@ -122,7 +129,7 @@ func (u *User) CreateUser(db *gorm.DB) (*User, error) {
 func (u *User) GetAllUsers(db *gorm.DB) (*[]User, error) {
 	var err error
 	users := []User{}
-	err = db.Debug().Model(&User{}).Limit(100).Find(&users).Error
+	err = db.Model(&User{}).Limit(100).Find(&users).Error
 
 	if err != nil {
 		return &[]User{}, err
@@ -133,7 +140,7 @@ func (u *User) GetAllUsers(db *gorm.DB) (*[]User, error) {
 
 func (u *User) GetUserById(db *gorm.DB, uid uint32) (*User, error) {
 	var err error
-	err = db.Debug().Model(User{}).Where("id = ?", uid).Take(&u).Error
+	err = db.Model(User{}).Where("id = ?", uid).Take(&u).Error
 
 	if err != nil {
 		return &User{}, err
@@ -145,32 +152,62 @@ func (u *User) GetUserById(db *gorm.DB, uid uint32) (*User, error) {
 	return u, err
 }
 
+func (u *User) GetUserByEmail(db *gorm.DB, email string) (*User, error) {
+	err := db.Model(User{}).Where("email = ?", email).Take(&u).Error
+
+	if err != nil {
+		return &User{}, err
+	}
+
+	return u, nil
+}
+
 func (u *User) UpdateUser(db *gorm.DB, uid uint32) (*User, error) {
 	// To hash the password
 	err := u.BeforeSave()
 
 	if err != nil {
-		log.Fatal(err)
+		return &User{}, err
 	}
 
-	db = db.Debug().Model(&User{}).Where("id = ?", uid).Take(&User{}).UpdateColumns(
+	err = db.Model(&User{}).Where("id = ?", uid).Take(&User{}).UpdateColumns(
 		map[string]interface{}{
-			"password":  u.Password,
-			"username":  u.Username,
-			"email":     u.Email,
-			"update_at": time.Now(),
+			"password":   u.Password,
+			"username":   u.Username,
+			"email":      u.Email,
+			"updated_at": time.Now(),
 		},
-	)
+	).Error
 
-	if db.Error != nil {
-		return &User{}, db.Error
+	if err != nil {
+		return &User{}, err
 	}
 
-	return u, nil
+	// reload the columns the update leaves alone, like the role
+	return u.GetUserById(db, uid)
+}
+
+func (u *User) UpdateUserRole(db *gorm.DB, uid uint32, role string) (*User, error) {
+	if !auth.ValidRole(role) {
+		return &User{}, errors.New("Invalid Role.")
+	}
+
+	err := db.Model(&User{}).Where("id = ?", uid).Take(&User{}).UpdateColumns(
+		map[string]interface{}{
+			"role":       role,
+			"updated_at": time.Now(),
+		},
+	).Error
+
+	if err != nil {
+		return &User{}, err
+	}
+
+	return u.GetUserById(db, uid)
 }
 
 func (u *User) DeleteUser(db *gorm.DB, uid uint32) (int64, error) {
-	db = db.Debug().Model(&User{}).Where("id = ?", uid).Take(&User{}).Delete(&User{})
+	db = db.Model(&User{}).Where("id = ?", uid).Take(&User{}).Delete(&User{})
 
 	if db.Error != nil {
 		return 0, db.Error
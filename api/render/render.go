@@ -0,0 +1,89 @@
+// Package render turns the Markdown body of a post into HTML or plain text.
+package render
+
+import (
+	"bytes"
+	"errors"
+	"html"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+// Formats a post body can be requested in
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatPlain    = "plain"
+)
+
+var ErrInvalidFormat = errors.New("Format must be markdown, html or plain")
+
+// Raw HTML in posts is let through by goldmark and cleaned up by the
+// sanitizer, so authors can use inline tags the allowlist permits.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+)
+
+// policy allows the formatting, links and images a post needs and strips
+// scripts, styles, event handlers and unsafe URLs.
+var policy = bluemonday.UGCPolicy()
+
+var strict = bluemonday.StrictPolicy()
+
+// ParseFormat checks a requested format, defaulting to html
+func ParseFormat(format string) (string, error) {
+	switch format {
+	case "":
+		return FormatHTML, nil
+	case FormatMarkdown, FormatHTML, FormatPlain:
+		return format, nil
+	}
+
+	return "", ErrInvalidFormat
+}
+
+// HTML renders Markdown to sanitized HTML
+func HTML(source string) (string, error) {
+	var out bytes.Buffer
+
+	err := markdown.Convert([]byte(source), &out)
+	if err != nil {
+		return "", err
+	}
+
+	return policy.Sanitize(out.String()), nil
+}
+
+// Plain renders Markdown to text without any markup
+func Plain(source string) (string, error) {
+	var out bytes.Buffer
+
+	err := markdown.Convert([]byte(source), &out)
+	if err != nil {
+		return "", err
+	}
+
+	text := html.UnescapeString(strict.Sanitize(out.String()))
+	return strings.TrimSpace(text), nil
+}
+
+// Excerpt cuts text to at most max characters at a space, marking the cut
+// with an ellipsis
+func Excerpt(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+
+	cut := string(runes[:max])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+
+	return strings.TrimRight(cut, " .,;:") + "…"
+}
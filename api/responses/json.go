@@ -14,12 +14,16 @@ func JSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	}
 }
 
+// ERROR writes err as {"error": ...}, along with the ID of the request set
+// by the request ID middleware so clients can quote it
 func ERROR(w http.ResponseWriter, statusCode int, err error) {
 	if err != nil {
 		JSON(w, statusCode, struct {
-			Error string `json:"error"`
+			Error     string `json:"error"`
+			RequestID string `json:"requestId,omitempty"`
 		}{
-			Error: err.Error(),
+			Error:     err.Error(),
+			RequestID: w.Header().Get("X-Request-ID"),
 		})
 
 		return
@@ -27,3 +31,17 @@ func ERROR(w http.ResponseWriter, statusCode int, err error) {
 
 	JSON(w, http.StatusBadRequest, nil)
 }
+
+// Page is the envelope for paginated listings. NextCursor is omitted on the
+// last page.
+type Page struct {
+	Data       interface{} `json:"data"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+func PAGE(w http.ResponseWriter, statusCode int, data interface{}, nextCursor string) {
+	JSON(w, statusCode, Page{
+		Data:       data,
+		NextCursor: nextCursor,
+	})
+}
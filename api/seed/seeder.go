@@ -1,8 +1,8 @@
 package seed
 
 import (
-	"log"
-
+	"github.com/dmdinh22/go-blog/api/auth"
+	"github.com/dmdinh22/go-blog/api/logger"
 	"github.com/dmdinh22/go-blog/api/models"
 	"github.com/jinzhu/gorm"
 )
@@ -12,11 +12,13 @@ var users = []models.User{
 		Username: "Tester McTesterson",
 		Email:    "Tester.McTesterson@mailinator.com",
 		Password: "p@$$w0rd",
+		Role:     auth.RoleAdmin,
 	},
 	models.User{
 		Username: "Martin Luther",
 		Email:    "luther@gmail.com",
 		Password: "p@$$w0rd",
+		Role:     auth.RoleUser,
 	},
 }
 
@@ -31,38 +33,40 @@ var posts = []models.Post{
 	},
 }
 
+// Load migrates the schema and adds sample users and posts to an empty
+// database. Databases that already have users are left alone.
 func Load(db *gorm.DB) {
-	err := db.Debug().DropTableIfExists(&models.Post{}, &models.User{}).Error
+	err := models.Migrate(db)
 
 	if err != nil {
-		log.Fatalf("cannot drop table: %v", err)
+		logger.Default().Fatal("Cannot migrate the database", "error", err)
 	}
 
-	err = db.Debug().AutoMigrate(&models.User{}, &models.Post{}).Error
+	var count int
+	err = db.Model(&models.User{}).Count(&count).Error
 
 	if err != nil {
-		log.Fatalf("cannot migrate table: %v", err)
+		logger.Default().Fatal("Cannot count users", "error", err)
 	}
 
-	err = db.Debug().Model(&models.Post{}).AddForeignKey("author_id", "users(id)", "cascade", "cascade").Error
-
-	if err != nil {
-		log.Fatalf("attaching foreign key error: %v", err)
+	if count > 0 {
+		logger.Default().Info("Database already has users, not seeding", "count", count)
+		return
 	}
 
 	for i, _ := range users {
-		err = db.Debug().Model(&models.User{}).Create(&users[i]).Error
+		err = db.Model(&models.User{}).Create(&users[i]).Error
 
 		if err != nil {
-			log.Fatalf("cannot seed users table: %v", err)
+			logger.Default().Fatal("Cannot seed users table", "error", err)
 		}
 
 		posts[i].AuthorID = users[i].ID
 
-		err = db.Debug().Model(&models.Post{}).Create(&posts[i]).Error
+		err = db.Model(&models.Post{}).Create(&posts[i]).Error
 
 		if err != nil {
-			log.Fatalf("cannot seed posts table: %v", err)
+			logger.Default().Fatal("Cannot seed posts table", "error", err)
 		}
 	}
 }
@@ -2,52 +2,41 @@ package api
 
 import (
 	"fmt"
-	"log"
 	"os"
 
+	"github.com/dmdinh22/go-blog/api/config"
 	"github.com/dmdinh22/go-blog/api/controllers"
-	"github.com/dmdinh22/go-blog/api/seed"
-	"github.com/joho/godotenv"
+	"github.com/dmdinh22/go-blog/api/logger"
+	"github.com/jinzhu/gorm"
 )
 
 var server = controllers.Server{}
 
-func Run() {
-	var err error
-	err = godotenv.Load()
-	var dbDriver, dbUser, dbPassword, dbPort, dbHost, dbName string
-	var currentEnv = os.Getenv("ENVIRONMENT")
+// loadConfig reads the configuration, exiting with every invalid setting
+// when there are any, and sets up the default logger
+func loadConfig(flags *config.Flags) *config.Config {
+	cfg, err := config.Load(flags)
 
 	if err != nil {
-		log.Fatalf("Error getting env vars", err)
-	} else {
-		fmt.Println("Loading env vars...")
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
-	if currentEnv == "dev" || currentEnv == "local" {
-		dbDriver = os.Getenv("DEV_DB_DRIVER")
-		dbUser = os.Getenv("DEV_DB_USER")
-		dbPassword = os.Getenv("DEV_DB_PASSWORD")
-		dbPort = os.Getenv("DEV_DB_PORT")
-		dbHost = os.Getenv("DEV_DB_HOST")
-		dbName = os.Getenv("DEV_DB_NAME")
-	} else if currentEnv == "production" {
-		dbDriver = os.Getenv("DB_DRIVER")
-		dbUser = os.Getenv("DB_USER")
-		dbPassword = os.Getenv("DB_PASSWORD")
-		dbPort = os.Getenv("DB_PORT")
-		dbHost = os.Getenv("DB_HOST")
-		dbName = os.Getenv("DB_NAME")
-	} else {
-		dbDriver = os.Getenv("TEST_DB_DRIVER")
-		dbUser = os.Getenv("TEST_DB_USER")
-		dbPassword = os.Getenv("TEST_DB_PASSWORD")
-		dbPort = os.Getenv("TEST_DB_PORT")
-		dbHost = os.Getenv("TEST_DB_HOST")
-		dbName = os.Getenv("TEST_DB_NAME")
+	// validated by config.Load
+	level, _ := logger.ParseLevel(cfg.Log.Level)
+	logger.SetDefault(logger.New(os.Stderr, cfg.Log.Format, level))
+
+	return cfg
+}
+
+// connect opens the database for the commands that do not start the server
+func connect(cfg *config.Config) *gorm.DB {
+	db, err := controllers.OpenDB(cfg.DB.Driver, cfg.DB.User, cfg.DB.Password, cfg.DB.Port, cfg.DB.Host, cfg.DB.Name)
+	if err != nil {
+		logger.Default().Fatal("Cannot connect to the database", "driver", cfg.DB.Driver, "error", err)
 	}
 
-	server.Initialize(dbDriver, dbUser, dbPassword, dbPort, dbHost, dbName)
-	seed.Load(server.DB)
-	server.Run(":8080")
+	controllers.SetDBLogger(db, cfg)
+
+	return db
 }
@@ -0,0 +1,68 @@
+// Package storage keeps uploaded files in a local directory or an
+// S3-compatible bucket behind one interface.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("Object not found")
+	ErrInvalidKey = errors.New("Invalid object key")
+)
+
+// ObjectStore stores blobs under slash separated keys
+type ObjectStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get returns ErrNotFound when there is no object under the key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// ValidKey rejects keys that are empty, absolute or escape their root
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+
+	return path.Clean(key) == key && !strings.HasPrefix(key, "../") && key != ".."
+}
+
+// Config selects the store and configures it
+type Config struct {
+	// Driver is local or s3
+	Driver string
+	// LocalDir is the directory of the local store
+	LocalDir string
+
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+}
+
+// Open builds the store selected by c.Driver
+func Open(c Config) (ObjectStore, error) {
+	switch c.Driver {
+	case "local":
+		if c.LocalDir == "" {
+			return nil, errors.New("A directory is required for local storage")
+		}
+		return NewLocalStore(c.LocalDir), nil
+
+	case "s3":
+		if c.S3Endpoint == "" || c.S3Bucket == "" {
+			return nil, errors.New("An endpoint and a bucket are required for s3 storage")
+		}
+		return NewS3Store(c.S3Endpoint, c.S3Region, c.S3Bucket, c.S3AccessKeyID, c.S3SecretAccessKey), nil
+
+	default:
+		return nil, fmt.Errorf("Unknown storage driver %q", c.Driver)
+	}
+}
@@ -0,0 +1,284 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around a change
+const DefaultContext = 3
+
+// MaxLines bounds the lines of each text, which bounds the time to compare
+// texts with little in common
+const MaxLines = 2000
+
+var ErrTooLarge = fmt.Errorf("Texts longer than %d lines cannot be compared", MaxLines)
+
+type op struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+// Unified returns the line by line difference between a and b in unified
+// diff format, with context unchanged lines around every change. Equal texts
+// have an empty diff. Texts of more than MaxLines lines fail with
+// ErrTooLarge.
+func Unified(fromName, toName, a, b string, context int) (string, error) {
+	linesA, linesB := splitLines(a), splitLines(b)
+	if len(linesA) > MaxLines || len(linesB) > MaxLines {
+		return "", ErrTooLarge
+	}
+
+	ops := lineOps(linesA, linesB)
+
+	changed := []int{}
+	for i, o := range ops {
+		if o.kind != ' ' {
+			changed = append(changed, i)
+		}
+	}
+
+	if len(changed) == 0 {
+		return "", nil
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+
+	for _, h := range hunks(changed, len(ops), context) {
+		writeHunk(&out, ops, h[0], h[1])
+	}
+
+	return out.String(), nil
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// lineOps turns a into b with the fewest deletions and insertions, using
+// the linear space variant of Myers' algorithm. It takes O((n+m)d) time for
+// d changed lines and O(n+m) memory.
+func lineOps(a, b []string) []op {
+	ops := make([]op, 0, len(a)+len(b))
+	return appendOps(ops, a, b)
+}
+
+// appendOps appends the operations turning a into b to ops
+func appendOps(ops []op, a, b []string) []op {
+	// common lines at both ends are kept as they are
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	for _, line := range a[:prefix] {
+		ops = append(ops, op{' ', line})
+	}
+
+	middleA, middleB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	switch {
+	case len(middleA) == 0:
+		for _, line := range middleB {
+			ops = append(ops, op{'+', line})
+		}
+	case len(middleB) == 0:
+		for _, line := range middleA {
+			ops = append(ops, op{'-', line})
+		}
+	default:
+		x, y, ok := middleSnake(middleA, middleB)
+		if ok {
+			ops = appendOps(ops, middleA[:x], middleB[:y])
+			ops = appendOps(ops, middleA[x:], middleB[y:])
+		} else {
+			// nothing in common
+			for _, line := range middleA {
+				ops = append(ops, op{'-', line})
+			}
+			for _, line := range middleB {
+				ops = append(ops, op{'+', line})
+			}
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, op{' ', line})
+	}
+
+	return ops
+}
+
+// middleSnake runs the shortest edit paths from the start and from the end
+// of a and b until they meet, and returns where, so both halves can be
+// diffed on their own. It reports false when a and b share no line.
+func middleSnake(a, b []string) (int, int, bool) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+
+	// forward[offset+k] is the furthest x reached on diagonal k = x-y from
+	// the start, backward the same counted from the end
+	forward := make([]int, 2*maxD+2)
+	backward := make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	delta := n - m
+	// when delta is odd the forward path is the one to find the overlap
+	front := delta%2 != 0
+
+	// paths running off the edit graph are not extended again
+	forwardStart, forwardEnd, backwardStart, backwardEnd := 0, 0, 0, 0
+
+	for d := 0; d < maxD; d++ {
+		for k := -d + forwardStart; k <= d-forwardEnd; k += 2 {
+			i := offset + k
+
+			var x int
+			if k == -d || (k != d && forward[i-1] < forward[i+1]) {
+				x = forward[i+1]
+			} else {
+				x = forward[i-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[i] = x
+
+			switch {
+			case x > n:
+				forwardEnd += 2
+			case y > m:
+				forwardStart += 2
+			case front:
+				j := offset + delta - k
+				if j >= 0 && j < len(backward) && backward[j] != -1 && x >= n-backward[j] {
+					return x, y, true
+				}
+			}
+		}
+
+		for k := -d + backwardStart; k <= d-backwardEnd; k += 2 {
+			i := offset + k
+
+			var x int
+			if k == -d || (k != d && backward[i-1] < backward[i+1]) {
+				x = backward[i+1]
+			} else {
+				x = backward[i-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			backward[i] = x
+
+			switch {
+			case x > n:
+				backwardEnd += 2
+			case y > m:
+				backwardStart += 2
+			case !front:
+				j := offset + delta - k
+				if j >= 0 && j < len(forward) && forward[j] != -1 {
+					forwardX := forward[j]
+					forwardY := offset + forwardX - j
+					if forwardX >= n-x {
+						return forwardX, forwardY, true
+					}
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+// hunks groups the changed operations into [start, end) ranges of ops,
+// merging changes whose context overlaps.
+func hunks(changed []int, total, context int) [][2]int {
+	result := [][2]int{}
+
+	for _, i := range changed {
+		start, end := i-context, i+context+1
+		if start < 0 {
+			start = 0
+		}
+		if end > total {
+			end = total
+		}
+
+		if n := len(result); n > 0 && start <= result[n-1][1] {
+			result[n-1][1] = end
+			continue
+		}
+
+		result = append(result, [2]int{start, end})
+	}
+
+	return result
+}
+
+func writeHunk(out *strings.Builder, ops []op, start, end int) {
+	// line numbers are 1-based and count the lines of each side before the
+	// hunk
+	aLine, bLine := 0, 0
+	for _, o := range ops[:start] {
+		if o.kind != '+' {
+			aLine++
+		}
+		if o.kind != '-' {
+			bLine++
+		}
+	}
+
+	aCount, bCount := 0, 0
+	for _, o := range ops[start:end] {
+		if o.kind != '+' {
+			aCount++
+		}
+		if o.kind != '-' {
+			bCount++
+		}
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(aLine, aCount), hunkRange(bLine, bCount))
+
+	for _, o := range ops[start:end] {
+		out.WriteByte(o.kind)
+		out.WriteString(o.text)
+		out.WriteByte('\n')
+	}
+}
+
+// hunkRange formats a side of a hunk header the way diff -u does, where an
+// empty side starts at the line before it.
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
@@ -9,8 +9,12 @@
 // @BasePath /
 package main
 
-import "github.com/dmdinh22/go-blog/api"
+import (
+	"os"
+
+	"github.com/dmdinh22/go-blog/api"
+)
 
 func main() {
-	api.Run()
+	api.Execute(os.Args[1:])
 }
@@ -0,0 +1,227 @@
+package configtests
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/config"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+var variables = []string{
+	"CONFIG_FILE", "ENVIRONMENT", "ADDR", "METRICS_ADDR", "API_SECRET", "THEME_DIR",
+	"DB_DRIVER", "DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_PASSWORD_FILE", "DB_NAME",
+	"HTTP_READ_TIMEOUT", "HTTP_WRITE_TIMEOUT", "SHUTDOWN_TIMEOUT",
+	"LOG_LEVEL", "LOG_FORMAT", "LOG_SQL",
+	"STORAGE_DRIVER", "STORAGE_LOCAL_DIR", "S3_ENDPOINT", "S3_REGION", "S3_BUCKET", "S3_ACCESS_KEY_ID", "S3_SECRET_ACCESS_KEY",
+}
+
+// setEnv replaces the settings in the environment with env until the test
+// ends
+func setEnv(t *testing.T, env map[string]string) {
+	saved := map[string]string{}
+	for _, name := range variables {
+		if value, ok := os.LookupEnv(name); ok {
+			saved[name] = value
+		}
+		os.Unsetenv(name)
+	}
+
+	for name, value := range env {
+		os.Setenv(name, value)
+	}
+
+	t.Cleanup(func() {
+		for _, name := range variables {
+			os.Unsetenv(name)
+		}
+		for name, value := range saved {
+			os.Setenv(name, value)
+		}
+	})
+}
+
+func writeFile(t *testing.T, name, data string) string {
+	file := filepath.Join(t.TempDir(), name)
+	err := ioutil.WriteFile(file, []byte(data), 0600)
+	if err != nil {
+		t.Fatalf("cannot write %s: %v", name, err)
+	}
+	return file
+}
+
+var validEnv = map[string]string{
+	"API_SECRET":  "secret",
+	"DB_DRIVER":   "mysql",
+	"DB_HOST":     "localhost",
+	"DB_PORT":     "3306",
+	"DB_USER":     "blog",
+	"DB_PASSWORD": "p@$$w0rd",
+	"DB_NAME":     "blog",
+}
+
+func TestLoadFromEnv(t *testing.T) {
+	setEnv(t, validEnv)
+
+	cfg, err := config.Load(nil)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, cfg.DB, config.DB{Driver: "mysql", Host: "localhost", Port: "3306", User: "blog", Password: "p@$$w0rd", Name: "blog"})
+	assert.Equal(t, cfg.APISecret, "secret")
+
+	// defaults
+	assert.Equal(t, cfg.Addr, ":8080")
+	assert.Equal(t, cfg.Storage.Driver, "local")
+	assert.Equal(t, cfg.Storage.LocalDir, "uploads")
+	assert.Equal(t, cfg.HTTP.ShutdownTimeout, 25*time.Second)
+
+	os.Setenv("HTTP_WRITE_TIMEOUT", "90s")
+	os.Setenv("SHUTDOWN_TIMEOUT", "0")
+
+	cfg, err = config.Load(nil)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, cfg.HTTP.WriteTimeout, 90*time.Second)
+	assert.Equal(t, cfg.HTTP.ShutdownTimeout, time.Duration(0))
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+addr: ":9000"
+api_secret: from-file
+theme_dir: /themes/file
+db:
+  driver: postgres
+  host: file-host
+  port: "5432"
+  user: blog
+  name: blog
+`)
+
+	setEnv(t, map[string]string{"CONFIG_FILE": file, "DB_HOST": "env-host", "THEME_DIR": "/themes/env"})
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	settings := config.AddFlags(flags)
+	err := flags.Parse([]string{"-theme-dir", "/themes/flag"})
+	assert.Equal(t, err, nil)
+
+	cfg, err := config.Load(settings)
+	assert.Equal(t, err, nil)
+
+	// the file overrides the defaults, the environment the file and the
+	// flags the environment
+	assert.Equal(t, cfg.Addr, ":9000")
+	assert.Equal(t, cfg.APISecret, "from-file")
+	assert.Equal(t, cfg.DB.Driver, "postgres")
+	assert.Equal(t, cfg.DB.Host, "env-host")
+	assert.Equal(t, cfg.ThemeDir, "/themes/flag")
+	assert.Equal(t, cfg.Storage.Driver, "local")
+
+	// -config wins over CONFIG_FILE
+	other := writeFile(t, "other.yaml", "api_secret: other\n")
+	flags = flag.NewFlagSet("test", flag.ContinueOnError)
+	settings = config.AddFlags(flags)
+	flags.Parse([]string{"-config", other, "-db-driver", "mysql", "-db-host", "h", "-db-port", "3306", "-db-user", "u", "-db-name", "n"})
+
+	cfg, err = config.Load(settings)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, cfg.APISecret, "other")
+
+	// unknown keys are rejected
+	setEnv(t, map[string]string{"CONFIG_FILE": writeFile(t, "typo.yaml", "api_secrte: x\n")})
+	_, err = config.Load(nil)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestLoadSecretFromFile(t *testing.T) {
+	env := map[string]string{}
+	for name, value := range validEnv {
+		env[name] = value
+	}
+	delete(env, "DB_PASSWORD")
+	env["DB_PASSWORD_FILE"] = writeFile(t, "password", "from-secret\n")
+	setEnv(t, env)
+
+	cfg, err := config.Load(nil)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, cfg.DB.Password, "from-secret")
+
+	// both is ambiguous
+	os.Setenv("DB_PASSWORD", "from-env")
+	_, err = config.Load(nil)
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, strings.Contains(err.Error(), "DB_PASSWORD is set along with DB_PASSWORD_FILE"), true)
+
+	os.Unsetenv("DB_PASSWORD")
+	os.Setenv("DB_PASSWORD_FILE", "/does/not/exist")
+	_, err = config.Load(nil)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	setEnv(t, map[string]string{"DB_DRIVER": "mysql", "DB_PORT": "port", "ADDR": "8080", "METRICS_ADDR": "9090", "STORAGE_DRIVER": "s3", "HTTP_READ_TIMEOUT": "10", "SHUTDOWN_TIMEOUT": "-1s"})
+
+	_, err := config.Load(nil)
+
+	problems, ok := err.(config.ValidationError)
+	assert.Equal(t, ok, true)
+
+	for _, key := range []string{"API_SECRET", "ADDR", "METRICS_ADDR", "DB_HOST", "DB_USER", "DB_NAME", "DB_PORT", "S3_ENDPOINT", "S3_BUCKET", "HTTP_READ_TIMEOUT", "SHUTDOWN_TIMEOUT"} {
+		_, found := problems[key]
+		assert.Equal(t, found, true)
+	}
+	assert.Equal(t, len(problems), 11)
+}
+
+func TestLoadSQLite(t *testing.T) {
+	setEnv(t, map[string]string{"API_SECRET": "secret", "DB_DRIVER": "sqlite3", "DB_NAME": ":memory:"})
+
+	// sqlite needs neither a host nor a port
+	cfg, err := config.Load(nil)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, cfg.DB, config.DB{Driver: "sqlite3", Name: ":memory:"})
+
+	os.Unsetenv("DB_NAME")
+
+	_, err = config.Load(nil)
+	problems, ok := err.(config.ValidationError)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, len(problems), 1)
+	assert.Equal(t, problems["DB_NAME"], "is required, a file name or :memory:")
+
+	os.Setenv("DB_DRIVER", "oracle")
+
+	_, err = config.Load(nil)
+	problems, ok = err.(config.ValidationError)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, problems["DB_DRIVER"], `"oracle" is not mysql, postgres or sqlite3`)
+}
+
+func TestLoadLogSettings(t *testing.T) {
+	setEnv(t, validEnv)
+
+	cfg, err := config.Load(nil)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, cfg.Log, config.Log{Level: "info", Format: "text"})
+
+	os.Setenv("LOG_LEVEL", "debug")
+	os.Setenv("LOG_FORMAT", "json")
+	os.Setenv("LOG_SQL", "true")
+
+	cfg, err = config.Load(nil)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, cfg.Log, config.Log{Level: "debug", Format: "json", SQL: true})
+
+	os.Setenv("LOG_LEVEL", "verbose")
+	os.Setenv("LOG_FORMAT", "xml")
+	os.Setenv("LOG_SQL", "sometimes")
+
+	_, err = config.Load(nil)
+	problems, ok := err.(config.ValidationError)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, len(problems), 3)
+	assert.Equal(t, problems["LOG_SQL"], `"sometimes" is not true or false`)
+}
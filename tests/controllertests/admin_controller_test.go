@@ -0,0 +1,170 @@
+package controllertests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/auth"
+	"github.com/dmdinh22/go-blog/api/middlewares"
+	"github.com/gorilla/mux"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestUpdateUserRole(t *testing.T) {
+	users, err := seedUsersWithRoles()
+	if err != nil {
+		log.Fatal(err)
+	}
+	admin, editor, reader := users[0], users[1], users[2]
+
+	tokens := map[uint32]string{}
+	for _, user := range users {
+		token, err := server.SignIn(user.Email, "p@$$w0rd")
+		if err != nil {
+			log.Fatalf("cannot login: %v\n", err)
+		}
+		tokens[user.ID] = fmt.Sprintf("Bearer %v", token)
+	}
+
+	samples := []struct {
+		id           string
+		updateJSON   string
+		tokenGiven   string
+		statusCode   int
+		role         string
+		errorMessage string
+	}{
+		{
+			id:         strconv.Itoa(int(reader.ID)),
+			updateJSON: `{"role": "editor"}`,
+			tokenGiven: tokens[admin.ID],
+			statusCode: 200,
+			role:       auth.RoleEditor,
+		},
+		{
+			// editors cannot manage users
+			id:           strconv.Itoa(int(reader.ID)),
+			updateJSON:   `{"role": "admin"}`,
+			tokenGiven:   tokens[editor.ID],
+			statusCode:   403,
+			errorMessage: "Forbidden",
+		},
+		{
+			// nor can users promote themselves
+			id:           strconv.Itoa(int(reader.ID)),
+			updateJSON:   `{"role": "admin"}`,
+			tokenGiven:   tokens[reader.ID],
+			statusCode:   403,
+			errorMessage: "Forbidden",
+		},
+		{
+			id:           strconv.Itoa(int(reader.ID)),
+			updateJSON:   `{"role": "admin"}`,
+			tokenGiven:   "",
+			statusCode:   401,
+			errorMessage: "Unauthorized",
+		},
+		{
+			id:           strconv.Itoa(int(reader.ID)),
+			updateJSON:   `{"role": "overlord"}`,
+			tokenGiven:   tokens[admin.ID],
+			statusCode:   422,
+			errorMessage: "Invalid Role.",
+		},
+		{
+			id:           "100",
+			updateJSON:   `{"role": "editor"}`,
+			tokenGiven:   tokens[admin.ID],
+			statusCode:   404,
+			errorMessage: "User not found",
+		},
+	}
+
+	for _, v := range samples {
+		req, err := http.NewRequest("PUT", "/api/admin/users", bytes.NewBufferString(v.updateJSON))
+		if err != nil {
+			t.Errorf("This is the error: %v\n", err)
+		}
+
+		req = mux.SetURLVars(req, map[string]string{"id": v.id})
+		rr := httptest.NewRecorder()
+		handler := middlewares.SetMiddlewareAuthentication(middlewares.SetMiddlewarePermission(auth.PermUsersManage, server.UpdateUserRole))
+
+		req.Header.Set("Authorization", v.tokenGiven)
+		handler.ServeHTTP(rr, req)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 {
+			assert.Equal(t, responseMap["role"], v.role)
+		}
+
+		if v.errorMessage != "" {
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+	}
+}
+
+func TestAdminDeleteUser(t *testing.T) {
+	users, err := seedUsersWithRoles()
+	if err != nil {
+		log.Fatal(err)
+	}
+	admin, editor, reader := users[0], users[1], users[2]
+
+	adminToken, err := server.SignIn(admin.Email, "p@$$w0rd")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+
+	editorToken, err := server.SignIn(editor.Email, "p@$$w0rd")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+
+	samples := []struct {
+		id         string
+		tokenGiven string
+		statusCode int
+	}{
+		{
+			id:         strconv.Itoa(int(reader.ID)),
+			tokenGiven: fmt.Sprintf("Bearer %v", editorToken),
+			statusCode: 403,
+		},
+		{
+			id:         strconv.Itoa(int(reader.ID)),
+			tokenGiven: fmt.Sprintf("Bearer %v", adminToken),
+			statusCode: 204,
+		},
+		{
+			id:         strconv.Itoa(int(reader.ID)),
+			tokenGiven: fmt.Sprintf("Bearer %v", adminToken),
+			statusCode: 404,
+		},
+	}
+
+	for _, v := range samples {
+		req, _ := http.NewRequest("DELETE", "/api/admin/users", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": v.id})
+
+		rr := httptest.NewRecorder()
+		handler := middlewares.SetMiddlewareAuthentication(middlewares.SetMiddlewarePermission(auth.PermUsersManage, server.AdminDeleteUser))
+
+		req.Header.Set("Authorization", v.tokenGiven)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+	}
+}
@@ -0,0 +1,212 @@
+package controllertests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/middlewares"
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/gorilla/mux"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestCreateComment(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	token, err := server.SignIn("magu@gmail.com", "p@$$w0rd")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", token)
+	postID := strconv.Itoa(int(posts[0].ID))
+
+	samples := []struct {
+		id           string
+		inputJSON    string
+		tokenGiven   string
+		statusCode   int
+		errorMessage string
+	}{
+		{
+			id:         postID,
+			inputJSON:  `{"content": "Nice post"}`,
+			tokenGiven: tokenString,
+			statusCode: 201,
+		},
+		{
+			id:         postID,
+			inputJSON:  `{"content": "Agreed", "parentId": 1}`,
+			tokenGiven: tokenString,
+			statusCode: 201,
+		},
+		{
+			id:           postID,
+			inputJSON:    `{"content": "Replying to nothing", "parentId": 100}`,
+			tokenGiven:   tokenString,
+			statusCode:   422,
+			errorMessage: "Parent comment not found",
+		},
+		{
+			id:           postID,
+			inputJSON:    `{"content": ""}`,
+			tokenGiven:   tokenString,
+			statusCode:   422,
+			errorMessage: "Required Content",
+		},
+		{
+			id:           postID,
+			inputJSON:    `{"content": "Anonymous"}`,
+			tokenGiven:   "",
+			statusCode:   401,
+			errorMessage: "Unauthorized",
+		},
+		{
+			id:           "100",
+			inputJSON:    `{"content": "Nobody home"}`,
+			tokenGiven:   tokenString,
+			statusCode:   404,
+			errorMessage: "Post not found",
+		},
+	}
+
+	for _, v := range samples {
+		req, err := http.NewRequest("POST", "/comments", bytes.NewBufferString(v.inputJSON))
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": v.id})
+
+		rr := httptest.NewRecorder()
+		handler := middlewares.SetMiddlewareAuthentication(server.CreateComment)
+
+		req.Header.Set("Authorization", v.tokenGiven)
+		handler.ServeHTTP(rr, req)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 201 {
+			assert.Equal(t, responseMap["postId"], float64(posts[0].ID))
+			assert.Equal(t, responseMap["authorId"], float64(2))
+		}
+
+		if v.errorMessage != "" {
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+	}
+
+	// the comment count shows up on the post
+	req, _ := http.NewRequest("GET", "/posts", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": postID})
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetPost).ServeHTTP(rr, req)
+
+	responseMap := make(map[string]interface{})
+	err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, responseMap["commentCount"], float64(2))
+
+	// and the reply is nested below its parent
+	req, _ = http.NewRequest("GET", "/comments", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": postID})
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.GetComments).ServeHTTP(rr, req)
+
+	var comments []models.Comment
+	err = json.Unmarshal([]byte(rr.Body.String()), &comments)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, len(comments), 1)
+	assert.Equal(t, comments[0].Replies[0].Content, "Agreed")
+}
+
+func TestUpdateAndDeleteComment(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// user 1 comments on their own post
+	comment := models.Comment{PostID: posts[0].ID, AuthorID: posts[0].AuthorID, Content: "Original"}
+	err = server.DB.Model(&models.Comment{}).Create(&comment).Error
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	authorToken, err := server.SignIn("steven@gmail.com", "p@$$w0rd")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+
+	otherToken, err := server.SignIn("magu@gmail.com", "p@$$w0rd")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+
+	vars := map[string]string{"id": strconv.Itoa(int(posts[0].ID)), "commentId": strconv.Itoa(int(comment.ID))}
+
+	samples := []struct {
+		method     string
+		updateJSON string
+		tokenGiven string
+		statusCode int
+	}{
+		{"PUT", `{"content": "Hijacked"}`, fmt.Sprintf("Bearer %v", otherToken), 401},
+		{"PUT", `{"content": ""}`, fmt.Sprintf("Bearer %v", authorToken), 422},
+		{"PUT", `{"content": "Edited"}`, fmt.Sprintf("Bearer %v", authorToken), 200},
+		{"DELETE", ``, fmt.Sprintf("Bearer %v", otherToken), 401},
+		{"DELETE", ``, fmt.Sprintf("Bearer %v", authorToken), 204},
+		{"DELETE", ``, fmt.Sprintf("Bearer %v", authorToken), 404},
+	}
+
+	for _, v := range samples {
+		req, _ := http.NewRequest(v.method, "/comments", bytes.NewBufferString(v.updateJSON))
+		req = mux.SetURLVars(req, vars)
+
+		handler := middlewares.SetMiddlewareAuthentication(server.UpdateComment)
+		if v.method == "DELETE" {
+			handler = middlewares.SetMiddlewareAuthentication(server.DeleteComment)
+		}
+
+		rr := httptest.NewRecorder()
+		req.Header.Set("Authorization", v.tokenGiven)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 {
+			responseMap := make(map[string]interface{})
+			err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+			if err != nil {
+				t.Errorf("Cannot convert to json: %v", err)
+			}
+			assert.Equal(t, responseMap["content"], "Edited")
+		}
+	}
+}
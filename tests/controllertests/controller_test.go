@@ -1,12 +1,15 @@
 package controllertests
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"testing"
 
+	"github.com/dmdinh22/go-blog/api/auth"
 	"github.com/dmdinh22/go-blog/api/controllers"
+	"github.com/dmdinh22/go-blog/api/migrations"
 	"github.com/dmdinh22/go-blog/api/models"
 	"github.com/jinzhu/gorm"
 	"github.com/joho/godotenv"
@@ -20,7 +23,8 @@ func TestMain(m *testing.M) {
 	var err error
 	err = godotenv.Load(os.ExpandEnv("../../.env"))
 
-	if err != nil {
+	// the .env is optional, without one the tests run on sqlite
+	if err != nil && !os.IsNotExist(err) {
 		log.Fatalf("Error getting env %v\n", err)
 	}
 	Database()
@@ -33,6 +37,23 @@ func Database() {
 
 	TestDbDriver := os.Getenv("TEST_DB_DRIVER")
 
+	if TestDbDriver == "" || TestDbDriver == "sqlite3" {
+		// in memory unless TEST_DB_NAME names a file
+		TestDbName := os.Getenv("TEST_DB_NAME")
+		if TestDbName == "" {
+			TestDbName = ":memory:"
+		}
+
+		server.DB, err = controllers.OpenDB("sqlite3", "", "", "", "", TestDbName)
+
+		if err != nil {
+			fmt.Println("Cannot connect to sqlite3 database")
+			log.Fatal("This is the error:", err)
+		} else {
+			fmt.Println("We are connected to the sqlite3 database")
+		}
+	}
+
 	if TestDbDriver == "mysql" {
 		DBURL := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True&loc=Local", os.Getenv("TEST_DB_USER"), os.Getenv("TEST_DB_PASSWORD"), os.Getenv("TEST_DB_HOST"), os.Getenv("TEST_DB_PORT"), os.Getenv("TEST_DB_NAME"))
 		server.DB, err = gorm.Open(TestDbDriver, DBURL)
@@ -58,22 +79,34 @@ func Database() {
 	}
 }
 
-func refreshUserTable() error {
-	err := server.DB.DropTableIfExists(&models.User{}).Error
+// refreshTables drops every table, then builds the schema from the
+// migrations the way a new database gets it. The tables reference each
+// other, so they are always refreshed together.
+func refreshTables() error {
+	err := server.DB.DropTableIfExists("post_tags", "post_media", &models.RevokedToken{}, &models.RefreshToken{}, &models.Comment{}, &models.PostSlug{}, &models.PostRevision{}, &models.Media{}, &models.Tag{}, &models.Post{}, &models.User{}, migrations.Table).Error
 	if err != nil {
 		return err
 	}
 
-	err = server.DB.AutoMigrate(&models.User{}).Error
+	migrator, err := migrations.New(server.DB.DB(), server.DB.Dialect().GetName())
 	if err != nil {
 		return err
 	}
 
-	log.Printf("Successfully refreshed table")
+	_, err = migrator.Up(context.Background())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Successfully refreshed tables")
 
 	return nil
 }
 
+func refreshUserTable() error {
+	return refreshTables()
+}
+
 func seedOneUser() (models.User, error) {
 	err := refreshUserTable()
 	if err != nil {
@@ -123,19 +156,49 @@ func seedUsers() ([]models.User, error) {
 	return users, nil
 }
 
-func refreshUserAndPostTable() error {
-	err := server.DB.DropTableIfExists(&models.User{}, &models.Post{}).Error
+func seedUsersWithRoles() ([]models.User, error) {
+	err := refreshUserTable()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = server.DB.AutoMigrate(&models.User{}, &models.Post{}).Error
-	if err != nil {
-		return err
+	users := []models.User{
+		models.User{
+			Username: "Ada Admin",
+			Email:    "ada@gmail.com",
+			Password: "p@$$w0rd",
+			Role:     auth.RoleAdmin,
+		},
+		models.User{
+			Username: "Ed Editor",
+			Email:    "ed@gmail.com",
+			Password: "p@$$w0rd",
+			Role:     auth.RoleEditor,
+		},
+		models.User{
+			Username: "Regular Reader",
+			Email:    "reader@gmail.com",
+			Password: "p@$$w0rd",
+			Role:     auth.RoleUser,
+		},
 	}
 
-	log.Printf("Successfully refreshed tables")
-	return nil
+	for i, _ := range users {
+		err := server.DB.Model(&models.User{}).Create(&users[i]).Error
+		if err != nil {
+			return []models.User{}, err
+		}
+	}
+
+	return users, nil
+}
+
+func refreshUserAndPostTable() error {
+	return refreshTables()
+}
+
+func refreshUserAndTokenTables() error {
+	return refreshTables()
 }
 
 func seedOneUserAndOnePost() (models.Post, error) {
@@ -0,0 +1,220 @@
+package controllertests
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/gorilla/mux"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func feedRequest(path string, vars map[string]string, header map[string]string) *http.Request {
+	req, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		log.Fatalf("cannot create request: %v", err)
+	}
+	req.Host = "blog.example.com"
+	req = mux.SetURLVars(req, vars)
+
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+
+	return req
+}
+
+func TestGetFeed(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	users, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	draft := models.Post{Title: "Unfinished", Content: "Draft", AuthorID: users[0].ID, Status: models.PostStatusDraft}
+	_, err = draft.CreatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot create post: %v\n", err)
+	}
+
+	samples := []struct {
+		format      string
+		contentType string
+		contains    []string
+	}{
+		{
+			format:      "rss",
+			contentType: "application/rss+xml; charset=utf-8",
+			contains:    []string{`<rss version="2.0"`, "<title>Title 1</title>", "<title>Title 2</title>", "http://blog.example.com/feed.rss"},
+		},
+		{
+			format:      "atom",
+			contentType: "application/atom+xml; charset=utf-8",
+			contains:    []string{`<feed xmlns="http://www.w3.org/2005/Atom">`, "<title>Title 1</title>", "<id>http://blog.example.com/feed.atom</id>"},
+		},
+		{
+			format:      "json",
+			contentType: "application/feed+json; charset=utf-8",
+			contains:    []string{`"version": "https://jsonfeed.org/version/1.1"`, `"title": "Title 2"`},
+		},
+	}
+
+	for _, v := range samples {
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.GetFeed).ServeHTTP(rr, feedRequest("/feed."+v.format, map[string]string{"format": v.format}, nil))
+
+		assert.Equal(t, rr.Code, 200)
+		assert.Equal(t, rr.Header().Get("Content-Type"), v.contentType)
+
+		body := rr.Body.String()
+		for _, text := range v.contains {
+			assert.Equal(t, strings.Contains(body, text), true)
+		}
+
+		// drafts are not syndicated
+		assert.Equal(t, strings.Contains(body, "Unfinished"), false)
+	}
+
+	// the user feed only has the posts of that author
+	rr := httptest.NewRecorder()
+	vars := map[string]string{"id": strconv.Itoa(int(users[1].ID)), "format": "json"}
+	http.HandlerFunc(server.GetUserFeed).ServeHTTP(rr, feedRequest("/api/users/2/feed.json", vars, nil))
+	assert.Equal(t, rr.Code, 200)
+
+	feed := map[string]interface{}{}
+	err = json.Unmarshal(rr.Body.Bytes(), &feed)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+
+	items := feed["items"].([]interface{})
+	assert.Equal(t, len(items), 1)
+	assert.Equal(t, items[0].(map[string]interface{})["title"], posts[1].Title)
+	assert.Equal(t, items[0].(map[string]interface{})["url"], "http://blog.example.com/posts/"+posts[1].Slug)
+
+	rr = httptest.NewRecorder()
+	vars = map[string]string{"id": "99", "format": "json"}
+	http.HandlerFunc(server.GetUserFeed).ServeHTTP(rr, feedRequest("/api/users/99/feed.json", vars, nil))
+	assert.Equal(t, rr.Code, 404)
+
+	// posts without a publication time, like the seeded ones, are dated by
+	// their creation
+	published := time.Date(2020, 3, 1, 9, 30, 0, 0, time.UTC)
+	old := models.Post{Title: "Old news", Content: "Old", AuthorID: users[0].ID, Status: models.PostStatusPublished, PublishAt: &published}
+	_, err = old.CreatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot create post: %v\n", err)
+	}
+
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.GetFeed).ServeHTTP(rr, feedRequest("/feed.json", map[string]string{"format": "json"}, nil))
+	assert.Equal(t, rr.Code, 200)
+
+	feed = map[string]interface{}{}
+	err = json.Unmarshal(rr.Body.Bytes(), &feed)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+
+	titles := []interface{}{}
+	for _, item := range feed["items"].([]interface{}) {
+		titles = append(titles, item.(map[string]interface{})["title"])
+	}
+	assert.Equal(t, titles, []interface{}{"Title 2", "Title 1", "Old news"})
+}
+
+func TestEmptyFeedConditionalRequests(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	vars := map[string]string{"id": strconv.Itoa(int(user.ID)), "format": "atom"}
+
+	// a feed without posts has no modification time
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetUserFeed).ServeHTTP(rr, feedRequest("/api/users/1/feed.atom", vars, nil))
+	assert.Equal(t, rr.Code, 200)
+	assert.Equal(t, rr.Header().Get("Last-Modified"), "")
+	assert.Equal(t, strings.Contains(rr.Body.String(), "<updated>1970-"), false)
+
+	since := map[string]string{"If-Modified-Since": time.Now().UTC().Format(http.TimeFormat)}
+
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.GetUserFeed).ServeHTTP(rr, feedRequest("/api/users/1/feed.atom", vars, since))
+	assert.Equal(t, rr.Code, 200)
+}
+
+func TestFeedConditionalRequests(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	vars := map[string]string{"format": "atom"}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetFeed).ServeHTTP(rr, feedRequest("/feed.atom", vars, nil))
+	assert.Equal(t, rr.Code, 200)
+
+	etag := rr.Header().Get("ETag")
+	lastModified := rr.Header().Get("Last-Modified")
+	assert.NotEqual(t, etag, "")
+	assert.NotEqual(t, lastModified, "")
+
+	samples := []struct {
+		header     map[string]string
+		statusCode int
+	}{
+		{header: map[string]string{"If-None-Match": etag}, statusCode: 304},
+		{header: map[string]string{"If-None-Match": `"other", ` + etag}, statusCode: 304},
+		{header: map[string]string{"If-None-Match": `"other"`}, statusCode: 200},
+		{header: map[string]string{"If-Modified-Since": lastModified}, statusCode: 304},
+		{header: map[string]string{"If-Modified-Since": time.Unix(0, 0).UTC().Format(http.TimeFormat)}, statusCode: 200},
+		// If-None-Match decides when both are sent
+		{header: map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": lastModified}, statusCode: 200},
+	}
+
+	for _, v := range samples {
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.GetFeed).ServeHTTP(rr, feedRequest("/feed.atom", vars, v.header))
+		assert.Equal(t, rr.Code, v.statusCode)
+
+		if v.statusCode == 304 {
+			assert.Equal(t, rr.Body.Len(), 0)
+		}
+	}
+
+	// editing a post changes the feed
+	post := posts[0]
+	post.Content = "Edited content"
+	_, err = post.UpdatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot update post: %v\n", err)
+	}
+
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.GetFeed).ServeHTTP(rr, feedRequest("/feed.atom", vars, map[string]string{"If-None-Match": etag}))
+	assert.Equal(t, rr.Code, 200)
+	assert.NotEqual(t, rr.Header().Get("ETag"), etag)
+}
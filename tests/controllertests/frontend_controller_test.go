@@ -0,0 +1,249 @@
+package controllertests
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/controllers"
+	"github.com/dmdinh22/go-blog/api/frontend"
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/gorilla/mux"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func loadTheme(dir string) {
+	theme, err := frontend.Load(dir)
+	if err != nil {
+		log.Fatalf("cannot load theme: %v", err)
+	}
+	server.Theme = theme
+}
+
+func getPage(handler http.HandlerFunc, path string, vars map[string]string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		log.Fatalf("cannot create request: %v", err)
+	}
+	req = mux.SetURLVars(req, vars)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestIndexPage(t *testing.T) {
+	loadTheme("")
+
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// enough posts for a second page
+	for i := 0; i < 10; i++ {
+		post := models.Post{Title: fmt.Sprintf("Page post %d", i), Content: "Some *words*", AuthorID: users[0].ID}
+		_, err = post.CreatePost(server.DB)
+		if err != nil {
+			log.Fatalf("cannot create post: %v\n", err)
+		}
+	}
+
+	draft := models.Post{Title: "Unfinished", Content: "Draft", AuthorID: users[0].ID, Status: models.PostStatusDraft}
+	_, err = draft.CreatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot create post: %v\n", err)
+	}
+
+	rr := getPage(server.Index, "/", nil)
+	assert.Equal(t, rr.Code, 200)
+	assert.Equal(t, rr.Header().Get("Content-Type"), "text/html; charset=utf-8")
+
+	body := rr.Body.String()
+	assert.Equal(t, strings.Contains(body, `<a href="/posts/page-post-9">Page post 9</a>`), true)
+	assert.Equal(t, strings.Contains(body, "Some words"), true)
+	assert.Equal(t, strings.Contains(body, "Unfinished"), false)
+	assert.Equal(t, strings.Contains(body, "Title 1"), false)
+
+	next := regexp.MustCompile(`<a href="(/\?cursor=[^"]+)">Older posts</a>`).FindStringSubmatch(body)
+	assert.Equal(t, len(next), 2)
+
+	rr = getPage(server.Index, strings.Replace(next[1], "&amp;", "&", -1), nil)
+	assert.Equal(t, rr.Code, 200)
+
+	body = rr.Body.String()
+	assert.Equal(t, strings.Contains(body, "Title 1"), true)
+	assert.Equal(t, strings.Contains(body, "Title 2"), true)
+	assert.Equal(t, strings.Contains(body, "Older posts"), false)
+
+	rr = getPage(server.Index, "/?cursor=garbage", nil)
+	assert.Equal(t, rr.Code, 400)
+
+	rr = getPage(server.Index, "/?tag=", nil)
+	assert.Equal(t, rr.Code, 400)
+
+	// a database failing is not the fault of the page asked for
+	db, err := controllers.OpenDB("sqlite3", "", "", "", "", ":memory:")
+	if err != nil {
+		log.Fatalf("cannot open database: %v", err)
+	}
+	db.Close()
+
+	brokenServer := controllers.Server{DB: db, Theme: server.Theme}
+	rr = getPage(brokenServer.Index, "/", nil)
+	assert.Equal(t, rr.Code, 500)
+	assert.Equal(t, strings.Contains(rr.Body.String(), "Something went wrong."), true)
+}
+
+func TestPostPage(t *testing.T) {
+	loadTheme("")
+
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	post := models.Post{
+		Title:    "Fish &amp; Chips",
+		Content:  "A **recipe** <script>alert(1)</script>",
+		AuthorID: users[1].ID,
+		Tags:     []models.Tag{{Name: "food"}},
+	}
+	_, err = post.CreatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot create post: %v\n", err)
+	}
+
+	oldSlug := post.Slug
+	post.Title = "Fish and Chips"
+	_, err = post.UpdatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot update post: %v\n", err)
+	}
+
+	draft := models.Post{Title: "Unfinished", Content: "Draft", AuthorID: users[0].ID, Status: models.PostStatusDraft}
+	_, err = draft.CreatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot create post: %v\n", err)
+	}
+
+	rr := getPage(server.PostPage, "/posts/fish-and-chips", map[string]string{"slug": "fish-and-chips"})
+	assert.Equal(t, rr.Code, 200)
+
+	body := rr.Body.String()
+	assert.Equal(t, strings.Contains(body, "<title>Fish and Chips - Go Blog</title>"), true)
+	assert.Equal(t, strings.Contains(body, "<strong>recipe</strong>"), true)
+	assert.Equal(t, strings.Contains(body, "<script>alert"), false)
+	assert.Equal(t, strings.Contains(body, fmt.Sprintf(`<a href="/authors/%d">`, users[1].ID)), true)
+	assert.Equal(t, strings.Contains(body, "#food"), true)
+
+	rr = getPage(server.PostPage, "/posts/"+oldSlug, map[string]string{"slug": oldSlug})
+	assert.Equal(t, rr.Code, 301)
+	assert.Equal(t, rr.Header().Get("Location"), "/posts/fish-and-chips")
+
+	rr = getPage(server.PostPage, "/posts/unfinished", map[string]string{"slug": "unfinished"})
+	assert.Equal(t, rr.Code, 404)
+	assert.Equal(t, strings.Contains(rr.Body.String(), "This post does not exist."), true)
+}
+
+func TestAuthorPage(t *testing.T) {
+	loadTheme("")
+
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	users, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	id := strconv.Itoa(int(users[1].ID))
+	rr := getPage(server.AuthorPage, "/authors/"+id, map[string]string{"id": id})
+	assert.Equal(t, rr.Code, 200)
+
+	body := rr.Body.String()
+	assert.Equal(t, strings.Contains(body, "Posts by "+users[1].Username), true)
+	assert.Equal(t, strings.Contains(body, posts[1].Title), true)
+	assert.Equal(t, strings.Contains(body, posts[0].Title), false)
+
+	rr = getPage(server.AuthorPage, "/authors/99", map[string]string{"id": "99"})
+	assert.Equal(t, rr.Code, 404)
+
+	// usernames are stored escaped, they are escaped once on the page
+	user := models.User{Username: "Tom & Jerry", Email: "tom@gmail.com", Password: "p@$$w0rd"}
+	user.Prepare()
+	_, err = user.CreateUser(server.DB)
+	if err != nil {
+		log.Fatalf("cannot create user: %v\n", err)
+	}
+
+	id = strconv.Itoa(int(user.ID))
+	rr = getPage(server.AuthorPage, "/authors/"+id, map[string]string{"id": id})
+	assert.Equal(t, rr.Code, 200)
+
+	body = rr.Body.String()
+	assert.Equal(t, strings.Contains(body, "<title>Tom &amp; Jerry - "), true)
+	assert.Equal(t, strings.Contains(body, "Posts by Tom &amp; Jerry"), true)
+	assert.Equal(t, strings.Contains(body, "&amp;amp;"), false)
+}
+
+func TestThemeOverride(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-blog-theme")
+	if err != nil {
+		log.Fatalf("cannot create theme dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	override := `{{define "content"}}<p class="custom">{{len .Posts}} posts</p>{{end}}`
+	err = ioutil.WriteFile(filepath.Join(dir, frontend.PageIndex), []byte(override), 0644)
+	if err != nil {
+		log.Fatalf("cannot write template: %v", err)
+	}
+
+	loadTheme(dir)
+	defer loadTheme("")
+
+	err = refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, _, err = seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the overridden page is used inside the embedded layout
+	rr := getPage(server.Index, "/", nil)
+	assert.Equal(t, rr.Code, 200)
+	assert.Equal(t, strings.Contains(rr.Body.String(), `<p class="custom">2 posts</p>`), true)
+	assert.Equal(t, strings.Contains(rr.Body.String(), "<title>Go Blog</title>"), true)
+
+	err = ioutil.WriteFile(filepath.Join(dir, frontend.PagePost), []byte(`{{define "content"}}{{.Missing}`), 0644)
+	if err != nil {
+		log.Fatalf("cannot write template: %v", err)
+	}
+
+	_, err = frontend.Load(dir)
+	assert.NotEqual(t, err, nil)
+}
@@ -0,0 +1,129 @@
+package controllertests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/controllers"
+	"github.com/dmdinh22/go-blog/api/migrations"
+	"github.com/dmdinh22/go-blog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func getReport(handler http.HandlerFunc) (int, controllers.HealthReport) {
+	req, err := http.NewRequest("GET", "/readyz", nil)
+	if err != nil {
+		log.Fatalf("this is the error: %v\n", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	report := controllers.HealthReport{}
+	err = json.Unmarshal(rr.Body.Bytes(), &report)
+	if err != nil {
+		log.Fatalf("Cannot convert to json: %v", err)
+	}
+
+	return rr.Code, report
+}
+
+func TestGetHealth(t *testing.T) {
+	healthServer := &controllers.Server{}
+
+	// liveness does not run the checks
+	healthServer.RegisterHealthChecker(controllers.NewHealthChecker("failing", func(ctx context.Context) error {
+		return errors.New("down")
+	}))
+
+	code, report := getReport(healthServer.GetHealth)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, report.Status, controllers.HealthStatusOK)
+	assert.Equal(t, len(report.Checks), 0)
+}
+
+func TestGetReadiness(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	healthServer := &controllers.Server{HealthCheckTimeout: 100 * time.Millisecond}
+	healthServer.RegisterHealthChecker(controllers.NewDatabaseHealthChecker(server.DB))
+
+	code, report := getReport(healthServer.GetReadiness)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, report.Status, controllers.HealthStatusOK)
+	assert.Equal(t, report.Checks["database"].Status, controllers.HealthStatusOK)
+
+	// a scheduler is only ready while it runs
+	scheduler := controllers.NewPublishScheduler(server.DB, time.Hour)
+	healthServer.RegisterHealthChecker(scheduler)
+
+	code, report = getReport(healthServer.GetReadiness)
+	assert.Equal(t, code, http.StatusServiceUnavailable)
+	assert.Equal(t, report.Status, controllers.HealthStatusUnavailable)
+	assert.Equal(t, report.Checks["database"].Status, controllers.HealthStatusOK)
+	assert.Equal(t, report.Checks["scheduler"].Status, controllers.HealthStatusError)
+	assert.Equal(t, report.Checks["scheduler"].Error, "The scheduler has not been started")
+
+	scheduler.Start()
+
+	code, report = getReport(healthServer.GetReadiness)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, report.Checks["scheduler"].Status, controllers.HealthStatusOK)
+
+	scheduler.Stop()
+
+	code, report = getReport(healthServer.GetReadiness)
+	assert.Equal(t, code, http.StatusServiceUnavailable)
+	assert.Equal(t, report.Checks["scheduler"].Error, "The scheduler has stopped")
+
+	// checks ignoring their context still time out
+	healthServer = &controllers.Server{HealthCheckTimeout: 50 * time.Millisecond}
+	healthServer.RegisterHealthChecker(controllers.NewHealthChecker("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}))
+
+	start := time.Now()
+	code, report = getReport(healthServer.GetReadiness)
+	assert.Equal(t, time.Since(start) < time.Second, true)
+	assert.Equal(t, code, http.StatusServiceUnavailable)
+	assert.Equal(t, report.Checks["slow"].Error, "Timed out after 50ms")
+}
+
+func TestMigrationsReadiness(t *testing.T) {
+	db, err := controllers.OpenDB("sqlite3", "", "", "", "", ":memory:")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	migrator, err := migrations.New(db.DB(), "sqlite3")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	healthServer := &controllers.Server{}
+	healthServer.RegisterHealthChecker(migrator)
+
+	code, report := getReport(healthServer.GetReadiness)
+	assert.Equal(t, code, http.StatusServiceUnavailable)
+	assert.Equal(t, report.Checks["migrations"].Status, controllers.HealthStatusError)
+
+	err = models.Migrate(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	code, report = getReport(healthServer.GetReadiness)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, report.Checks["migrations"].Status, controllers.HealthStatusOK)
+}
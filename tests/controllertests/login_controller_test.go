@@ -24,6 +24,12 @@ func TestSignIn(t *testing.T) {
 		fmt.Printf("This is the error %v\n", err)
 	}
 
+	// a stored password bcrypt cannot read matches nothing
+	err = server.DB.Exec("INSERT INTO users (username, email, password) VALUES (?, ?, ?)", "Broken", "broken@gmail.com", "not a hash").Error
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	samples := []struct {
 		email        string
 		password     string
@@ -44,22 +50,32 @@ func TestSignIn(t *testing.T) {
 			password:     "p@$$w0rd",
 			errorMessage: "record not found",
 		},
+		{
+			email:        "broken@gmail.com",
+			password:     "not a hash",
+			errorMessage: "crypto/bcrypt: hashedSecret too short to be a bcrypted password",
+		},
 	}
 
 	for _, v := range samples {
 
 		token, err := server.SignIn(v.email, v.password)
-		if err != nil {
+		if v.errorMessage != "" {
 			assert.Equal(t, err, errors.New(v.errorMessage))
 		} else {
+			assert.Equal(t, err, nil)
 			assert.NotEqual(t, token, "")
 		}
 	}
 }
 
 func TestLogin(t *testing.T) {
-	refreshUserTable()
-	_, err := seedOneUser()
+	err := refreshUserAndTokenTables()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = seedOneUser()
 	if err != nil {
 		fmt.Printf("This is the error %v\n", err)
 	}
@@ -0,0 +1,296 @@
+package controllertests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/middlewares"
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/dmdinh22/go-blog/api/storage"
+	"github.com/gorilla/mux"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func pngImage(width, height int) []byte {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)))
+	if err != nil {
+		log.Fatalf("cannot encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// uploadRequest builds a multipart upload of data under field
+func uploadRequest(field, filename string, data []byte) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreateFormFile(field, filename)
+	if err != nil {
+		log.Fatalf("cannot create form file: %v", err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	req, err := http.NewRequest("POST", "/media", &body)
+	if err != nil {
+		log.Fatalf("cannot create request: %v", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return req
+}
+
+func useTempStorage() func() {
+	dir, err := ioutil.TempDir("", "go-blog-media")
+	if err != nil {
+		log.Fatalf("cannot create storage dir: %v", err)
+	}
+
+	server.Storage = storage.NewLocalStore(dir)
+	return func() { os.RemoveAll(dir) }
+}
+
+func TestUploadMedia(t *testing.T) {
+	defer useTempStorage()()
+
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, _, err = seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	token, err := server.SignIn("magu@gmail.com", "p@$$w0rd")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", token)
+
+	samples := []struct {
+		field        string
+		filename     string
+		data         []byte
+		tokenGiven   string
+		statusCode   int
+		contentType  string
+		width        int
+		height       int
+		errorMessage string
+	}{
+		{
+			field:       "file",
+			filename:    "cat.png",
+			data:        pngImage(4, 3),
+			tokenGiven:  tokenString,
+			statusCode:  201,
+			contentType: "image/png",
+			width:       4,
+			height:      3,
+		},
+		{
+			// the extension does not decide the type
+			field:       "file",
+			filename:    "../../notes.txt",
+			data:        []byte("%PDF-1.4\n%%EOF\n"),
+			tokenGiven:  tokenString,
+			statusCode:  201,
+			contentType: "application/pdf",
+		},
+		{
+			field:        "file",
+			filename:     "page.png",
+			data:         []byte("<html><script>alert(1)</script></html>"),
+			tokenGiven:   tokenString,
+			statusCode:   415,
+			errorMessage: "Unsupported file type",
+		},
+		{
+			field:        "file",
+			filename:     "huge.png",
+			data:         append(pngImage(1, 1), make([]byte, models.MaxMediaSize)...),
+			tokenGiven:   tokenString,
+			statusCode:   413,
+			errorMessage: "File is larger than 10 MB",
+		},
+		{
+			field:        "upload",
+			filename:     "cat.png",
+			data:         pngImage(1, 1),
+			tokenGiven:   tokenString,
+			statusCode:   400,
+			errorMessage: "Required file",
+		},
+		{
+			field:        "file",
+			filename:     "cat.png",
+			data:         pngImage(1, 1),
+			tokenGiven:   "",
+			statusCode:   401,
+			errorMessage: "Unauthorized",
+		},
+	}
+
+	for _, v := range samples {
+		req := uploadRequest(v.field, v.filename, v.data)
+		req.Header.Set("Authorization", v.tokenGiven)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(middlewares.SetMiddlewareAuthentication(server.UploadMedia))
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+
+		if v.statusCode == 201 {
+			media := models.Media{}
+			err = json.Unmarshal(rr.Body.Bytes(), &media)
+			if err != nil {
+				t.Errorf("Cannot convert to json: %v", err)
+			}
+
+			assert.Equal(t, media.ContentType, v.contentType)
+			assert.Equal(t, media.Size, int64(len(v.data)))
+			assert.Equal(t, media.Width, v.width)
+			assert.Equal(t, media.Height, v.height)
+			assert.Equal(t, media.UserID, uint32(2))
+			assert.Equal(t, media.URL, fmt.Sprintf("/api/media/%d/content", media.ID))
+		} else {
+			responseMap := make(map[string]interface{})
+			err = json.Unmarshal(rr.Body.Bytes(), &responseMap)
+			if err != nil {
+				t.Errorf("Cannot convert to json: %v", err)
+			}
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+	}
+
+	var count int
+	server.DB.Model(&models.Media{}).Count(&count)
+	assert.Equal(t, count, 2)
+
+	saved := models.Media{}
+	server.DB.Model(&models.Media{}).Where("content_type = ?", "application/pdf").Take(&saved)
+	assert.Equal(t, saved.Filename, "notes.txt")
+}
+
+func TestGetAndDeleteMedia(t *testing.T) {
+	defer useTempStorage()()
+
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, _, err = seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	owner, err := server.SignIn("magu@gmail.com", "p@$$w0rd")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+
+	other, err := server.SignIn("steven@gmail.com", "p@$$w0rd")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+
+	data := pngImage(2, 2)
+	req := uploadRequest("file", "dot.png", data)
+	req.Header.Set("Authorization", "Bearer "+owner)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(middlewares.SetMiddlewareAuthentication(server.UploadMedia)).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, 201)
+
+	media := models.Media{}
+	err = json.Unmarshal(rr.Body.Bytes(), &media)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+	id := strconv.Itoa(int(media.ID))
+
+	stored := models.Media{}
+	_, err = stored.GetMediaByID(server.DB, media.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the media: %v\n", err)
+	}
+
+	// the content is served as stored
+	req, _ = http.NewRequest("GET", "/media/"+id+"/content", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.GetMediaContent).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, 200)
+	assert.Equal(t, rr.Header().Get("Content-Type"), "image/png")
+	assert.Equal(t, rr.Body.Bytes(), data)
+
+	// a post can reference it by id
+	inputJSON := fmt.Sprintf(`{"title": "With media", "content": "![dot](%s)", "authorId": 2, "media": [%d]}`, media.URL, media.ID)
+	req, _ = http.NewRequest("POST", "/posts", bytes.NewBufferString(inputJSON))
+	req.Header.Set("Authorization", "Bearer "+owner)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(middlewares.SetMiddlewareAuthentication(server.CreatePost)).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, 201)
+
+	post := models.Post{}
+	err = json.Unmarshal(rr.Body.Bytes(), &post)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, len(post.Media), 1)
+	assert.Equal(t, post.Media[0].ID, media.ID)
+
+	// but not one uploaded by someone else
+	inputJSON = fmt.Sprintf(`{"title": "Borrowed", "content": "Not mine", "authorId": 1, "media": [%d]}`, media.ID)
+	req, _ = http.NewRequest("POST", "/posts", bytes.NewBufferString(inputJSON))
+	req.Header.Set("Authorization", "Bearer "+other)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(middlewares.SetMiddlewareAuthentication(server.CreatePost)).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, 422)
+
+	samples := []struct {
+		id         string
+		tokenGiven string
+		statusCode int
+	}{
+		{id: id, tokenGiven: "Bearer " + other, statusCode: 401},
+		{id: id, tokenGiven: "Bearer " + owner, statusCode: 204},
+		{id: id, tokenGiven: "Bearer " + owner, statusCode: 404},
+		{id: "unknown", tokenGiven: "Bearer " + owner, statusCode: 400},
+	}
+
+	for _, v := range samples {
+		req, _ := http.NewRequest("DELETE", "/media/"+v.id, nil)
+		req = mux.SetURLVars(req, map[string]string{"id": v.id})
+		req.Header.Set("Authorization", v.tokenGiven)
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(middlewares.SetMiddlewareAuthentication(server.DeleteMedia)).ServeHTTP(rr, req)
+		assert.Equal(t, rr.Code, v.statusCode)
+	}
+
+	req, _ = http.NewRequest("GET", "/media/"+id+"/content", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.GetMediaContent).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, 404)
+
+	// the file is gone along with the record
+	_, err = server.Storage.Get(req.Context(), stored.Key)
+	assert.Equal(t, err, storage.ErrNotFound)
+}
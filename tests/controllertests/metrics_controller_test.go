@@ -0,0 +1,180 @@
+package controllertests
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/config"
+	"github.com/dmdinh22/go-blog/api/controllers"
+	"github.com/gorilla/mux"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// scrape returns the samples served on /metrics by name and labels
+func scrape(handler http.Handler) map[string]float64 {
+	req, err := http.NewRequest("GET", "/metrics", nil)
+	if err != nil {
+		log.Fatalf("this is the error: %v\n", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	samples := map[string]float64{}
+	for _, line := range strings.Split(rr.Body.String(), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.LastIndex(line, " ")
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			log.Fatalf("cannot parse %q: %v\n", line, err)
+		}
+		samples[line[:i]] = value
+	}
+
+	return samples
+}
+
+func TestGetMetrics(t *testing.T) {
+	err := refreshUserAndTokenTables()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = seedOneUser()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	metricsServer := &controllers.Server{DB: server.DB, Router: mux.NewRouter()}
+	metricsServer.Router.HandleFunc("/api/login", metricsServer.Login).Methods("POST")
+	metricsServer.Router.HandleFunc("/api/users/{id}", metricsServer.GetUser).Methods("GET")
+	metricsServer.Router.HandleFunc("/metrics", metricsServer.GetMetrics).Methods("GET")
+	handler := metricsServer.Handler()
+
+	before := scrape(handler)
+
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{method: "POST", path: "/api/login", body: `{"email": "pet@gmail.com", "password": "p@$$w0rd"}`},
+		{method: "POST", path: "/api/login", body: `{"email": "pet@gmail.com", "password": "wrong password"}`},
+		{method: "POST", path: "/api/login", body: `{"email": "frank@gmail.com", "password": "p@$$w0rd"}`},
+		{method: "POST", path: "/api/login", body: `not json`},
+		{method: "POST", path: "/api/login", body: `{"email": "pet@gmail.com"}`},
+		{method: "GET", path: "/api/users/1"},
+		{method: "GET", path: "/api/users/2"},
+		{method: "GET", path: "/no/such/page"},
+	}
+
+	for _, v := range requests {
+		req, err := http.NewRequest(v.method, v.path, bytes.NewBufferString(v.body))
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rr := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "/metrics", nil)
+	if err != nil {
+		t.Errorf("this is the error: %v\n", err)
+	}
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, strings.HasPrefix(rr.Header().Get("Content-Type"), "text/plain; version=0.0.4"), true)
+
+	after := scrape(handler)
+
+	samples := []struct {
+		sample string
+		delta  float64
+	}{
+		{sample: `login_attempts_total{result="success"}`, delta: 1},
+		{sample: `login_attempts_total{result="failure"}`, delta: 2},
+		// requests that are not a login at all
+		{sample: `login_attempts_total{result="invalid"}`, delta: 2},
+		{sample: `http_requests_total{method="POST",route="/api/login",status="200"}`, delta: 1},
+		{sample: `http_requests_total{method="POST",route="/api/login",status="422"}`, delta: 4},
+		// requests are labelled with the template of their route, not their path
+		{sample: `http_requests_total{method="GET",route="/api/users/{id}",status="200"}`, delta: 1},
+		{sample: `http_requests_total{method="GET",route="/api/users/{id}",status="400"}`, delta: 1},
+		{sample: `http_requests_total{method="GET",route="unmatched",status="404"}`, delta: 1},
+		{sample: `http_request_duration_seconds_count{method="GET",route="/api/users/{id}"}`, delta: 2},
+		{sample: `http_request_duration_seconds_bucket{method="GET",route="/api/users/{id}",le="+Inf"}`, delta: 2},
+	}
+
+	for _, v := range samples {
+		assert.Equal(t, after[v.sample]-before[v.sample], v.delta)
+	}
+
+	_, ok := after[`http_requests_total{method="GET",route="/api/users/1",status="200"}`]
+	assert.Equal(t, ok, false)
+
+	// the connection pool of the database and the Go runtime
+	for _, name := range []string{`go_sql_open_connections{db_name="sqlite3"}`, `go_sql_in_use_connections{db_name="sqlite3"}`, `go_sql_idle_connections{db_name="sqlite3"}`, `go_sql_wait_count_total{db_name="sqlite3"}`, "go_goroutines", "go_memstats_alloc_bytes"} {
+		_, ok := after[name]
+		assert.Equal(t, ok, true)
+	}
+	assert.NotEqual(t, after[`go_sql_open_connections{db_name="sqlite3"}`], float64(0))
+}
+
+func TestRunServesMetricsApart(t *testing.T) {
+	// a free port for the metrics
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatalf("cannot listen: %v", err)
+	}
+	metricsAddr := listener.Addr().String()
+	listener.Close()
+
+	metricsServer := &controllers.Server{Router: mux.NewRouter(), MetricsAddr: metricsAddr}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- metricsServer.Run(ctx, &config.Config{Addr: "127.0.0.1:0"})
+	}()
+
+	// wait for the listener
+	var res *http.Response
+	for i := 0; i < 50; i++ {
+		res, err = http.Get("http://" + metricsAddr + "/metrics")
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, err, nil)
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.StatusCode, http.StatusOK)
+	assert.Equal(t, strings.Contains(string(body), "\ngo_goroutines "), true)
+
+	// only the metrics are served there
+	res, err = http.Get("http://" + metricsAddr + "/api/users")
+	assert.Equal(t, err, nil)
+	res.Body.Close()
+	assert.Equal(t, res.StatusCode, http.StatusNotFound)
+
+	cancel()
+	assert.Equal(t, <-served, nil)
+
+	// and stop with the server
+	_, err = http.Get("http://" + metricsAddr + "/metrics")
+	assert.NotEqual(t, err, nil)
+}
@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"testing"
 
+	"github.com/dmdinh22/go-blog/api/middlewares"
 	"github.com/dmdinh22/go-blog/api/models"
 	"github.com/gorilla/mux"
 	"gopkg.in/go-playground/assert.v1"
@@ -103,7 +104,7 @@ func TestCreatePost(t *testing.T) {
 			t.Errorf("this is the error: %v\n", err)
 		}
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(server.CreatePost)
+		handler := middlewares.SetMiddlewareAuthentication(server.CreatePost)
 
 		req.Header.Set("Authorization", v.tokenGiven)
 		handler.ServeHTTP(rr, req)
@@ -146,11 +147,136 @@ func TestGetPosts(t *testing.T) {
 	handler := http.HandlerFunc(server.GetPosts)
 	handler.ServeHTTP(rr, req)
 
-	var posts []models.Post
-	err = json.Unmarshal([]byte(rr.Body.String()), &posts)
+	var page struct {
+		Data       []models.Post `json:"data"`
+		NextCursor string        `json:"next_cursor"`
+	}
+	err = json.Unmarshal([]byte(rr.Body.String()), &page)
 
 	assert.Equal(t, rr.Code, http.StatusOK)
-	assert.Equal(t, len(posts), 2)
+	assert.Equal(t, len(page.Data), 2)
+	assert.Equal(t, page.NextCursor, "")
+}
+
+func TestGetPostsPagination(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, _, err = seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	samples := []struct {
+		query        string
+		statusCode   int
+		count        int
+		hasNext      bool
+		errorMessage string
+	}{
+		{
+			query:      "?limit=1",
+			statusCode: 200,
+			count:      1,
+			hasNext:    true,
+		},
+		{
+			query:      "?limit=2",
+			statusCode: 200,
+			count:      2,
+			hasNext:    false,
+		},
+		{
+			query:      "?author_id=2",
+			statusCode: 200,
+			count:      1,
+			hasNext:    false,
+		},
+		{
+			query:      "?since=2000-01-01T00:00:00Z&sort=created_at",
+			statusCode: 200,
+			count:      2,
+			hasNext:    false,
+		},
+		{
+			query:        "?limit=1000",
+			statusCode:   400,
+			errorMessage: "Limit must be between 1 and 100",
+		},
+		{
+			query:        "?sort=title",
+			statusCode:   400,
+			errorMessage: "Sort must be created_at or -created_at",
+		},
+		{
+			query:        "?since=yesterday",
+			statusCode:   400,
+			errorMessage: "Invalid since, expected RFC3339",
+		},
+		{
+			query:        "?cursor=not-a-cursor",
+			statusCode:   400,
+			errorMessage: "Invalid cursor",
+		},
+	}
+
+	for _, v := range samples {
+		req, err := http.NewRequest("GET", "/posts"+v.query, nil)
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.GetPosts)
+		handler.ServeHTTP(rr, req)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 {
+			assert.Equal(t, len(responseMap["data"].([]interface{})), v.count)
+			_, hasNext := responseMap["next_cursor"]
+			assert.Equal(t, hasNext, v.hasNext)
+		}
+
+		if v.statusCode == 400 {
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+	}
+
+	// walking the cursors must visit every post exactly once
+	seen := map[float64]bool{}
+	cursor := ""
+	for {
+		req, _ := http.NewRequest("GET", "/posts?limit=1&cursor="+cursor, nil)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.GetPosts).ServeHTTP(rr, req)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+		if err != nil {
+			t.Fatalf("Cannot convert to json: %v", err)
+		}
+
+		for _, p := range responseMap["data"].([]interface{}) {
+			id := p.(map[string]interface{})["ID"].(float64)
+			assert.Equal(t, seen[id], false)
+			seen[id] = true
+		}
+
+		next, ok := responseMap["next_cursor"].(string)
+		if !ok {
+			break
+		}
+		cursor = next
+	}
+	assert.Equal(t, len(seen), 2)
 }
 
 func TestGetPostByID(t *testing.T) {
@@ -318,6 +444,7 @@ func TestUpdatePost(t *testing.T) {
 		},
 		{
 			id:         "unknwon",
+			tokenGiven: tokenString,
 			statusCode: 400,
 		},
 		{
@@ -338,7 +465,7 @@ func TestUpdatePost(t *testing.T) {
 
 		req = mux.SetURLVars(req, map[string]string{"id": v.id})
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(server.UpdatePost)
+		handler := middlewares.SetMiddlewareAuthentication(server.UpdatePost)
 
 		req.Header.Set("Authorization", v.tokenGiven)
 
@@ -452,7 +579,7 @@ func TestDeletePost(t *testing.T) {
 		req = mux.SetURLVars(req, map[string]string{"id": v.id})
 
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(server.DeletePost)
+		handler := middlewares.SetMiddlewareAuthentication(server.DeletePost)
 
 		req.Header.Set("Authorization", v.tokenGiven)
 
@@ -472,3 +599,65 @@ func TestDeletePost(t *testing.T) {
 		}
 	}
 }
+
+func TestEditorCanModerateAnyPost(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	users, err := seedUsersWithRoles()
+	if err != nil {
+		log.Fatal(err)
+	}
+	editor, reader := users[1], users[2]
+
+	post := models.Post{
+		Title:    "The reader's post",
+		Content:  "Written by a reader",
+		AuthorID: reader.ID,
+	}
+	err = server.DB.Model(&models.Post{}).Create(&post).Error
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	editorToken, err := server.SignIn(editor.Email, "p@$$w0rd")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", editorToken)
+	id := strconv.Itoa(int(post.ID))
+
+	// editors may change the content but not the author
+	updateJSON := fmt.Sprintf(`{"title":"Moderated title", "content": "Moderated content", "authorId": %d}`, editor.ID)
+	req, _ := http.NewRequest("PUT", "/posts", bytes.NewBufferString(updateJSON))
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	req.Header.Set("Authorization", tokenString)
+	rr := httptest.NewRecorder()
+	middlewares.SetMiddlewareAuthentication(server.UpdatePost).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusUnauthorized)
+
+	updateJSON = fmt.Sprintf(`{"title":"Moderated title", "content": "Moderated content", "authorId": %d}`, reader.ID)
+	req, _ = http.NewRequest("PUT", "/posts", bytes.NewBufferString(updateJSON))
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	req.Header.Set("Authorization", tokenString)
+	rr = httptest.NewRecorder()
+	middlewares.SetMiddlewareAuthentication(server.UpdatePost).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	responseMap := make(map[string]interface{})
+	err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, responseMap["Title"], "Moderated title")
+	assert.Equal(t, responseMap["AuthorID"], float64(reader.ID))
+
+	req, _ = http.NewRequest("DELETE", "/posts", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	req.Header.Set("Authorization", tokenString)
+	rr = httptest.NewRecorder()
+	middlewares.SetMiddlewareAuthentication(server.DeletePost).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusNoContent)
+}
@@ -0,0 +1,166 @@
+package controllertests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/controllers"
+	"github.com/dmdinh22/go-blog/api/middlewares"
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/gorilla/mux"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestDraftPostVisibility(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	authorToken, err := server.SignIn("magu@gmail.com", "p@$$w0rd")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+
+	readerToken, err := server.SignIn("steven@gmail.com", "p@$$w0rd")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+
+	// the author drafts a post
+	inputJSON := fmt.Sprintf(`{"title": "Work in progress", "content": "Soon", "authorId": %d, "status": "draft"}`, users[1].ID)
+	req, err := http.NewRequest("POST", "/posts", bytes.NewBufferString(inputJSON))
+	if err != nil {
+		t.Errorf("this is the error: %v\n", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", authorToken))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(middlewares.SetMiddlewareAuthentication(server.CreatePost)).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusCreated)
+
+	draft := models.Post{}
+	err = json.Unmarshal(rr.Body.Bytes(), &draft)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v\n", err)
+	}
+	assert.Equal(t, draft.Status, models.PostStatusDraft)
+
+	samples := []struct {
+		tokenGiven string
+		listed     int
+		statusCode int
+	}{
+		{tokenGiven: "", listed: 2, statusCode: 404},
+		{tokenGiven: readerToken, listed: 2, statusCode: 404},
+		{tokenGiven: authorToken, listed: 3, statusCode: 200},
+	}
+
+	for _, v := range samples {
+		req, err := http.NewRequest("GET", "/posts", nil)
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+		if v.tokenGiven != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", v.tokenGiven))
+		}
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(middlewares.SetMiddlewareOptionalAuthentication(server.GetPosts)).ServeHTTP(rr, req)
+
+		page := struct {
+			Data []models.Post `json:"data"`
+		}{}
+		err = json.Unmarshal(rr.Body.Bytes(), &page)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v\n", err)
+		}
+		assert.Equal(t, len(page.Data), v.listed)
+
+		req, err = http.NewRequest("GET", "/posts", nil)
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.Itoa(int(draft.ID))})
+		if v.tokenGiven != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", v.tokenGiven))
+		}
+
+		rr = httptest.NewRecorder()
+		http.HandlerFunc(middlewares.SetMiddlewareOptionalAuthentication(server.GetPost)).ServeHTTP(rr, req)
+		assert.Equal(t, rr.Code, v.statusCode)
+	}
+
+	// a bad token is rejected rather than treated as anonymous
+	req, err = http.NewRequest("GET", "/posts", nil)
+	if err != nil {
+		t.Errorf("this is the error: %v\n", err)
+	}
+	req.Header.Set("Authorization", "Bearer wrong-token")
+
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(middlewares.SetMiddlewareOptionalAuthentication(server.GetPosts)).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusUnauthorized)
+}
+
+func TestPublishScheduler(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	publishAt := time.Now().Add(200 * time.Millisecond)
+	post := models.Post{
+		Title:     "Scheduled",
+		Content:   "Coming soon",
+		AuthorID:  users[0].ID,
+		Status:    models.PostStatusScheduled,
+		PublishAt: &publishAt,
+	}
+	_, err = post.CreatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot seed posts table: %v", err)
+	}
+
+	// the interval is much longer than the test, so the post can only be
+	// published on time by sleeping until it is due
+	scheduler := controllers.NewPublishScheduler(server.DB, time.Hour)
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	status := ""
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		current := models.Post{}
+		err = server.DB.Model(&models.Post{}).Where("id = ?", post.ID).Take(&current).Error
+		if err != nil {
+			t.Errorf("this is the error getting the post: %v\n", err)
+			return
+		}
+
+		status = current.Status
+		if status == models.PostStatusPublished {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	assert.Equal(t, status, models.PostStatusPublished)
+}
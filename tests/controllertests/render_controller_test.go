@@ -0,0 +1,72 @@
+package controllertests
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/gorilla/mux"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestGetPostFormats(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	post := posts[0]
+	err = server.DB.Model(&models.Post{}).Where("id = ?", post.ID).Update("content", "**Bold** <script>x()</script>move").Error
+	if err != nil {
+		log.Fatalf("cannot update post: %v\n", err)
+	}
+
+	samples := []struct {
+		query        string
+		statusCode   int
+		html         interface{}
+		text         interface{}
+		errorMessage string
+	}{
+		{query: "", statusCode: 200, html: "<p><strong>Bold</strong> move</p>\n"},
+		{query: "?format=html", statusCode: 200, html: "<p><strong>Bold</strong> move</p>\n"},
+		{query: "?format=plain", statusCode: 200, text: "Bold move"},
+		{query: "?format=markdown", statusCode: 200},
+		{query: "?format=pdf", statusCode: 400, errorMessage: "Format must be markdown, html or plain"},
+	}
+
+	for _, v := range samples {
+		req, err := http.NewRequest("GET", "/posts"+v.query, nil)
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.Itoa(int(post.ID))})
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.GetPost).ServeHTTP(rr, req)
+		assert.Equal(t, rr.Code, v.statusCode)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal(rr.Body.Bytes(), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+
+		if v.statusCode == 200 {
+			assert.Equal(t, responseMap["Content"], "**Bold** <script>x()</script>move")
+			assert.Equal(t, responseMap["contentHtml"], v.html)
+			assert.Equal(t, responseMap["contentText"], v.text)
+		} else {
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+	}
+}
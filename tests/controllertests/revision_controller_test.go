@@ -0,0 +1,197 @@
+package controllertests
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/middlewares"
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/dmdinh22/go-blog/api/utils/diff"
+	"github.com/gorilla/mux"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestPostRevisions(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	post := posts[1]
+	for _, content := range []string{"Hello world 2, edited", "Hello world 2, edited again"} {
+		postUpdate := models.Post{ID: post.ID, Title: post.Title, Content: content, AuthorID: post.AuthorID}
+		_, err = postUpdate.UpdatePost(server.DB)
+		if err != nil {
+			log.Fatalf("cannot update post: %v\n", err)
+		}
+	}
+
+	postID := strconv.Itoa(int(post.ID))
+
+	req, err := http.NewRequest("GET", "/posts/"+postID+"/revisions", nil)
+	if err != nil {
+		t.Errorf("this is the error: %v\n", err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"id": postID})
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetRevisions).ServeHTTP(rr, req)
+
+	revisions := []models.PostRevision{}
+	err = json.Unmarshal(rr.Body.Bytes(), &revisions)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v\n", err)
+	}
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, len(revisions), 3)
+
+	diffs := []struct {
+		query      string
+		statusCode int
+		from       int
+		to         int
+	}{
+		{query: "", statusCode: 200, from: 2, to: 3},
+		{query: "?from=1&to=3", statusCode: 200, from: 1, to: 3},
+		{query: "?from=1&to=9", statusCode: 404},
+		{query: "?from=one", statusCode: 400},
+	}
+
+	for _, v := range diffs {
+		req, err := http.NewRequest("GET", "/posts/"+postID+"/revisions/diff"+v.query, nil)
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": postID})
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.GetRevisionDiff).ServeHTTP(rr, req)
+		assert.Equal(t, rr.Code, v.statusCode)
+
+		if v.statusCode == 200 {
+			diff := models.RevisionDiff{}
+			err = json.Unmarshal(rr.Body.Bytes(), &diff)
+			if err != nil {
+				t.Errorf("Cannot convert to json: %v\n", err)
+			}
+			assert.Equal(t, diff.From, v.from)
+			assert.Equal(t, diff.To, v.to)
+			assert.NotEqual(t, diff.Diff, "")
+		}
+	}
+
+	// a post with a single revision is compared with the empty text, and
+	// texts too long to compare are refused
+	single := models.Post{Title: "Single revision", Content: "Only once", AuthorID: post.AuthorID}
+	_, err = single.CreatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot create post: %v\n", err)
+	}
+
+	long := models.Post{Title: "Long", Content: strings.Repeat("line\n", diff.MaxLines), AuthorID: post.AuthorID}
+	_, err = long.CreatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot create post: %v\n", err)
+	}
+
+	singles := []struct {
+		postID     uint64
+		statusCode int
+		diff       string
+	}{
+		{postID: single.ID, statusCode: 200, diff: "--- revision 0\n+++ revision 1\n@@ -0,0 +1,3 @@\n+Single revision\n+\n+Only once\n"},
+		{postID: long.ID, statusCode: 422},
+	}
+
+	for _, v := range singles {
+		id := strconv.Itoa(int(v.postID))
+		req, err := http.NewRequest("GET", "/posts/"+id+"/revisions/diff", nil)
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.GetRevisionDiff).ServeHTTP(rr, req)
+		assert.Equal(t, rr.Code, v.statusCode)
+
+		responseMap := map[string]interface{}{}
+		err = json.Unmarshal(rr.Body.Bytes(), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v\n", err)
+		}
+
+		if v.statusCode == 200 {
+			assert.Equal(t, responseMap["from"], float64(0))
+			assert.Equal(t, responseMap["to"], float64(1))
+			assert.Equal(t, responseMap["diff"], v.diff)
+		} else {
+			assert.Equal(t, responseMap["error"], diff.ErrTooLarge.Error())
+		}
+	}
+
+	authorToken, err := server.SignIn("magu@gmail.com", "p@$$w0rd")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+
+	otherToken, err := server.SignIn("steven@gmail.com", "p@$$w0rd")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+
+	restores := []struct {
+		rev          string
+		tokenGiven   string
+		statusCode   int
+		content      string
+		errorMessage string
+	}{
+		{rev: "1", tokenGiven: otherToken, statusCode: 401, errorMessage: "Unauthorized"},
+		{rev: "9", tokenGiven: authorToken, statusCode: 404, errorMessage: "Revision not found"},
+		{rev: "1", tokenGiven: authorToken, statusCode: 200, content: "Hello world 2"},
+	}
+
+	for _, v := range restores {
+		req, err := http.NewRequest("POST", "/posts/"+postID+"/revisions/"+v.rev+"/restore", nil)
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": postID, "rev": v.rev})
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", v.tokenGiven))
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(middlewares.SetMiddlewareAuthentication(server.RestoreRevision)).ServeHTTP(rr, req)
+		assert.Equal(t, rr.Code, v.statusCode)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal(rr.Body.Bytes(), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+
+		if v.statusCode == 200 {
+			assert.Equal(t, responseMap["Content"], v.content)
+		} else {
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+	}
+
+	// restoring is itself a revision
+	latest, err := post.LatestRevision(server.DB, post.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the latest revision: %v\n", err)
+	}
+	assert.Equal(t, latest, 4)
+}
@@ -0,0 +1,100 @@
+package controllertests
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestSearchPosts(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, _, err = seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server.Search = models.TextSearcher{}
+
+	samples := []struct {
+		query        string
+		statusCode   int
+		titles       []string
+		errorMessage string
+	}{
+		{
+			query:      "?q=hello+world",
+			statusCode: 200,
+			titles:     []string{"Title 2", "Title 1"},
+		},
+		{
+			query:      "?q=title+1",
+			statusCode: 200,
+			titles:     []string{"Title 1", "Title 2"},
+		},
+		{
+			query:      "?q=world&limit=1",
+			statusCode: 200,
+			titles:     []string{"Title 2"},
+		},
+		{
+			query:      "?q=nothing",
+			statusCode: 200,
+			titles:     []string{},
+		},
+		{
+			query:        "?q=",
+			statusCode:   400,
+			errorMessage: "Required Query",
+		},
+		{
+			query:        "?q=world&limit=many",
+			statusCode:   400,
+			errorMessage: "Invalid limit",
+		},
+	}
+
+	for _, v := range samples {
+		req, err := http.NewRequest("GET", "/search"+v.query, nil)
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.SearchPosts)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+
+		if v.statusCode == 200 {
+			posts := []models.Post{}
+			err = json.Unmarshal(rr.Body.Bytes(), &posts)
+			if err != nil {
+				t.Errorf("Cannot convert to json: %v\n", err)
+			}
+
+			titles := []string{}
+			for _, post := range posts {
+				titles = append(titles, post.Title)
+			}
+			assert.Equal(t, titles, v.titles)
+		}
+
+		if v.statusCode == 400 {
+			responseMap := make(map[string]interface{})
+			err = json.Unmarshal(rr.Body.Bytes(), &responseMap)
+			if err != nil {
+				t.Errorf("Cannot convert to json: %v", err)
+			}
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+	}
+}
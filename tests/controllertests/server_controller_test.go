@@ -0,0 +1,143 @@
+package controllertests
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/config"
+	"github.com/dmdinh22/go-blog/api/controllers"
+	"github.com/gorilla/mux"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// slowServer serves a route taking delay to answer, closing started once a
+// request came in
+func slowServer(delay time.Duration, started chan struct{}) (*controllers.Server, net.Listener) {
+	router := mux.NewRouter()
+	router.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(delay)
+		w.Write([]byte("done"))
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatalf("cannot listen: %v", err)
+	}
+
+	return &controllers.Server{Router: router}, listener
+}
+
+func TestServeDrainsRequests(t *testing.T) {
+	started := make(chan struct{})
+	s, listener := slowServer(300*time.Millisecond, started)
+	url := "http://" + listener.Addr().String() + "/slow"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(ctx, listener, config.HTTP{ShutdownTimeout: 5 * time.Second})
+	}()
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	answered := make(chan result, 1)
+	go func() {
+		res, err := http.Get(url)
+		if err != nil {
+			answered <- result{err: err}
+			return
+		}
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		answered <- result{status: res.StatusCode, body: string(body), err: err}
+	}()
+
+	<-started
+	cancel()
+
+	// the request in flight is answered before Serve returns
+	r := <-answered
+	assert.Equal(t, r.err, nil)
+	assert.Equal(t, r.status, 200)
+	assert.Equal(t, r.body, "done")
+	assert.Equal(t, <-served, nil)
+
+	// and no new connections are accepted
+	_, err := http.Get(url)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestServeShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	s, listener := slowServer(2*time.Second, started)
+
+	db, err := controllers.OpenDB("sqlite3", "", "", "", "", ":memory:")
+	if err != nil {
+		log.Fatalf("cannot open database: %v", err)
+	}
+	s.DB = db
+	s.Scheduler = controllers.NewPublishScheduler(db, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(ctx, listener, config.HTTP{ShutdownTimeout: 100 * time.Millisecond})
+	}()
+
+	answered := make(chan error, 1)
+	go func() {
+		res, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err == nil {
+			res.Body.Close()
+		}
+		answered <- err
+	}()
+
+	<-started
+	begin := time.Now()
+	cancel()
+
+	assert.Equal(t, <-served, context.DeadlineExceeded)
+	assert.Equal(t, time.Since(begin) < time.Second, true)
+
+	// the scheduler was stopped before the requests used up the timeout,
+	// then the database closed
+	assert.Equal(t, s.Scheduler.Check(context.Background()).Error(), "The scheduler has stopped")
+	assert.NotEqual(t, s.DB.DB().Ping(), nil)
+
+	// the connections still open are closed rather than left to the handler
+	assert.NotEqual(t, <-answered, nil)
+	assert.Equal(t, time.Since(begin) < time.Second, true)
+}
+
+func TestSchedulerStop(t *testing.T) {
+	scheduler := controllers.NewPublishScheduler(server.DB, time.Hour)
+
+	// a scheduler never started has nothing to wait for
+	stopped := make(chan struct{})
+	go func() {
+		scheduler.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop blocked on a scheduler never started")
+	}
+
+	// and a started one can be stopped twice
+	scheduler.Start()
+	scheduler.Stop()
+	scheduler.Stop()
+	assert.Equal(t, scheduler.Check(context.Background()).Error(), "The scheduler has stopped")
+}
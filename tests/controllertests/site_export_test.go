@@ -0,0 +1,125 @@
+package controllertests
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/export"
+	"github.com/dmdinh22/go-blog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func readExported(dir, name string) string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func TestExport(t *testing.T) {
+	loadTheme("")
+
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	users, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	draft := models.Post{Title: "Unfinished", Content: "Draft", AuthorID: users[0].ID, Status: models.PostStatusDraft}
+	_, err = draft.CreatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot create post: %v\n", err)
+	}
+
+	// enough posts for a second front page
+	for i := 0; i < 9; i++ {
+		post := models.Post{Title: fmt.Sprintf("Filler post %d", i), Content: "Filler", AuthorID: users[0].ID}
+		_, err = post.CreatePost(server.DB)
+		if err != nil {
+			log.Fatalf("cannot create post: %v\n", err)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "go-blog-export")
+	if err != nil {
+		log.Fatalf("cannot create export dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	exporter := export.Exporter{DB: server.DB, Theme: server.Theme, Dir: dir, BaseURL: "https://blog.example.com/"}
+
+	stats, err := exporter.Run()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, stats, export.Stats{Written: 11})
+
+	index := readExported(dir, "index.html")
+	assert.Equal(t, strings.Contains(index, "Filler post 8"), true)
+	assert.Equal(t, strings.Contains(index, `href="/page/2/"`), true)
+	assert.Equal(t, strings.Contains(index, "Unfinished"), false)
+
+	assert.Equal(t, strings.Contains(readExported(dir, "posts/"+posts[1].Slug+"/index.html"), posts[1].Title), true)
+	assert.Equal(t, readExported(dir, "posts/unfinished/index.html"), "")
+	assert.Equal(t, strings.Contains(readExported(dir, "authors/2/index.html"), posts[1].Title), true)
+	assert.Equal(t, strings.Contains(readExported(dir, "feed.atom"), "https://blog.example.com/posts/"+posts[0].Slug), true)
+	assert.Equal(t, strings.Contains(readExported(dir, "api/users/2/feed.json"), posts[1].Title), true)
+	assert.Equal(t, strings.Contains(readExported(dir, "sitemap.xml"), "<loc>https://blog.example.com/posts/"+posts[1].Slug+"</loc>"), true)
+	assert.Equal(t, strings.Contains(readExported(dir, "page/2/index.html"), posts[0].Title), true)
+
+	// nothing changed
+	stats, err = exporter.Run()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, stats, export.Stats{Skipped: 11})
+
+	// a renamed post moves and leaves a redirect at its old path, a
+	// post taken back to draft is removed
+	oldSlug := posts[0].Slug
+	post := posts[0]
+	post.Title = "A brand new title"
+	_, err = post.UpdatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot update post: %v\n", err)
+	}
+
+	err = server.DB.Model(&models.Post{}).Where("id = ?", posts[1].ID).UpdateColumns(map[string]interface{}{
+		"status":     models.PostStatusDraft,
+		"updated_at": time.Now().Add(time.Minute),
+	}).Error
+	if err != nil {
+		log.Fatalf("cannot update post: %v\n", err)
+	}
+
+	stats, err = exporter.Run()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, stats, export.Stats{Written: 1, Skipped: 9, Removed: 2})
+
+	assert.Equal(t, strings.Contains(readExported(dir, "posts/a-brand-new-title/index.html"), "A brand new title"), true)
+	assert.Equal(t, strings.Contains(readExported(dir, "posts/"+oldSlug+"/index.html"), `url=/posts/a-brand-new-title`), true)
+	assert.Equal(t, readExported(dir, "posts/"+posts[1].Slug+"/index.html"), "")
+	assert.Equal(t, strings.Contains(readExported(dir, "index.html"), posts[1].Title), false)
+
+	// so are the listing and feeds of an author with nothing published, and
+	// pages past the end of a listing
+	for _, name := range []string{"authors/2", "api/users/2", "page/2"} {
+		_, err = os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		assert.Equal(t, os.IsNotExist(err), true)
+	}
+	assert.NotEqual(t, readExported(dir, "authors/1/index.html"), "")
+	assert.NotEqual(t, readExported(dir, "api/users/1/feed.rss"), "")
+
+	// force rewrites unchanged posts
+	exporter.Force = true
+	stats, err = exporter.Run()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, stats, export.Stats{Written: 10})
+}
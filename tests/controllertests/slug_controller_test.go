@@ -0,0 +1,86 @@
+package controllertests
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/gorilla/mux"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestGetPostBySlug(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// rename the first post so its original slug is kept as history
+	post := posts[0]
+	original := post.Slug
+	post.Title = "Renamed Title"
+	_, err = post.UpdatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot update post: %v\n", err)
+	}
+
+	draft := models.Post{Title: "Secret Plans", Content: "Draft", AuthorID: posts[1].AuthorID, Status: models.PostStatusDraft}
+	_, err = draft.CreatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot create post: %v\n", err)
+	}
+
+	samples := []struct {
+		slug         string
+		query        string
+		statusCode   int
+		title        string
+		location     string
+		errorMessage string
+	}{
+		{slug: "renamed-title", statusCode: 200, title: "Renamed Title"},
+		{slug: posts[1].Slug, statusCode: 200, title: posts[1].Title},
+		{slug: original, statusCode: 301, location: "/api/posts/by-slug/renamed-title"},
+		{slug: original, query: "?format=plain", statusCode: 301, location: "/api/posts/by-slug/renamed-title?format=plain"},
+		{slug: "secret-plans", statusCode: 404, errorMessage: "Post not found"},
+		{slug: "no-such-post", statusCode: 404, errorMessage: "Post not found"},
+	}
+
+	for _, v := range samples {
+		req, err := http.NewRequest("GET", "/api/posts/by-slug/"+v.slug+v.query, nil)
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"slug": v.slug})
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.GetPostBySlug).ServeHTTP(rr, req)
+		assert.Equal(t, rr.Code, v.statusCode)
+
+		if v.statusCode == 301 {
+			assert.Equal(t, rr.Header().Get("Location"), v.location)
+			continue
+		}
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal(rr.Body.Bytes(), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+
+		if v.statusCode == 200 {
+			assert.Equal(t, responseMap["Title"], v.title)
+			assert.Equal(t, responseMap["slug"], v.slug)
+		} else {
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package controllertests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/middlewares"
+	"github.com/dmdinh22/go-blog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestCreatePostWithTags(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, _, err = seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	token, err := server.SignIn("magu@gmail.com", "p@$$w0rd")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", token)
+
+	samples := []struct {
+		inputJSON    string
+		statusCode   int
+		tags         []string
+		errorMessage string
+	}{
+		{
+			inputJSON:  `{"title": "Go and SQL", "content": "Both", "authorId": 2, "tags": ["Go", "databases", "go"]}`,
+			statusCode: 201,
+			tags:       []string{"databases", "go"},
+		},
+		{
+			inputJSON:  `{"title": "Just Go", "content": "One", "authorId": 2, "tags": [{"name": "go"}]}`,
+			statusCode: 201,
+			tags:       []string{"go"},
+		},
+		{
+			inputJSON:  `{"title": "Untagged", "content": "None", "authorId": 2}`,
+			statusCode: 201,
+			tags:       []string{},
+		},
+		{
+			inputJSON:    `{"title": "Blank tag", "content": "Blank", "authorId": 2, "tags": [" "]}`,
+			statusCode:   422,
+			errorMessage: "Tag names cannot be empty",
+		},
+	}
+
+	for _, v := range samples {
+		req, err := http.NewRequest("POST", "/posts", bytes.NewBufferString(v.inputJSON))
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(middlewares.SetMiddlewareAuthentication(server.CreatePost))
+		req.Header.Set("Authorization", tokenString)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+
+		if v.statusCode == 201 {
+			post := models.Post{}
+			err = json.Unmarshal(rr.Body.Bytes(), &post)
+			if err != nil {
+				t.Errorf("Cannot convert to json: %v", err)
+			}
+
+			names := []string{}
+			for _, tag := range post.Tags {
+				names = append(names, tag.Name)
+			}
+			assert.Equal(t, names, v.tags)
+		}
+
+		if v.statusCode == 422 {
+			responseMap := make(map[string]interface{})
+			err = json.Unmarshal(rr.Body.Bytes(), &responseMap)
+			if err != nil {
+				t.Errorf("Cannot convert to json: %v", err)
+			}
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+	}
+
+	// listings can be filtered by tag
+	filters := []struct {
+		query string
+		count int
+	}{
+		{query: "?tag=go", count: 2},
+		{query: "?tag=go&tag=databases", count: 1},
+		{query: "?tag=go&tag=databases&tag_mode=any", count: 2},
+		{query: "?tag=rust", count: 0},
+	}
+
+	for _, v := range filters {
+		req, err := http.NewRequest("GET", "/posts"+v.query, nil)
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.GetPosts)
+		handler.ServeHTTP(rr, req)
+
+		page := struct {
+			Data []models.Post `json:"data"`
+		}{}
+		err = json.Unmarshal(rr.Body.Bytes(), &page)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v\n", err)
+		}
+
+		assert.Equal(t, rr.Code, http.StatusOK)
+		assert.Equal(t, len(page.Data), v.count)
+	}
+
+	req, err := http.NewRequest("GET", "/tags", nil)
+	if err != nil {
+		t.Errorf("this is the error: %v\n", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(server.GetTags)
+	handler.ServeHTTP(rr, req)
+
+	tags := []models.TagCount{}
+	err = json.Unmarshal(rr.Body.Bytes(), &tags)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v\n", err)
+	}
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, len(tags), 2)
+	assert.Equal(t, tags[0].Name, "go")
+	assert.Equal(t, tags[0].PostCount, 2)
+	assert.Equal(t, tags[1].Name, "databases")
+	assert.Equal(t, tags[1].PostCount, 1)
+}
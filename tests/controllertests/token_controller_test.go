@@ -0,0 +1,149 @@
+package controllertests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/middlewares"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func login(t *testing.T, email, password string) map[string]interface{} {
+	inputJSON := fmt.Sprintf(`{"email": "%s", "password": "%s"}`, email, password)
+	req, err := http.NewRequest("POST", "/login", bytes.NewBufferString(inputJSON))
+	if err != nil {
+		t.Fatalf("this is the error: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(server.Login)
+	handler.ServeHTTP(rr, req)
+
+	responseMap := make(map[string]interface{})
+	err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+	if err != nil {
+		t.Fatalf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	return responseMap
+}
+
+func refresh(refreshToken string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	inputJSON := fmt.Sprintf(`{"refresh_token": "%s"}`, refreshToken)
+	req, _ := http.NewRequest("POST", "/api/token/refresh", bytes.NewBufferString(inputJSON))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(server.RefreshToken)
+	handler.ServeHTTP(rr, req)
+
+	responseMap := make(map[string]interface{})
+	json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+
+	return rr, responseMap
+}
+
+func TestRefreshToken(t *testing.T) {
+	err := refreshUserAndTokenTables()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+
+	tokens := login(t, user.Email, "p@$$w0rd")
+	firstRefresh := tokens["refresh_token"].(string)
+	assert.NotEqual(t, firstRefresh, "")
+
+	// a valid refresh token is rotated
+	rr, responseMap := refresh(firstRefresh)
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.NotEqual(t, responseMap["token"], nil)
+	secondRefresh := responseMap["refresh_token"].(string)
+	assert.NotEqual(t, secondRefresh, firstRefresh)
+
+	// replaying the old token is rejected and revokes the whole chain
+	rr, responseMap = refresh(firstRefresh)
+	assert.Equal(t, rr.Code, http.StatusUnauthorized)
+	assert.Equal(t, responseMap["error"], "Refresh token has already been used")
+
+	rr, responseMap = refresh(secondRefresh)
+	assert.Equal(t, rr.Code, http.StatusUnauthorized)
+	assert.Equal(t, responseMap["error"], "Refresh token has already been used")
+
+	rr, responseMap = refresh("not a refresh token")
+	assert.Equal(t, rr.Code, http.StatusUnauthorized)
+	assert.Equal(t, responseMap["error"], "Invalid refresh token")
+}
+
+func TestLogout(t *testing.T) {
+	err := refreshUserAndTokenTables()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+
+	tokens := login(t, user.Email, "p@$$w0rd")
+	tokenString := fmt.Sprintf("Bearer %v", tokens["token"])
+
+	samples := []struct {
+		tokenGiven   string
+		statusCode   int
+		errorMessage string
+	}{
+		{
+			tokenGiven: tokenString,
+			statusCode: 204,
+		},
+		{
+			// the access token is revoked by the first logout
+			tokenGiven:   tokenString,
+			statusCode:   401,
+			errorMessage: "Unauthorized",
+		},
+		{
+			tokenGiven:   "",
+			statusCode:   401,
+			errorMessage: "Unauthorized",
+		},
+	}
+
+	for _, v := range samples {
+		req, err := http.NewRequest("POST", "/api/logout", bytes.NewBufferString(""))
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := middlewares.SetMiddlewareAuthentication(server.Logout)
+
+		req.Header.Set("Authorization", v.tokenGiven)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 401 {
+			responseMap := make(map[string]interface{})
+			err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+			if err != nil {
+				t.Errorf("Cannot convert to json: %v", err)
+			}
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+	}
+
+	// logging out without a refresh token revokes all of them
+	rr, _ := refresh(tokens["refresh_token"].(string))
+	assert.Equal(t, rr.Code, http.StatusUnauthorized)
+}
@@ -10,6 +10,8 @@ import (
 	"strconv"
 	"testing"
 
+	"github.com/dmdinh22/go-blog/api/auth"
+	"github.com/dmdinh22/go-blog/api/middlewares"
 	"github.com/dmdinh22/go-blog/api/models"
 	"github.com/gorilla/mux"
 	"gopkg.in/go-playground/assert.v1"
@@ -318,7 +320,7 @@ func TestUpdateUser(t *testing.T) {
 		req = mux.SetURLVars(req, map[string]string{"id": v.id})
 
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(server.UpdateUser)
+		handler := middlewares.SetMiddlewareAuthentication(server.UpdateUser)
 		req.Header.Set("Authorization", v.tokenGiven)
 		handler.ServeHTTP(rr, req)
 
@@ -333,6 +335,7 @@ func TestUpdateUser(t *testing.T) {
 		if v.statusCode == 200 {
 			assert.Equal(t, responseMap["Username"], v.updateUsername)
 			assert.Equal(t, responseMap["Email"], v.updateEmail)
+			assert.Equal(t, responseMap["role"], auth.RoleUser)
 		}
 
 		if v.statusCode == 401 || v.statusCode == 422 || v.statusCode == 500 && v.errorMessage != "" {
@@ -421,7 +424,7 @@ func TestDeleteUser(t *testing.T) {
 
 		req = mux.SetURLVars(req, map[string]string{"id": v.id})
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(server.DeleteUser)
+		handler := middlewares.SetMiddlewareAuthentication(server.DeleteUser)
 
 		req.Header.Set("Authorization", v.tokenGiven)
 
@@ -0,0 +1,117 @@
+package difftests
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/utils/diff"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestUnified(t *testing.T) {
+	samples := []struct {
+		a    string
+		b    string
+		diff string
+	}{
+		{a: "one\ntwo\nthree\n", b: "one\ntwo\nthree\n", diff: ""},
+		{a: "", b: "new\n", diff: "--- a\n+++ b\n@@ -0,0 +1 @@\n+new\n"},
+		{a: "old\n", b: "", diff: "--- a\n+++ b\n@@ -1 +0,0 @@\n-old\n"},
+		{
+			a:    "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n",
+			b:    "a\nB\nc\nd\ne\nf\ng\nh\ni\nJ\n",
+			diff: "--- a\n+++ b\n@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n@@ -7,4 +7,4 @@\n g\n h\n i\n-j\n+J\n",
+		},
+		{a: "x\ny\n", b: "y\nx\n", diff: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n-x\n y\n+x\n"},
+	}
+
+	for _, v := range samples {
+		text, err := diff.Unified("a", "b", v.a, v.b, diff.DefaultContext)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, text, v.diff)
+	}
+}
+
+// lcs is the length of the longest common subsequence of a and b
+func lcs(a, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				table[i][j] = table[i+1][j+1] + 1
+			case table[i+1][j] >= table[i][j+1]:
+				table[i][j] = table[i+1][j]
+			default:
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+
+	return table[0][0]
+}
+
+func randomLines(r *rand.Rand) []string {
+	lines := make([]string, r.Intn(30))
+	for i := range lines {
+		lines[i] = string(rune('a' + r.Intn(4)))
+	}
+
+	return lines
+}
+
+func TestUnifiedIsMinimal(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 500; i++ {
+		a, b := randomLines(r), randomLines(r)
+		textA, textB := strings.Join(a, "\n"), strings.Join(b, "\n")
+
+		// enough context for a single hunk holding both texts
+		text, err := diff.Unified("a", "b", textA, textB, len(a)+len(b))
+		assert.Equal(t, err, nil)
+
+		oldLines, newLines, changes := []string{}, []string{}, 0
+		for _, line := range strings.Split(text, "\n") {
+			if line == "" || strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "@@") {
+				continue
+			}
+
+			switch line[0] {
+			case ' ':
+				oldLines = append(oldLines, line[1:])
+				newLines = append(newLines, line[1:])
+			case '-':
+				oldLines = append(oldLines, line[1:])
+				changes++
+			case '+':
+				newLines = append(newLines, line[1:])
+				changes++
+			}
+		}
+
+		if text == "" {
+			oldLines, newLines = a, b
+		}
+
+		// the diff turns a into b with the fewest changed lines
+		assert.Equal(t, strings.Join(oldLines, "\n"), textA)
+		assert.Equal(t, strings.Join(newLines, "\n"), textB)
+		assert.Equal(t, changes, len(a)+len(b)-2*lcs(a, b))
+	}
+}
+
+func TestUnifiedTooLarge(t *testing.T) {
+	long := strings.Repeat("line\n", diff.MaxLines+1)
+
+	_, err := diff.Unified("a", "b", long, "short\n", diff.DefaultContext)
+	assert.Equal(t, err, diff.ErrTooLarge)
+
+	_, err = diff.Unified("a", "b", strings.Repeat("line\n", diff.MaxLines), "short\n", diff.DefaultContext)
+	assert.Equal(t, err, nil)
+}
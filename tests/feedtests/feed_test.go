@@ -0,0 +1,244 @@
+package feedtests
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/feeds"
+	"github.com/dmdinh22/go-blog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+var published = time.Date(2020, 3, 1, 9, 30, 0, 0, time.UTC)
+
+func sampleFeed() feeds.Feed {
+	return feeds.Feed{
+		Title:       "Go Blog",
+		Description: "The latest posts",
+		Link:        "http://example.com/",
+		FeedURL:     "http://example.com/feed.xml",
+		Updated:     published.Add(time.Hour),
+		Items: []feeds.Item{
+			{
+				ID:        "http://example.com/api/posts/1",
+				Title:     "Fish & Chips",
+				Link:      "http://example.com/posts/fish-chips",
+				Author:    "magu",
+				Summary:   "A recipe",
+				Content:   "<p>A <em>recipe</em> &amp; more</p>",
+				Tags:      []string{"food"},
+				Published: published,
+				Updated:   published.Add(time.Hour),
+			},
+		},
+	}
+}
+
+// RSS 2.0: https://www.rssboard.org/rss-specification
+func TestRSS(t *testing.T) {
+	out, err := feeds.RSS(sampleFeed())
+	if err != nil {
+		t.Fatalf("cannot write rss: %v", err)
+	}
+
+	doc := struct {
+		XMLName xml.Name
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Title       string `xml:"title"`
+			Description string `xml:"description"`
+			// the rss link and atom:link share a local name
+			Links []struct {
+				XMLName xml.Name
+				Href    string `xml:"href,attr"`
+				Rel     string `xml:"rel,attr"`
+				Value   string `xml:",chardata"`
+			} `xml:"link"`
+			Items []struct {
+				Title       string   `xml:"title"`
+				Link        string   `xml:"link"`
+				Description string   `xml:"description"`
+				Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+				Categories  []string `xml:"category"`
+				GUID        struct {
+					IsPermaLink string `xml:"isPermaLink,attr"`
+					Value       string `xml:",chardata"`
+				} `xml:"guid"`
+				PubDate string `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}{}
+
+	err = xml.Unmarshal(out, &doc)
+	if err != nil {
+		t.Fatalf("invalid xml: %v\n%s", err, out)
+	}
+
+	assert.Equal(t, doc.XMLName.Local, "rss")
+	assert.Equal(t, doc.Version, "2.0")
+
+	// title, link and description are required on the channel
+	assert.Equal(t, doc.Channel.Title, "Go Blog")
+	assert.Equal(t, doc.Channel.Description, "The latest posts")
+	assert.Equal(t, len(doc.Channel.Links), 2)
+	assert.Equal(t, doc.Channel.Links[0].XMLName.Space, "")
+	assert.Equal(t, doc.Channel.Links[0].Value, "http://example.com/")
+	assert.Equal(t, doc.Channel.Links[1].XMLName.Space, "http://www.w3.org/2005/Atom")
+	assert.Equal(t, doc.Channel.Links[1].Href, "http://example.com/feed.xml")
+	assert.Equal(t, doc.Channel.Links[1].Rel, "self")
+
+	assert.Equal(t, len(doc.Channel.Items), 1)
+	item := doc.Channel.Items[0]
+	assert.Equal(t, item.Title, "Fish & Chips")
+	assert.Equal(t, item.Link, "http://example.com/posts/fish-chips")
+	assert.Equal(t, item.Description, "<p>A <em>recipe</em> &amp; more</p>")
+	assert.Equal(t, item.Creator, "magu")
+	assert.Equal(t, item.Categories, []string{"food"})
+	assert.Equal(t, item.GUID.IsPermaLink, "false")
+	assert.Equal(t, item.GUID.Value, "http://example.com/api/posts/1")
+
+	// dates are RFC 822 dates
+	date, err := time.Parse(time.RFC1123Z, item.PubDate)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, date.Equal(published), true)
+}
+
+// Atom 1.0: RFC 4287
+func TestAtom(t *testing.T) {
+	out, err := feeds.Atom(sampleFeed())
+	if err != nil {
+		t.Fatalf("cannot write atom: %v", err)
+	}
+
+	type link struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	}
+
+	doc := struct {
+		XMLName xml.Name
+		ID      string `xml:"http://www.w3.org/2005/Atom id"`
+		Title   string `xml:"http://www.w3.org/2005/Atom title"`
+		Updated string `xml:"http://www.w3.org/2005/Atom updated"`
+		Links   []link `xml:"http://www.w3.org/2005/Atom link"`
+		Entries []struct {
+			ID        string `xml:"http://www.w3.org/2005/Atom id"`
+			Title     string `xml:"http://www.w3.org/2005/Atom title"`
+			Updated   string `xml:"http://www.w3.org/2005/Atom updated"`
+			Published string `xml:"http://www.w3.org/2005/Atom published"`
+			Links     []link `xml:"http://www.w3.org/2005/Atom link"`
+			Author    string `xml:"http://www.w3.org/2005/Atom author>name"`
+			Content   struct {
+				Type  string `xml:"type,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"http://www.w3.org/2005/Atom content"`
+		} `xml:"http://www.w3.org/2005/Atom entry"`
+	}{}
+
+	err = xml.Unmarshal(out, &doc)
+	if err != nil {
+		t.Fatalf("invalid xml: %v\n%s", err, out)
+	}
+
+	assert.Equal(t, doc.XMLName.Space, "http://www.w3.org/2005/Atom")
+	assert.Equal(t, doc.XMLName.Local, "feed")
+
+	// id, title and updated are required on the feed and every entry
+	assert.Equal(t, doc.ID, "http://example.com/feed.xml")
+	assert.Equal(t, doc.Title, "Go Blog")
+	_, err = time.Parse(time.RFC3339, doc.Updated)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, doc.Links[1], link{Href: "http://example.com/feed.xml", Rel: "self"})
+
+	assert.Equal(t, len(doc.Entries), 1)
+	entry := doc.Entries[0]
+	assert.Equal(t, entry.ID, "http://example.com/api/posts/1")
+	assert.Equal(t, entry.Title, "Fish & Chips")
+	assert.Equal(t, entry.Updated, "2020-03-01T10:30:00Z")
+	assert.Equal(t, entry.Published, "2020-03-01T09:30:00Z")
+	assert.Equal(t, entry.Links[0], link{Href: "http://example.com/posts/fish-chips", Rel: "alternate"})
+	assert.Equal(t, entry.Author, "magu")
+	assert.Equal(t, entry.Content.Type, "html")
+	assert.Equal(t, entry.Content.Value, "<p>A <em>recipe</em> &amp; more</p>")
+}
+
+// JSON Feed 1.1: https://www.jsonfeed.org/version/1.1/
+func TestJSONFeed(t *testing.T) {
+	out, err := feeds.JSON(sampleFeed())
+	if err != nil {
+		t.Fatalf("cannot write json feed: %v", err)
+	}
+
+	doc := map[string]interface{}{}
+	err = json.Unmarshal(out, &doc)
+	if err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, out)
+	}
+
+	// version, title and items are required
+	assert.Equal(t, doc["version"], "https://jsonfeed.org/version/1.1")
+	assert.Equal(t, doc["title"], "Go Blog")
+	assert.Equal(t, doc["home_page_url"], "http://example.com/")
+	assert.Equal(t, doc["feed_url"], "http://example.com/feed.xml")
+
+	items := doc["items"].([]interface{})
+	assert.Equal(t, len(items), 1)
+
+	item := items[0].(map[string]interface{})
+	assert.Equal(t, item["id"], "http://example.com/api/posts/1")
+	assert.Equal(t, item["url"], "http://example.com/posts/fish-chips")
+	assert.Equal(t, item["content_html"], "<p>A <em>recipe</em> &amp; more</p>")
+	assert.Equal(t, item["date_published"], "2020-03-01T09:30:00Z")
+	assert.Equal(t, item["authors"], []interface{}{map[string]interface{}{"name": "magu"}})
+	assert.Equal(t, item["tags"], []interface{}{"food"})
+}
+
+func TestEmptyFeeds(t *testing.T) {
+	feed := feeds.Feed{Title: "Go Blog", Link: "http://example.com/", FeedURL: "http://example.com/feed.json"}
+
+	for _, format := range []string{feeds.FormatRSS, feeds.FormatAtom, feeds.FormatJSON} {
+		out, err := feeds.Write(feed, format)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, strings.Contains(string(out), "Go Blog"), true)
+	}
+
+	out, err := feeds.JSON(feed)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.Contains(string(out), `"items": []`), true)
+
+	// atom requires an updated time, there is no entry to take it from
+	out, err = feeds.Atom(feed)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.Contains(string(out), "<updated>"+time.Now().UTC().Format("2006-")), true)
+
+	out, err = feeds.RSS(feed)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.Contains(string(out), "lastBuildDate"), false)
+
+	_, err = feeds.Write(feed, "csv")
+	assert.Equal(t, err, feeds.ErrInvalidFormat)
+}
+
+func TestPostItems(t *testing.T) {
+	// titles and usernames are stored html escaped
+	post := models.Post{
+		ID:        1,
+		Title:     "Fish &amp; Chips",
+		Slug:      "fish-chips",
+		Content:   "A *recipe*",
+		Author:    models.User{Username: "Tom &amp; Jerry"},
+		PublishAt: &published,
+		UpdatedAt: published,
+	}
+
+	items, err := feeds.PostItems("http://example.com", []models.Post{post})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(items), 1)
+	assert.Equal(t, items[0].Title, "Fish & Chips")
+	assert.Equal(t, items[0].Author, "Tom & Jerry")
+	assert.Equal(t, items[0].Link, "http://example.com/posts/fish-chips")
+	assert.Equal(t, items[0].Content, "<p>A <em>recipe</em></p>\n")
+}
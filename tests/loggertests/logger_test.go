@@ -0,0 +1,107 @@
+package loggertests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/logger"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// withoutTime drops the time= field, which changes on every run
+func withoutTime(line string) string {
+	fields := strings.SplitN(line, " ", 2)
+	return strings.TrimSpace(fields[1])
+}
+
+func TestTextFormat(t *testing.T) {
+	samples := []struct {
+		msg     string
+		keyvals []interface{}
+		line    string
+	}{
+		{msg: "Listening", keyvals: []interface{}{"addr", ":8080"}, line: `level=info msg=Listening addr=:8080`},
+		{msg: "Cannot publish scheduled posts", keyvals: []interface{}{"error", errors.New("no database")}, line: `level=info msg="Cannot publish scheduled posts" error="no database"`},
+		{msg: "Shutting down", keyvals: []interface{}{"timeout", 25 * time.Second, "empty", ""}, line: `level=info msg="Shutting down" timeout=25s empty=""`},
+		{msg: "Odd", keyvals: []interface{}{"count"}, line: `level=info msg=Odd !BADKEY=count`},
+	}
+
+	for _, v := range samples {
+		out := bytes.Buffer{}
+		logger.New(&out, logger.FormatText, logger.LevelInfo).Info(v.msg, v.keyvals...)
+
+		assert.Equal(t, strings.HasPrefix(out.String(), "time="), true)
+		assert.Equal(t, withoutTime(out.String()), v.line)
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	out := bytes.Buffer{}
+	l := logger.New(&out, logger.FormatJSON, logger.LevelDebug).With("request_id", "abc")
+
+	l.Warn("Slow request", "status", 200, "duration", 1500*time.Millisecond, "error", errors.New("timeout"))
+
+	line := map[string]interface{}{}
+	err := json.Unmarshal(out.Bytes(), &line)
+	assert.Equal(t, err, nil)
+
+	assert.Equal(t, line["level"], "warn")
+	assert.Equal(t, line["msg"], "Slow request")
+	assert.Equal(t, line["request_id"], "abc")
+	assert.Equal(t, line["status"], float64(200))
+	assert.Equal(t, line["duration"], "1.5s")
+	assert.Equal(t, line["error"], "timeout")
+	assert.NotEqual(t, line["time"], nil)
+}
+
+func TestLevels(t *testing.T) {
+	out := bytes.Buffer{}
+	l := logger.New(&out, logger.FormatText, logger.LevelWarn)
+
+	l.Debug("debug")
+	l.Info("info")
+	l.Warn("warn")
+	l.Error("error")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, len(lines), 2)
+	assert.Equal(t, withoutTime(lines[0]), "level=warn msg=warn")
+	assert.Equal(t, withoutTime(lines[1]), "level=error msg=error")
+
+	samples := []struct {
+		name  string
+		level logger.Level
+		valid bool
+	}{
+		{name: "debug", level: logger.LevelDebug, valid: true},
+		{name: "INFO", level: logger.LevelInfo, valid: true},
+		{name: "error", level: logger.LevelError, valid: true},
+		{name: "verbose"},
+	}
+
+	for _, v := range samples {
+		level, err := logger.ParseLevel(v.name)
+		assert.Equal(t, err == nil, v.valid)
+		if v.valid {
+			assert.Equal(t, level, v.level)
+		}
+	}
+}
+
+func TestSQLLogger(t *testing.T) {
+	out := bytes.Buffer{}
+	sql := logger.SQLLogger{Logger: logger.New(&out, logger.FormatText, logger.LevelInfo)}
+
+	// what gorm passes for a query and for an error
+	sql.Print("sql", "/api/models/User.go:121", 2*time.Millisecond, "SELECT * FROM users WHERE email = ?", []interface{}{"pet@gmail.com"}, int64(1))
+	sql.Print("error", "/api/models/User.go:121", errors.New("no such table: users"))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, len(lines), 2)
+	assert.Equal(t, withoutTime(lines[0]), `level=info msg=SQL source=/api/models/User.go:121 duration=2ms query="SELECT * FROM users WHERE email = ?" vars=1 rows=1`)
+	assert.Equal(t, withoutTime(lines[1]), `level=error msg="Database error" source=/api/models/User.go:121 error="no such table: users"`)
+}
@@ -0,0 +1,84 @@
+package loggertests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/logger"
+	"github.com/dmdinh22/go-blog/api/middlewares"
+	"github.com/dmdinh22/go-blog/api/responses"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestRequestIDAndAccessLog(t *testing.T) {
+	out := bytes.Buffer{}
+	previous := logger.Default()
+	logger.SetDefault(logger.New(&out, logger.FormatJSON, logger.LevelInfo))
+	defer logger.SetDefault(previous)
+
+	handler := middlewares.SetMiddlewareRequestID(middlewares.SetMiddlewareAccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context()).Info("Handling")
+		responses.ERROR(w, http.StatusNotFound, errors.New("Post not found"))
+	})))
+
+	samples := []struct {
+		requestID string
+		kept      bool
+	}{
+		{requestID: "from-the-proxy-1", kept: true},
+		{requestID: ""},
+		{requestID: "has spaces"},
+		{requestID: strings.Repeat("a", 200)},
+	}
+
+	for _, v := range samples {
+		out.Reset()
+
+		req, err := http.NewRequest("GET", "/api/posts/1", nil)
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+		if v.requestID != "" {
+			req.Header.Set("X-Request-ID", v.requestID)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		id := rr.Header().Get("X-Request-ID")
+		assert.NotEqual(t, id, "")
+		assert.Equal(t, id == v.requestID, v.kept)
+
+		// the error response quotes the ID
+		responseMap := map[string]interface{}{}
+		err = json.Unmarshal(rr.Body.Bytes(), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, responseMap["error"], "Post not found")
+		assert.Equal(t, responseMap["requestId"], id)
+
+		// the handler's line and the access log line both carry it
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		assert.Equal(t, len(lines), 2)
+
+		handling, access := map[string]interface{}{}, map[string]interface{}{}
+		assert.Equal(t, json.Unmarshal([]byte(lines[0]), &handling), nil)
+		assert.Equal(t, json.Unmarshal([]byte(lines[1]), &access), nil)
+
+		assert.Equal(t, handling["request_id"], id)
+		assert.Equal(t, access["request_id"], id)
+		assert.Equal(t, access["msg"], "Request")
+		assert.Equal(t, access["method"], "GET")
+		assert.Equal(t, access["path"], "/api/posts/1")
+		assert.Equal(t, access["status"], float64(http.StatusNotFound))
+		assert.Equal(t, access["bytes"], float64(rr.Body.Len()))
+		_, timed := access["duration_ms"].(float64)
+		assert.Equal(t, timed, true)
+	}
+}
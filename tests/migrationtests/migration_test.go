@@ -0,0 +1,91 @@
+package migrationtests
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/dmdinh22/go-blog/api/migrations"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	for _, driver := range []string{"mysql", "postgres", "sqlite3"} {
+		all, err := migrations.ForDriver(driver)
+		assert.Equal(t, err, nil)
+		assert.NotEqual(t, len(all), 0)
+
+		// versions are numbered without gaps
+		for i, m := range all {
+			assert.Equal(t, m.Version, int64(i+1))
+			assert.NotEqual(t, len(migrations.Statements(m.Up)), 0)
+			assert.NotEqual(t, len(migrations.Statements(m.Down)), 0)
+		}
+	}
+
+	_, err := migrations.ForDriver("oracle")
+	assert.NotEqual(t, err, nil)
+}
+
+func TestLoadMigrations(t *testing.T) {
+	samples := []struct {
+		files    fstest.MapFS
+		versions []int64
+		valid    bool
+	}{
+		{
+			files: fstest.MapFS{
+				"0002_second.up.sql":   {Data: []byte("SELECT 2;")},
+				"0002_second.down.sql": {Data: []byte("SELECT 2;")},
+				"0001_first.up.sql":    {Data: []byte("SELECT 1;")},
+				"0001_first.down.sql":  {Data: []byte("SELECT 1;")},
+				"README.md":            {Data: []byte("not a migration")},
+			},
+			versions: []int64{1, 2},
+			valid:    true,
+		},
+		{
+			// no down file
+			files: fstest.MapFS{"0001_first.up.sql": {Data: []byte("SELECT 1;")}},
+		},
+		{
+			files: fstest.MapFS{
+				"0001_first.up.sql":   {Data: []byte("SELECT 1;")},
+				"0001_other.down.sql": {Data: []byte("SELECT 1;")},
+			},
+		},
+		{
+			files: fstest.MapFS{"first.up.sql": {Data: []byte("SELECT 1;")}},
+		},
+	}
+
+	for _, v := range samples {
+		all, err := migrations.Load(v.files)
+		assert.Equal(t, err == nil, v.valid)
+
+		versions := []int64{}
+		for _, m := range all {
+			versions = append(versions, m.Version)
+		}
+		if v.valid {
+			assert.Equal(t, versions, v.versions)
+		}
+	}
+}
+
+func TestStatements(t *testing.T) {
+	sql := `-- a comment
+CREATE TABLE a (
+  id integer, -- trailing comments stay
+  name varchar(10)
+);
+
+-- another comment
+DROP TABLE b;
+SELECT 1`
+
+	assert.Equal(t, migrations.Statements(sql), []string{
+		"CREATE TABLE a (\n  id integer, -- trailing comments stay\n  name varchar(10)\n)",
+		"DROP TABLE b",
+		"SELECT 1",
+	})
+}
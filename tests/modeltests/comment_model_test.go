@@ -0,0 +1,115 @@
+package modeltests
+
+import (
+	"log"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+var commentInstance = models.Comment{}
+
+func seedComment(post models.Post, parentID *uint64, content string) models.Comment {
+	comment := models.Comment{
+		PostID:   post.ID,
+		ParentID: parentID,
+		Content:  content,
+		AuthorID: post.AuthorID,
+	}
+
+	err := server.DB.Model(&models.Comment{}).Create(&comment).Error
+	if err != nil {
+		log.Fatalf("cannot seed comments table: %v", err)
+	}
+
+	return comment
+}
+
+func TestSaveComment(t *testing.T) {
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error Seeding table")
+	}
+
+	newComment := models.Comment{
+		PostID:   post.ID,
+		Content:  "First!",
+		AuthorID: post.AuthorID,
+	}
+	savedComment, err := newComment.SaveComment(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the comment: %v\n", err)
+		return
+	}
+
+	assert.Equal(t, savedComment.Content, "First!")
+	assert.Equal(t, savedComment.Author.ID, post.AuthorID)
+
+	// a reply to a comment of another post is rejected
+	otherParent := uint64(100)
+	orphan := models.Comment{
+		PostID:   post.ID,
+		ParentID: &otherParent,
+		Content:  "Lost",
+		AuthorID: post.AuthorID,
+	}
+	_, err = orphan.SaveComment(server.DB)
+	assert.Equal(t, err.Error(), "Parent comment not found")
+}
+
+func TestGetCommentsByPostID(t *testing.T) {
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error Seeding table")
+	}
+
+	first := seedComment(post, nil, "first")
+	reply := seedComment(post, &first.ID, "reply")
+	seedComment(post, &reply.ID, "reply to reply")
+	seedComment(post, nil, "second")
+
+	comments, err := commentInstance.GetCommentsByPostID(server.DB, post.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the comments: %v\n", err)
+		return
+	}
+
+	assert.Equal(t, len(*comments), 2)
+	assert.Equal(t, (*comments)[0].Content, "first")
+	assert.Equal(t, (*comments)[0].Replies[0].Content, "reply")
+	assert.Equal(t, (*comments)[0].Replies[0].Replies[0].Content, "reply to reply")
+	assert.Equal(t, (*comments)[1].Content, "second")
+	assert.Equal(t, len((*comments)[1].Replies), 0)
+}
+
+func TestDeleteCommentRemovesReplies(t *testing.T) {
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error Seeding table")
+	}
+
+	first := seedComment(post, nil, "first")
+	reply := seedComment(post, &first.ID, "reply")
+	seedComment(post, &reply.ID, "reply to reply")
+	seedComment(post, nil, "second")
+
+	deleted, err := commentInstance.DeleteComment(server.DB, first.ID)
+	if err != nil {
+		t.Errorf("this is the error deleting the comment: %v\n", err)
+		return
+	}
+	assert.Equal(t, deleted, int64(3))
+
+	// a thread deleted already is not counted again
+	deleted, err = commentInstance.DeleteComment(server.DB, reply.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, deleted, int64(0))
+
+	foundPost, err := postInstance.GetPostByID(server.DB, post.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, foundPost.CommentCount, 1)
+}
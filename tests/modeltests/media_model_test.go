@@ -0,0 +1,166 @@
+package modeltests
+
+import (
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func seedMedia(userID uint32, filename string) models.Media {
+	media := models.Media{
+		Key:         fmt.Sprintf("%d/%s", userID, filename),
+		Filename:    filename,
+		ContentType: "image/png",
+		Size:        100,
+		Width:       4,
+		Height:      3,
+		UserID:      userID,
+	}
+
+	_, err := media.SaveMedia(server.DB)
+	if err != nil {
+		log.Fatalf("cannot seed media table: %v", err)
+	}
+
+	return media
+}
+
+func TestSaveMedia(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing tables: %v", err)
+	}
+
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user: %v", err)
+	}
+
+	media := seedMedia(user.ID, "cat.png")
+
+	found := models.Media{}
+	_, err = found.GetMediaByID(server.DB, media.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the media: %v\n", err)
+		return
+	}
+
+	assert.Equal(t, found.Filename, "cat.png")
+	assert.Equal(t, found.UserID, user.ID)
+	assert.Equal(t, found.URL, fmt.Sprintf("/api/media/%d/content", media.ID))
+
+	_, err = found.GetMediaByID(server.DB, media.ID+1)
+	assert.Equal(t, err, models.ErrMediaNotFound)
+}
+
+func TestValidateMedia(t *testing.T) {
+	samples := []struct {
+		media        models.Media
+		errorMessage string
+	}{
+		{media: models.Media{ContentType: "image/png", Size: 10, UserID: 1}, errorMessage: ""},
+		{media: models.Media{ContentType: "text/html; charset=utf-8", Size: 10, UserID: 1}, errorMessage: "Unsupported file type"},
+		{media: models.Media{ContentType: "image/png", Size: models.MaxMediaSize + 1, UserID: 1}, errorMessage: "File is larger than 10 MB"},
+		{media: models.Media{ContentType: "image/png", Size: 10}, errorMessage: "Required User"},
+	}
+
+	for _, v := range samples {
+		err := v.media.Validate()
+		if v.errorMessage == "" {
+			assert.Equal(t, err, nil)
+		} else {
+			assert.Equal(t, err.Error(), v.errorMessage)
+		}
+	}
+}
+
+func TestCreatePostWithMedia(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing tables: %v", err)
+	}
+
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatalf("Error seeding tables: %v", err)
+	}
+
+	own := seedMedia(users[0].ID, "own.png")
+	other := seedMedia(users[1].ID, "other.png")
+
+	post := models.Post{
+		Title:    "With media",
+		Content:  "![cat](/api/media/1/content)",
+		AuthorID: users[0].ID,
+		Media:    []models.Media{{ID: own.ID}},
+	}
+
+	savedPost, err := post.CreatePost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error creating the post: %v\n", err)
+		return
+	}
+
+	assert.Equal(t, len(savedPost.Media), 1)
+	assert.Equal(t, savedPost.Media[0].Filename, "own.png")
+	assert.Equal(t, savedPost.Media[0].URL, fmt.Sprintf("/api/media/%d/content", own.ID))
+
+	// authors can only attach their own uploads
+	post = models.Post{
+		Title:    "Borrowed media",
+		Content:  "Not mine",
+		AuthorID: users[0].ID,
+		Media:    []models.Media{{ID: other.ID}},
+	}
+
+	_, err = post.CreatePost(server.DB)
+	assert.Equal(t, err, models.ErrMediaNotFound)
+
+	var count int
+	server.DB.Model(&models.Post{}).Where("title = ?", "Borrowed media").Count(&count)
+	assert.Equal(t, count, 0)
+}
+
+func TestDeleteMediaDetachesPosts(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing tables: %v", err)
+	}
+
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user: %v", err)
+	}
+
+	media := seedMedia(user.ID, "gone.png")
+
+	post := models.Post{
+		Title:    "Soon without media",
+		Content:  "Content",
+		AuthorID: user.ID,
+		Media:    []models.Media{{ID: media.ID}},
+	}
+
+	_, err = post.CreatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot seed posts table: %v", err)
+	}
+
+	deleted, err := media.DeleteMedia(server.DB, media.ID)
+	if err != nil {
+		t.Errorf("this is the error deleting the media: %v\n", err)
+		return
+	}
+	assert.Equal(t, deleted, int64(1))
+
+	found := models.Post{}
+	_, err = found.GetPostByID(server.DB, post.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(found.Media), 0)
+}
@@ -0,0 +1,231 @@
+package modeltests
+
+import (
+	"context"
+	"log"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dmdinh22/go-blog/api/controllers"
+	"github.com/dmdinh22/go-blog/api/migrations"
+	"github.com/dmdinh22/go-blog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func testMigrator() *migrations.Migrator {
+	err := server.DB.DropTableIfExists(migrations.Table, "widgets", "gadgets").Error
+	if err != nil {
+		log.Fatalf("Error dropping tables: %v", err)
+	}
+
+	all, err := migrations.Load(fstest.MapFS{
+		"0001_widgets.up.sql":   {Data: []byte("CREATE TABLE widgets (\n  id integer NOT NULL PRIMARY KEY\n);")},
+		"0001_widgets.down.sql": {Data: []byte("DROP TABLE widgets;")},
+		"0002_gadgets.up.sql":   {Data: []byte("CREATE TABLE gadgets (id integer NOT NULL PRIMARY KEY);\nINSERT INTO gadgets (id) VALUES (1);")},
+		"0002_gadgets.down.sql": {Data: []byte("DROP TABLE gadgets;")},
+	})
+	if err != nil {
+		log.Fatalf("Cannot load migrations: %v", err)
+	}
+
+	return &migrations.Migrator{DB: server.DB.DB(), Driver: server.DB.Dialect().GetName(), Migrations: all}
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	migrator := testMigrator()
+	ctx := context.Background()
+
+	status, err := migrator.Status(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(status), 2)
+	assert.Equal(t, status[0].Applied, false)
+
+	done, err := migrator.Up(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(done), 2)
+	assert.Equal(t, server.DB.HasTable("widgets"), true)
+	assert.Equal(t, server.DB.HasTable("gadgets"), true)
+
+	// applied migrations are not run again
+	done, err = migrator.Up(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(done), 0)
+
+	status, err = migrator.Status(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, status[1].Applied, true)
+	assert.Equal(t, status[1].AppliedAt.IsZero(), false)
+
+	done, err = migrator.Down(ctx, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(done), 1)
+	assert.Equal(t, done[0].ID(), "0002_gadgets")
+	assert.Equal(t, server.DB.HasTable("gadgets"), false)
+	assert.Equal(t, server.DB.HasTable("widgets"), true)
+
+	done, err = migrator.Up(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(done), 1)
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	migrator := testMigrator()
+	ctx := context.Background()
+
+	out := strings.Builder{}
+	migrator.DryRun = true
+	migrator.Out = &out
+
+	done, err := migrator.Up(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(done), 2)
+	assert.Equal(t, strings.Contains(out.String(), "-- 0001_widgets (up)\nCREATE TABLE widgets (\n  id integer NOT NULL PRIMARY KEY\n);"), true)
+	assert.Equal(t, strings.Contains(out.String(), "INSERT INTO gadgets (id) VALUES (1);"), true)
+
+	// nothing ran, not even the tracking table was created
+	assert.Equal(t, server.DB.HasTable("widgets"), false)
+	assert.Equal(t, server.DB.HasTable(migrations.Table), false)
+}
+
+func TestMigrateFailure(t *testing.T) {
+	migrator := testMigrator()
+	ctx := context.Background()
+
+	migrator.Migrations[1].Up = "CREATE TABLE gadgets (id integer NOT NULL PRIMARY KEY);\nTHIS IS NOT SQL;"
+
+	_, err := migrator.Up(ctx)
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, strings.Contains(err.Error(), "0002_gadgets up, statement 2"), true)
+
+	status, err := migrator.Status(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, status[0].Applied, true)
+
+	// drivers without transactional DDL keep the migration marked dirty
+	// until it is fixed by hand
+	if status[1].Dirty {
+		_, err = migrator.Up(ctx)
+		assert.NotEqual(t, err, nil)
+	} else {
+		assert.Equal(t, status[1].Applied, false)
+		assert.Equal(t, server.DB.HasTable("gadgets"), false)
+	}
+}
+
+func TestMigrateSQLite(t *testing.T) {
+	// a database of its own, the other tests share server.DB
+	db, err := controllers.OpenDB("sqlite3", "", "", "", "", ":memory:")
+	if err != nil {
+		log.Fatalf("Cannot open database: %v", err)
+	}
+	defer db.Close()
+
+	err = models.Migrate(db)
+	assert.Equal(t, err, nil)
+
+	user := models.User{Username: "Pet", Email: "pet@gmail.com", Password: "p@$$w0rd"}
+	_, err = user.CreateUser(db)
+	assert.Equal(t, err, nil)
+
+	post := models.Post{Title: "Hello World", Content: "content", AuthorID: user.ID, Tags: []models.Tag{{Name: "go"}}}
+	created, err := post.CreatePost(db)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, created.Slug, "hello-world")
+	assert.Equal(t, len(created.Tags), 1)
+
+	// deleting the author deletes their posts
+	_, err = user.DeleteUser(db, user.ID)
+	assert.Equal(t, err, nil)
+
+	count := 0
+	db.Model(&models.Post{}).Count(&count)
+	assert.Equal(t, count, 0)
+
+	// migrating again is a no-op
+	err = models.Migrate(db)
+	assert.Equal(t, err, nil)
+}
+
+func TestMigrateUnescapesPostContent(t *testing.T) {
+	db, err := controllers.OpenDB("sqlite3", "", "", "", "", ":memory:")
+	if err != nil {
+		log.Fatalf("Cannot open database: %v", err)
+	}
+	defer db.Close()
+
+	all, err := migrations.ForDriver("sqlite3")
+	if err != nil {
+		log.Fatalf("Cannot load migrations: %v", err)
+	}
+
+	// a post saved while content was stored escaped
+	ctx := context.Background()
+	_, err = (&migrations.Migrator{DB: db.DB(), Driver: "sqlite3", Migrations: all[:1]}).Up(ctx)
+	assert.Equal(t, err, nil)
+
+	err = db.Exec("INSERT INTO users (id, username, email, password) VALUES (1, 'Pet', 'pet@gmail.com', 'p@$$w0rd')").Error
+	assert.Equal(t, err, nil)
+	err = db.Exec("INSERT INTO posts (title, content, author_id) VALUES ('Old', ?, 1)", "&lt;b&gt; &#34;Tom&#39;s&#34; &amp;amp; &amp;lt;").Error
+	assert.Equal(t, err, nil)
+
+	_, err = (&migrations.Migrator{DB: db.DB(), Driver: "sqlite3", Migrations: all}).Up(ctx)
+	assert.Equal(t, err, nil)
+
+	post := models.Post{}
+	err = db.Model(&models.Post{}).Where("title = ?", "Old").Take(&post).Error
+	assert.Equal(t, err, nil)
+	assert.Equal(t, post.Content, `<b> "Tom's" &amp; &lt;`)
+}
+
+func TestMigrateAdoptsBaselineSchema(t *testing.T) {
+	db, err := controllers.OpenDB("sqlite3", "", "", "", "", ":memory:")
+	if err != nil {
+		log.Fatalf("Cannot open database: %v", err)
+	}
+	defer db.Close()
+
+	// the tables AutoMigrate created before migrations, with a post
+	baseline := []string{
+		"CREATE TABLE users (id integer PRIMARY KEY AUTOINCREMENT, username varchar(255) NOT NULL UNIQUE, email varchar(100) NOT NULL UNIQUE, password varchar(100) NOT NULL, created_at datetime DEFAULT CURRENT_TIMESTAMP, updated_at datetime DEFAULT CURRENT_TIMESTAMP)",
+		"CREATE TABLE posts (id integer PRIMARY KEY AUTOINCREMENT, title varchar(255) NOT NULL UNIQUE, content varchar(255) NOT NULL, author_id integer NOT NULL, created_at datetime DEFAULT CURRENT_TIMESTAMP, updated_at datetime DEFAULT CURRENT_TIMESTAMP)",
+		"INSERT INTO users (id, username, email, password) VALUES (1, 'Pet', 'pet@gmail.com', 'p@$$w0rd')",
+		"INSERT INTO posts (title, content, author_id) VALUES ('Hello World', 'Old content', 1)",
+	}
+	for _, statement := range baseline {
+		err = db.Exec(statement).Error
+		if err != nil {
+			log.Fatalf("Cannot create the baseline schema: %v", err)
+		}
+	}
+
+	err = models.Migrate(db)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, db.Dialect().HasIndex("posts", "uix_posts_slug"), true)
+	assert.Equal(t, db.HasTable("comments"), true)
+
+	post := models.Post{}
+	err = db.Model(&models.Post{}).Where("title = ?", "Hello World").Take(&post).Error
+	assert.Equal(t, err, nil)
+	assert.Equal(t, post.Slug, "hello-world")
+	assert.Equal(t, post.Status, models.PostStatusPublished)
+
+	user := models.User{}
+	err = db.Model(&models.User{}).Where("id = ?", 1).Take(&user).Error
+	assert.Equal(t, err, nil)
+	assert.Equal(t, user.Role, "user")
+
+	created, err := (&models.Post{Title: "New", Content: "content", AuthorID: 1}).CreatePost(db)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, created.Slug, "new")
+
+	// the adopted tables need nothing more
+	migrator, err := migrations.New(db.DB(), "sqlite3")
+	if err != nil {
+		log.Fatalf("Cannot create migrator: %v", err)
+	}
+	status, err := migrator.Status(context.Background())
+	assert.Equal(t, err, nil)
+	assert.Equal(t, status[0].Applied, true)
+	assert.Equal(t, status[len(status)-1].Applied, true)
+}
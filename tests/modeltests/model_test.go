@@ -1,12 +1,14 @@
 package modeltests
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"testing"
 
 	"github.com/dmdinh22/go-blog/api/controllers"
+	"github.com/dmdinh22/go-blog/api/migrations"
 	"github.com/dmdinh22/go-blog/api/models"
 	"github.com/jinzhu/gorm"
 	"github.com/joho/godotenv"
@@ -20,7 +22,8 @@ func TestMain(m *testing.M) {
 	var err error
 	err = godotenv.Load(os.ExpandEnv("../../.env"))
 
-	if err != nil {
+	// the .env is optional, without one the tests run on sqlite
+	if err != nil && !os.IsNotExist(err) {
 		log.Fatalf("Error getting env %v\n", err)
 	}
 
@@ -33,6 +36,23 @@ func Database() {
 
 	TestDbDriver := os.Getenv("TEST_DB_DRIVER")
 
+	if TestDbDriver == "" || TestDbDriver == "sqlite3" {
+		// in memory unless TEST_DB_NAME names a file
+		TestDbName := os.Getenv("TEST_DB_NAME")
+		if TestDbName == "" {
+			TestDbName = ":memory:"
+		}
+
+		server.DB, err = controllers.OpenDB("sqlite3", "", "", "", "", TestDbName)
+
+		if err != nil {
+			fmt.Println("Cannot connect to sqlite3 database")
+			log.Fatal("This is the error:", err)
+		} else {
+			fmt.Println("We are connected to the sqlite3 database")
+		}
+	}
+
 	if TestDbDriver == "mysql" {
 		DBURL := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True&loc=Local", os.Getenv("TEST_DB_USER"), os.Getenv("TEST_DB_PASSWORD"), os.Getenv("TEST_DB_HOST"), os.Getenv("TEST_DB_PORT"), os.Getenv("TEST_DB_NAME"))
 		server.DB, err = gorm.Open(TestDbDriver, DBURL)
@@ -58,24 +78,34 @@ func Database() {
 	}
 }
 
-func refreshUserTable() error {
-	err := server.DB.DropTableIfExists(&models.User{}).Error
-
+// refreshTables drops every table, then builds the schema from the
+// migrations the way a new database gets it. The tables reference each
+// other, so they are always refreshed together.
+func refreshTables() error {
+	err := server.DB.DropTableIfExists("post_tags", "post_media", &models.RevokedToken{}, &models.RefreshToken{}, &models.Comment{}, &models.PostSlug{}, &models.PostRevision{}, &models.Media{}, &models.Tag{}, &models.Post{}, &models.User{}, migrations.Table).Error
 	if err != nil {
 		return err
 	}
 
-	err = server.DB.AutoMigrate((&models.User{})).Error
+	migrator, err := migrations.New(server.DB.DB(), server.DB.Dialect().GetName())
+	if err != nil {
+		return err
+	}
 
+	_, err = migrator.Up(context.Background())
 	if err != nil {
 		return err
 	}
 
-	log.Printf("Successfully refreshed table")
+	log.Printf("Successfully refreshed tables")
 
 	return nil
 }
 
+func refreshUserTable() error {
+	return refreshTables()
+}
+
 func seedOneUser() (models.User, error) {
 	refreshUserTable()
 
@@ -120,20 +150,7 @@ func seedUsers() error {
 }
 
 func refreshUserAndPostTable() error {
-	err := server.DB.DropTableIfExists(&models.User{}, &models.Post{}).Error
-
-	if err != nil {
-		return err
-	}
-
-	err = server.DB.AutoMigrate(&models.User{}, &models.Post{}).Error
-	if err != nil {
-		return err
-	}
-
-	log.Printf("Successfully refreshed tables")
-
-	return nil
+	return refreshTables()
 }
 
 func seedOneUserAndOnePost() (models.Post, error) {
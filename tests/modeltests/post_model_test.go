@@ -29,6 +29,46 @@ func TestGetAllPosts(t *testing.T) {
 	assert.Equal(t, len(*posts), 2)
 }
 
+func TestFindPosts(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table %v\n", err)
+	}
+
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatalf("Error seeding user and post  table %v\n", err)
+	}
+
+	firstPage, nextCursor, err := postInstance.FindPosts(server.DB, models.PostQuery{Limit: 1})
+	if err != nil {
+		t.Errorf("this is the error getting the posts: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*firstPage), 1)
+	assert.NotEqual(t, nextCursor, "")
+
+	secondPage, nextCursor, err := postInstance.FindPosts(server.DB, models.PostQuery{Limit: 1, Cursor: nextCursor})
+	if err != nil {
+		t.Errorf("this is the error getting the posts: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*secondPage), 1)
+	assert.Equal(t, nextCursor, "")
+	assert.NotEqual(t, (*firstPage)[0].ID, (*secondPage)[0].ID)
+
+	byAuthor, _, err := postInstance.FindPosts(server.DB, models.PostQuery{AuthorID: users[1].ID})
+	if err != nil {
+		t.Errorf("this is the error getting the posts: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*byAuthor), 1)
+	assert.Equal(t, (*byAuthor)[0].AuthorID, users[1].ID)
+
+	_, _, err = postInstance.FindPosts(server.DB, models.PostQuery{Cursor: "bogus"})
+	assert.Equal(t, err, models.ErrInvalidCursor)
+}
+
 func TestCreatePost(t *testing.T) {
 	err := refreshUserAndPostTable()
 	if err != nil {
@@ -132,3 +172,56 @@ func TestDeletePost(t *testing.T) {
 	//Can be done this way too
 	assert.Equal(t, isDeleted, int64(1))
 }
+
+func TestDeletePostRemovesAttachedRows(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table: %v\n", err)
+	}
+
+	seeded, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error Seeding tables")
+	}
+
+	// a revision per save, and the renaming keeps the first slug
+	post := models.Post{Title: "Tagged", Content: "With comments", AuthorID: seeded.AuthorID, Tags: []models.Tag{{Name: "go"}}}
+	_, err = post.CreatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot seed posts table: %v", err)
+	}
+	post.Title = "Renamed"
+	_, err = post.UpdatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot update the post: %v", err)
+	}
+	seedComment(post, nil, "First")
+
+	// someone else's delete leaves everything in place
+	_, err = postInstance.DeletePost(server.DB, post.ID, seeded.AuthorID+1)
+	assert.Equal(t, err.Error(), "Post not found")
+
+	tables := []interface{}{&models.Comment{}, &models.PostRevision{}, &models.PostSlug{}}
+	for _, table := range tables {
+		count := 0
+		server.DB.Model(table).Where("post_id = ?", post.ID).Count(&count)
+		assert.NotEqual(t, count, 0)
+	}
+
+	isDeleted, err := postInstance.DeletePost(server.DB, post.ID, post.AuthorID)
+	if err != nil {
+		t.Errorf("this is the error deleting the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, isDeleted, int64(1))
+
+	for _, table := range tables {
+		count := 0
+		server.DB.Model(table).Where("post_id = ?", post.ID).Count(&count)
+		assert.Equal(t, count, 0)
+	}
+
+	count := 0
+	server.DB.Table("post_tags").Where("post_id = ?", post.ID).Count(&count)
+	assert.Equal(t, count, 0)
+}
@@ -0,0 +1,143 @@
+package modeltests
+
+import (
+	"log"
+	"testing"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func seedPostWithStatus(authorID uint32, title, status string, publishAt *time.Time) models.Post {
+	post := models.Post{
+		Title:     title,
+		Content:   "Content of " + title,
+		AuthorID:  authorID,
+		Status:    status,
+		PublishAt: publishAt,
+	}
+
+	_, err := post.CreatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot seed posts table: %v", err)
+	}
+
+	return post
+}
+
+func TestValidatePostStatus(t *testing.T) {
+	publishAt := time.Now().Add(time.Hour)
+
+	samples := []struct {
+		status       string
+		publishAt    *time.Time
+		errorMessage string
+	}{
+		{status: "", errorMessage: ""},
+		{status: models.PostStatusDraft, errorMessage: ""},
+		{status: models.PostStatusScheduled, publishAt: &publishAt, errorMessage: ""},
+		{status: models.PostStatusScheduled, errorMessage: "Scheduled posts require publishAt"},
+		{status: "hidden", errorMessage: "Status must be draft, scheduled, published or archived"},
+	}
+
+	for _, v := range samples {
+		post := models.Post{Title: "Title", Content: "Content", AuthorID: 1, Status: v.status, PublishAt: v.publishAt}
+		err := post.Validate()
+		if v.errorMessage == "" {
+			assert.Equal(t, err, nil)
+		} else {
+			assert.Equal(t, err.Error(), v.errorMessage)
+		}
+	}
+}
+
+func TestCreatePostIsPublishedByDefault(t *testing.T) {
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error Seeding table")
+	}
+
+	created := seedPostWithStatus(post.AuthorID, "Straight out", "", nil)
+	assert.Equal(t, created.Status, models.PostStatusPublished)
+	assert.NotEqual(t, created.PublishAt, nil)
+
+	draft := seedPostWithStatus(post.AuthorID, "Not yet", models.PostStatusDraft, nil)
+	assert.Equal(t, draft.Status, models.PostStatusDraft)
+	assert.Equal(t, draft.PublishAt == nil, true)
+}
+
+func TestFindPostsVisibility(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = seedUsers()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	later := time.Now().Add(time.Hour)
+	seedPostWithStatus(1, "Published by one", models.PostStatusPublished, nil)
+	seedPostWithStatus(1, "Draft by one", models.PostStatusDraft, nil)
+	seedPostWithStatus(1, "Scheduled by one", models.PostStatusScheduled, &later)
+	seedPostWithStatus(2, "Published by two", models.PostStatusPublished, nil)
+	seedPostWithStatus(2, "Archived by two", models.PostStatusArchived, nil)
+
+	samples := []struct {
+		query models.PostQuery
+		count int
+	}{
+		{query: models.PostQuery{}, count: 2},
+		{query: models.PostQuery{ViewerID: 1}, count: 4},
+		{query: models.PostQuery{ViewerID: 2}, count: 3},
+		{query: models.PostQuery{ViewerID: 1, Status: models.PostStatusDraft}, count: 1},
+		{query: models.PostQuery{ViewerID: 2, Status: models.PostStatusDraft}, count: 0},
+		{query: models.PostQuery{Status: models.PostStatusArchived}, count: 0},
+	}
+
+	for _, v := range samples {
+		posts, _, err := postInstance.FindPosts(server.DB, v.query)
+		if err != nil {
+			t.Errorf("this is the error getting the posts: %v\n", err)
+			return
+		}
+
+		assert.Equal(t, len(*posts), v.count)
+	}
+}
+
+func TestPublishDuePosts(t *testing.T) {
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error Seeding table")
+	}
+
+	now := time.Now()
+	due := now.Add(-time.Minute)
+	later := now.Add(time.Hour)
+	duePost := seedPostWithStatus(post.AuthorID, "Due", models.PostStatusScheduled, &due)
+	seedPostWithStatus(post.AuthorID, "Later", models.PostStatusScheduled, &later)
+
+	count, err := models.PublishDuePosts(server.DB, now)
+	if err != nil {
+		t.Errorf("this is the error publishing posts: %v\n", err)
+		return
+	}
+	assert.Equal(t, count, int64(1))
+
+	published, err := duePost.GetPostByID(server.DB, duePost.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, published.Status, models.PostStatusPublished)
+
+	next, err := models.NextScheduledPublish(server.DB)
+	if err != nil {
+		t.Errorf("this is the error getting the next post: %v\n", err)
+		return
+	}
+	assert.Equal(t, next.Unix(), later.Unix())
+}
@@ -0,0 +1,120 @@
+package modeltests
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+var queryCount int64
+var registerQueryCounter sync.Once
+
+// countQueries returns how many SELECTs gorm issued while running fn
+func countQueries(fn func()) int64 {
+	registerQueryCounter.Do(func() {
+		server.DB.Callback().Query().After("gorm:query").Register("modeltests:count_queries", func(*gorm.Scope) {
+			atomic.AddInt64(&queryCount, 1)
+		})
+	})
+
+	start := atomic.LoadInt64(&queryCount)
+	fn()
+
+	return atomic.LoadInt64(&queryCount) - start
+}
+
+func seedAuthorsAndPosts(authorCount, postCount int) error {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		return err
+	}
+
+	users := make([]models.User, authorCount)
+	for i := range users {
+		users[i] = models.User{
+			Username: fmt.Sprintf("author %d", i),
+			Email:    fmt.Sprintf("author%d@mailinator.com", i),
+			Password: "p@$$w0rd",
+		}
+
+		err = server.DB.Model(&models.User{}).Create(&users[i]).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < postCount; i++ {
+		post := models.Post{
+			Title:    fmt.Sprintf("Title %d", i),
+			Content:  fmt.Sprintf("Hello world %d", i),
+			AuthorID: users[i%authorCount].ID,
+		}
+
+		err = server.DB.Model(&models.Post{}).Create(&post).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestPreloadAuthorsQueryCount(t *testing.T) {
+	for _, size := range []int{1, 10, 50} {
+		err := seedAuthorsAndPosts(5, size)
+		if err != nil {
+			log.Fatalf("Error seeding user and post table %v\n", err)
+		}
+
+		var posts *[]models.Post
+		queries := countQueries(func() {
+			posts, _, err = postInstance.FindPosts(server.DB, models.PostQuery{Limit: models.MaxPostLimit})
+		})
+		if err != nil {
+			t.Errorf("this is the error getting the posts: %v\n", err)
+			return
+		}
+
+		assert.Equal(t, len(*posts), size)
+		for _, post := range *posts {
+			assert.Equal(t, post.Author.ID, post.AuthorID)
+		}
+
+		// one query for the page of posts and one each for all of their
+		// authors, comment counts, tags and media
+		assert.Equal(t, queries, int64(5))
+	}
+}
+
+func BenchmarkFindPostsWithAuthors(b *testing.B) {
+	for _, size := range []int{10, 50, 100} {
+		err := seedAuthorsAndPosts(10, size)
+		if err != nil {
+			log.Fatalf("Error seeding user and post table %v\n", err)
+		}
+
+		b.Run(fmt.Sprintf("posts=%d", size), func(b *testing.B) {
+			var queries int64
+			for i := 0; i < b.N; i++ {
+				queries += countQueries(func() {
+					_, _, err = postInstance.FindPosts(server.DB, models.PostQuery{Limit: models.MaxPostLimit})
+				})
+				if err != nil {
+					b.Fatalf("this is the error getting the posts: %v\n", err)
+				}
+			}
+
+			perOp := float64(queries) / float64(b.N)
+			if perOp != 5 {
+				b.Fatalf("expected 5 queries per listing regardless of size, got %v", perOp)
+			}
+			b.ReportMetric(perOp, "queries/op")
+		})
+	}
+}
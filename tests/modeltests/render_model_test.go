@@ -0,0 +1,93 @@
+package modeltests
+
+import (
+	"log"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/dmdinh22/go-blog/api/render"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestRenderPost(t *testing.T) {
+	samples := []struct {
+		content string
+		format  string
+		html    string
+		text    string
+	}{
+		{
+			content: "# Hello\n\nSome *emphasis* & [a link](https://example.com).",
+			format:  render.FormatHTML,
+			html:    "<h1>Hello</h1>\n<p>Some <em>emphasis</em> &amp; <a href=\"https://example.com\" rel=\"nofollow\">a link</a>.</p>\n",
+		},
+		{
+			content: "Hi <script>alert('x')</script><b onclick=\"steal()\">there</b>",
+			format:  render.FormatHTML,
+			html:    "<p>Hi <b>there</b></p>\n",
+		},
+		{
+			content: "[click](javascript:alert(1))",
+			format:  render.FormatHTML,
+			html:    "<p>click</p>\n",
+		},
+		{
+			content: "# Hello\n\nSome *emphasis* & more.",
+			format:  render.FormatPlain,
+			text:    "Hello\nSome emphasis & more.",
+		},
+		{
+			content: "# Hello",
+			format:  render.FormatMarkdown,
+		},
+	}
+
+	for _, v := range samples {
+		post := models.Post{Content: v.content}
+		err := post.Render(v.format)
+		if err != nil {
+			t.Errorf("this is the error rendering the post: %v\n", err)
+			return
+		}
+
+		assert.Equal(t, post.ContentHTML, v.html)
+		assert.Equal(t, post.ContentText, v.text)
+		assert.Equal(t, post.Content, v.content)
+	}
+
+	post := models.Post{Content: "# Hello"}
+	err := post.Render("pdf")
+	assert.Equal(t, err, render.ErrInvalidFormat)
+}
+
+func TestCreatePostKeepsMarkdown(t *testing.T) {
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error Seeding table")
+	}
+
+	content := "Use `a < b` & keep <em>this</em>"
+	for len(content) < 1000 {
+		content += "\n\nAnother paragraph that makes this longer than the old 255 character column."
+	}
+
+	newPost := models.Post{
+		Title:    "Markdown",
+		Content:  content,
+		AuthorID: post.AuthorID,
+	}
+	newPost.Prepare()
+
+	savedPost, err := newPost.CreatePost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error creating the post: %v\n", err)
+		return
+	}
+
+	saved, err := savedPost.GetPostByID(server.DB, savedPost.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, saved.Content, content)
+}
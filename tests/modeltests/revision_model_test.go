@@ -0,0 +1,116 @@
+package modeltests
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestUpdatePostRecordsRevisions(t *testing.T) {
+	// seeded directly, so the post predates its revision history
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error Seeding table")
+	}
+
+	updates := []string{"Second version", "Third version"}
+	for _, content := range updates {
+		postUpdate := models.Post{ID: post.ID, Title: post.Title, Content: content, AuthorID: post.AuthorID}
+		_, err = postUpdate.UpdatePost(server.DB)
+		if err != nil {
+			t.Errorf("this is the error updating the post: %v\n", err)
+			return
+		}
+	}
+
+	revisions, err := post.GetRevisions(server.DB, post.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the revisions: %v\n", err)
+		return
+	}
+
+	assert.Equal(t, len(*revisions), 3)
+	assert.Equal(t, (*revisions)[0].Revision, 3)
+	assert.Equal(t, (*revisions)[0].Content, "Third version")
+	assert.Equal(t, (*revisions)[2].Revision, 1)
+	assert.Equal(t, (*revisions)[2].Content, "This is the content sam")
+
+	created := seedPostWithStatus(post.AuthorID, "Fresh", "", nil)
+	latest, err := created.LatestRevision(server.DB, created.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the latest revision: %v\n", err)
+		return
+	}
+	assert.Equal(t, latest, 1)
+}
+
+func TestConcurrentUpdatesNumberRevisions(t *testing.T) {
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error Seeding table")
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			postUpdate := models.Post{ID: post.ID, Title: post.Title, Content: fmt.Sprintf("Version %d", i), AuthorID: post.AuthorID}
+			_, err := postUpdate.UpdatePost(server.DB)
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.Equal(t, err, nil)
+	}
+
+	latest, err := post.LatestRevision(server.DB, post.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, latest, 6)
+}
+
+func TestDiffRevisions(t *testing.T) {
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error Seeding table")
+	}
+
+	postUpdate := models.Post{ID: post.ID, Title: "This is the new title sam", Content: "This is the content sam", AuthorID: post.AuthorID}
+	_, err = postUpdate.UpdatePost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error updating the post: %v\n", err)
+		return
+	}
+
+	diff, err := post.DiffRevisions(server.DB, post.ID, 1, 2)
+	if err != nil {
+		t.Errorf("this is the error diffing the revisions: %v\n", err)
+		return
+	}
+
+	assert.Equal(t, diff.Diff, "--- revision 1\n"+
+		"+++ revision 2\n"+
+		"@@ -1,3 +1,3 @@\n"+
+		"-This is the title sam\n"+
+		"+This is the new title sam\n"+
+		" \n"+
+		" This is the content sam\n")
+
+	same, err := post.DiffRevisions(server.DB, post.ID, 2, 2)
+	if err != nil {
+		t.Errorf("this is the error diffing the revisions: %v\n", err)
+		return
+	}
+	assert.Equal(t, same.Diff, "")
+
+	_, err = post.DiffRevisions(server.DB, post.ID, 1, 5)
+	assert.Equal(t, err, models.ErrRevisionNotFound)
+}
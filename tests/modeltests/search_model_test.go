@@ -0,0 +1,75 @@
+package modeltests
+
+import (
+	"log"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestNewPostSearcher(t *testing.T) {
+	assert.Equal(t, models.NewPostSearcher("mysql"), models.MySQLSearcher{})
+	assert.Equal(t, models.NewPostSearcher("postgres"), models.PostgresSearcher{})
+	assert.Equal(t, models.NewPostSearcher("sqlite3"), models.TextSearcher{})
+}
+
+func TestTextSearcherRanksPosts(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("cannot seed user: %v\n", err)
+	}
+
+	posts := []models.Post{
+		{Title: "Cooking pasta", Content: "Boil water, add salt and pasta."},
+		{Title: "Go databases", Content: "Connecting Go to a database with gorm."},
+		{Title: "Weekend notes", Content: "Spent the weekend reading about go channels."},
+	}
+	for i := range posts {
+		posts[i].AuthorID = user.ID
+		err = server.DB.Model(&models.Post{}).Create(&posts[i]).Error
+		if err != nil {
+			log.Fatalf("cannot seed posts table: %v\n", err)
+		}
+	}
+
+	samples := []struct {
+		query  string
+		limit  int
+		titles []string
+	}{
+		{query: "go", limit: 20, titles: []string{"Go databases", "Weekend notes"}},
+		{query: "GO", limit: 1, titles: []string{"Go databases"}},
+		{query: "pasta salt", limit: 20, titles: []string{"Cooking pasta"}},
+		// substrings are not words
+		{query: "data", limit: 20, titles: []string{}},
+		{query: "rust", limit: 20, titles: []string{}},
+	}
+
+	searcher := models.TextSearcher{}
+	for _, v := range samples {
+		found, err := searcher.SearchPosts(server.DB, v.query, v.limit)
+		if err != nil {
+			t.Errorf("this is the error searching posts: %v\n", err)
+			return
+		}
+
+		titles := []string{}
+		for _, post := range *found {
+			assert.Equal(t, post.Author.ID, user.ID)
+			titles = append(titles, post.Title)
+		}
+		assert.Equal(t, titles, v.titles)
+	}
+
+	_, err = searcher.SearchPosts(server.DB, "  ", 20)
+	assert.Equal(t, err.Error(), "Required Query")
+
+	_, err = searcher.SearchPosts(server.DB, "go", 0)
+	assert.Equal(t, err.Error(), "Limit must be between 1 and 100")
+}
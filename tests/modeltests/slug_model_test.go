@@ -0,0 +1,155 @@
+package modeltests
+
+import (
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/dmdinh22/go-blog/api/utils/slug"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestMakeSlug(t *testing.T) {
+	samples := []struct {
+		text string
+		slug string
+	}{
+		{text: "Hello World", slug: "hello-world"},
+		{text: "  Go 1.13: what's new?  ", slug: "go-1-13-whats-new"},
+		{text: "Crème Brûlée & Co.", slug: "creme-brulee-co"},
+		{text: "Straße über Łódź", slug: "strasse-ueber-lodz"},
+		{text: "Привет, мир", slug: "privet-mir"},
+		{text: "Καλημέρα", slug: "kalimera"},
+		{text: "日本語", slug: ""},
+		{text: strings.Repeat("word ", 30), slug: strings.TrimSuffix(strings.Repeat("word-", 16), "-")},
+	}
+
+	for _, v := range samples {
+		assert.Equal(t, slug.Make(v.text), v.slug)
+	}
+}
+
+func TestCreatePostSlugs(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing tables: %v", err)
+	}
+
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user: %v", err)
+	}
+
+	samples := []struct {
+		title string
+		slug  string
+	}{
+		{title: "Hello World", slug: "hello-world"},
+		{title: "Hello, World!", slug: "hello-world-2"},
+		{title: "hello world?", slug: "hello-world-3"},
+		{title: "Tom &amp; Jerry", slug: "tom-jerry"},
+		{title: "日本語", slug: "post"},
+	}
+
+	for _, v := range samples {
+		post := models.Post{Title: v.title, Content: "Content", AuthorID: user.ID}
+		savedPost, err := post.CreatePost(server.DB)
+		if err != nil {
+			t.Errorf("this is the error creating the post: %v\n", err)
+			return
+		}
+		assert.Equal(t, savedPost.Slug, v.slug)
+	}
+}
+
+func TestUpdatePostSlugHistory(t *testing.T) {
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error Seeding table")
+	}
+
+	original := post.Slug
+
+	// the same words keep the slug
+	post.Title = post.Title + "!"
+	updatedPost, err := post.UpdatePost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error updating the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, updatedPost.Slug, original)
+
+	updatedPost.Title = "A New Title"
+	updatedPost, err = updatedPost.UpdatePost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error updating the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, updatedPost.Slug, "a-new-title")
+
+	// the old slug still leads to the post
+	found := models.Post{}
+	_, err = found.GetPostByOldSlug(server.DB, original)
+	if err != nil {
+		t.Errorf("this is the error getting the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, found.ID, post.ID)
+	assert.Equal(t, found.Slug, "a-new-title")
+
+	// and is not given to another post
+	other := models.Post{Title: "This is the title, Sam", Content: "Content", AuthorID: post.AuthorID}
+	otherPost, err := other.CreatePost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error creating the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, otherPost.Slug, original+"-2")
+
+	var count int
+	server.DB.Model(&models.PostSlug{}).Where("post_id = ?", post.ID).Count(&count)
+	assert.Equal(t, count, 1)
+}
+
+func TestBackfillSlugs(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing tables: %v", err)
+	}
+
+	_, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatalf("Error seeding tables: %v", err)
+	}
+
+	err = server.DB.Exec("UPDATE posts SET slug = NULL").Error
+	if err != nil {
+		log.Fatalf("cannot clear slugs: %v", err)
+	}
+
+	pending, err := models.PostsWithoutSlug(server.DB)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, pending, 2)
+
+	updated, err := models.BackfillSlugs(server.DB)
+	if err != nil {
+		t.Errorf("this is the error backfilling slugs: %v\n", err)
+		return
+	}
+	assert.Equal(t, updated, 2)
+
+	pending, err = models.PostsWithoutSlug(server.DB)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, pending, 0)
+
+	for _, post := range posts {
+		found := models.Post{}
+		_, err = found.GetPostByID(server.DB, post.ID)
+		if err != nil {
+			t.Errorf("this is the error getting the post: %v\n", err)
+			return
+		}
+		assert.Equal(t, found.Slug, slug.Make(post.Title))
+	}
+}
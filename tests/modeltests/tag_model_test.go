@@ -0,0 +1,204 @@
+package modeltests
+
+import (
+	"log"
+	"testing"
+
+	"github.com/dmdinh22/go-blog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func tagged(tags ...string) []models.Tag {
+	result := []models.Tag{}
+	for _, name := range tags {
+		result = append(result, models.Tag{Name: name})
+	}
+	return result
+}
+
+func seedTaggedPost(authorID uint32, title string, tags ...string) models.Post {
+	post := models.Post{
+		Title:    title,
+		Content:  "Content of " + title,
+		AuthorID: authorID,
+		Tags:     tagged(tags...),
+	}
+
+	err := post.Validate()
+	if err != nil {
+		log.Fatalf("cannot validate post: %v", err)
+	}
+
+	_, err = post.CreatePost(server.DB)
+	if err != nil {
+		log.Fatalf("cannot seed posts table: %v", err)
+	}
+
+	return post
+}
+
+func TestCreatePostWithTags(t *testing.T) {
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error Seeding table")
+	}
+
+	first := seedTaggedPost(post.AuthorID, "First", " Go ", "databases", "go")
+	second := seedTaggedPost(post.AuthorID, "Second", "GO")
+
+	assert.Equal(t, len(first.Tags), 2)
+	assert.Equal(t, first.Tags[0].Name, "databases")
+	assert.Equal(t, first.Tags[1].Name, "go")
+
+	// the same tag is shared between posts rather than created twice
+	assert.Equal(t, second.Tags[0].ID, first.Tags[1].ID)
+
+	var count int
+	server.DB.Model(&models.Tag{}).Count(&count)
+	assert.Equal(t, count, 2)
+}
+
+func TestValidatePostTags(t *testing.T) {
+	tooMany := []string{}
+	for i := 0; i <= models.MaxTagsPerPost; i++ {
+		tooMany = append(tooMany, string(rune('a'+i)))
+	}
+
+	samples := []struct {
+		tags         []models.Tag
+		errorMessage string
+	}{
+		{tags: tagged("go", "Go"), errorMessage: ""},
+		{tags: tagged("  "), errorMessage: "Tag names cannot be empty"},
+		{tags: tagged(tooMany...), errorMessage: "A post can have at most 10 tags"},
+	}
+
+	for _, v := range samples {
+		post := models.Post{Title: "Title", Content: "Content", AuthorID: 1, Tags: v.tags}
+		err := post.Validate()
+		if v.errorMessage == "" {
+			assert.Equal(t, err, nil)
+			assert.Equal(t, len(post.Tags), 1)
+		} else {
+			assert.Equal(t, err.Error(), v.errorMessage)
+		}
+	}
+}
+
+func TestUpdatePostTags(t *testing.T) {
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error Seeding table")
+	}
+
+	created := seedTaggedPost(post.AuthorID, "Tagged", "go", "web")
+
+	// leaving the tags out keeps them
+	update := models.Post{ID: created.ID, Title: "Tagged", Content: "Updated", AuthorID: post.AuthorID}
+	updated, err := update.UpdatePost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error updating the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(updated.Tags), 2)
+
+	update = models.Post{ID: created.ID, Title: "Tagged", Content: "Updated", AuthorID: post.AuthorID, Tags: tagged("web", "databases")}
+	updated, err = update.UpdatePost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error updating the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(updated.Tags), 2)
+	assert.Equal(t, updated.Tags[0].Name, "databases")
+	assert.Equal(t, updated.Tags[1].Name, "web")
+
+	// an empty list removes every tag
+	update = models.Post{ID: created.ID, Title: "Tagged", Content: "Updated", AuthorID: post.AuthorID, Tags: []models.Tag{}}
+	updated, err = update.UpdatePost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error updating the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(updated.Tags), 0)
+}
+
+func TestFindPostsByTag(t *testing.T) {
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error Seeding table")
+	}
+
+	seedTaggedPost(post.AuthorID, "Go only", "go")
+	seedTaggedPost(post.AuthorID, "Go and databases", "go", "databases")
+	seedTaggedPost(post.AuthorID, "Databases only", "databases")
+
+	samples := []struct {
+		query  models.PostQuery
+		titles []string
+	}{
+		{
+			query:  models.PostQuery{Tags: []string{"go"}},
+			titles: []string{"Go and databases", "Go only"},
+		},
+		{
+			query:  models.PostQuery{Tags: []string{"Go", "databases"}},
+			titles: []string{"Go and databases"},
+		},
+		{
+			query:  models.PostQuery{Tags: []string{"go", "databases"}, TagMode: models.TagModeAny},
+			titles: []string{"Databases only", "Go and databases", "Go only"},
+		},
+		{
+			query:  models.PostQuery{Tags: []string{"rust"}},
+			titles: []string{},
+		},
+	}
+
+	for _, v := range samples {
+		posts, _, err := postInstance.FindPosts(server.DB, v.query)
+		if err != nil {
+			t.Errorf("this is the error getting the posts: %v\n", err)
+			return
+		}
+
+		titles := []string{}
+		for _, p := range *posts {
+			titles = append(titles, p.Title)
+		}
+		assert.Equal(t, titles, v.titles)
+	}
+
+	_, _, err = postInstance.FindPosts(server.DB, models.PostQuery{Tags: []string{"go"}, TagMode: "some"})
+	assert.Equal(t, err.Error(), "Tag mode must be all or any")
+}
+
+func TestGetTagsWithPostCounts(t *testing.T) {
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error Seeding table")
+	}
+
+	seedTaggedPost(post.AuthorID, "One", "go", "web")
+	seedTaggedPost(post.AuthorID, "Two", "go")
+	deleted := seedTaggedPost(post.AuthorID, "Three", "go", "rust")
+
+	_, err = postInstance.DeletePost(server.DB, deleted.ID, post.AuthorID)
+	if err != nil {
+		t.Errorf("this is the error deleting the post: %v\n", err)
+		return
+	}
+
+	tags, err := models.GetTagsWithPostCounts(server.DB)
+	if err != nil {
+		t.Errorf("this is the error getting the tags: %v\n", err)
+		return
+	}
+
+	assert.Equal(t, len(*tags), 3)
+	assert.Equal(t, (*tags)[0].Name, "go")
+	assert.Equal(t, (*tags)[0].PostCount, 2)
+	assert.Equal(t, (*tags)[1].Name, "web")
+	assert.Equal(t, (*tags)[1].PostCount, 1)
+	assert.Equal(t, (*tags)[2].Name, "rust")
+	assert.Equal(t, (*tags)[2].PostCount, 0)
+}
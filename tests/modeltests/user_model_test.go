@@ -4,6 +4,7 @@ import (
 	"log"
 	"testing"
 
+	"github.com/dmdinh22/go-blog/api/auth"
 	"github.com/dmdinh22/go-blog/api/models"
 	_ "github.com/jinzhu/gorm/dialects/mysql"    //mysql driver
 	_ "github.com/jinzhu/gorm/dialects/postgres" //postgres driver
@@ -102,6 +103,7 @@ func TestUpdateUser(t *testing.T) {
 	assert.Equal(t, updatedUser.ID, userUpdate.ID)
 	assert.Equal(t, updatedUser.Email, userUpdate.Email)
 	assert.Equal(t, updatedUser.Username, userUpdate.Username)
+	assert.Equal(t, updatedUser.Role, auth.RoleUser)
 }
 
 func TestDeleteUser(t *testing.T) {
@@ -0,0 +1,273 @@
+package repositorytests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dmdinh22/go-blog/api/auth"
+	"github.com/dmdinh22/go-blog/api/controllers"
+	"github.com/dmdinh22/go-blog/api/middlewares"
+	"github.com/dmdinh22/go-blog/api/models"
+	"github.com/gorilla/mux"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// newServer returns a server keeping its posts and users in memory, with
+// the users Pet and Kenny signed up
+func newServer() (*controllers.Server, []models.User) {
+	auth.SetSecret("hermetic test secret")
+
+	server := &controllers.Server{}
+	server.Posts, server.Users = models.NewMemoryRepositories()
+
+	users := []models.User{
+		models.User{Username: "Pet", Email: "pet@gmail.com", Password: "p@$$w0rd"},
+		models.User{Username: "Kenny Morris", Email: "kenny@gmail.com", Password: "p@$$w0rd"},
+	}
+
+	for i := range users {
+		_, err := server.Users.Create(&users[i])
+		if err != nil {
+			log.Fatalf("cannot create user: %v\n", err)
+		}
+	}
+
+	return server, users
+}
+
+func bearer(server *controllers.Server, email string) string {
+	token, err := server.SignIn(email, "p@$$w0rd")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+
+	return fmt.Sprintf("Bearer %v", token)
+}
+
+func serve(handler http.HandlerFunc, method, path, body, token string, vars map[string]string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, path, bytes.NewBufferString(body))
+	if err != nil {
+		log.Fatalf("this is the error: %v\n", err)
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	if vars != nil {
+		req = mux.SetURLVars(req, vars)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestCreateAndGetUsers(t *testing.T) {
+	server, _ := newServer()
+
+	samples := []struct {
+		inputJSON    string
+		statusCode   int
+		username     string
+		errorMessage string
+	}{
+		{inputJSON: `{"username":"Steven", "email": "steven@gmail.com", "password": "password"}`, statusCode: 201, username: "Steven"},
+		{inputJSON: `{"username":"Frank", "email": "pet@gmail.com", "password": "password"}`, statusCode: 500, errorMessage: "Email has already been used."},
+		{inputJSON: `{"username":"Pet", "email": "grand@gmail.com", "password": "password"}`, statusCode: 500, errorMessage: "Username has already been used."},
+		{inputJSON: `{"username":"Kan", "email": "kangmail.com", "password": "password"}`, statusCode: 422, errorMessage: "Invalid Email."},
+	}
+
+	for _, v := range samples {
+		rr := serve(server.CreateUser, "POST", "/api/users", v.inputJSON, "", nil)
+
+		responseMap := map[string]interface{}{}
+		err := json.Unmarshal(rr.Body.Bytes(), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 201 {
+			assert.Equal(t, responseMap["Username"], v.username)
+			assert.Equal(t, responseMap["role"], auth.RoleUser)
+		} else {
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+	}
+
+	rr := serve(server.GetUsers, "GET", "/api/users", "", "", nil)
+	users := []models.User{}
+	err := json.Unmarshal(rr.Body.Bytes(), &users)
+	if err != nil {
+		log.Fatalf("Cannot convert to json: %v\n", err)
+	}
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, len(users), 3)
+
+	rr = serve(server.GetUser, "GET", "/api/users/9", "", "", map[string]string{"id": "9"})
+	assert.Equal(t, rr.Code, http.StatusBadRequest)
+}
+
+func TestPostLifecycle(t *testing.T) {
+	server, users := newServer()
+	pet := bearer(server, users[0].Email)
+	kenny := bearer(server, users[1].Email)
+
+	create := middlewares.SetMiddlewareAuthentication(server.CreatePost)
+	update := middlewares.SetMiddlewareAuthentication(server.UpdatePost)
+	remove := middlewares.SetMiddlewareAuthentication(server.DeletePost)
+
+	rr := serve(create, "POST", "/api/posts", `{"title":"Hello World", "content": "**hi**", "authorId": 1, "tags": ["Go"]}`, pet, nil)
+	assert.Equal(t, rr.Code, http.StatusCreated)
+
+	post := models.Post{}
+	err := json.Unmarshal(rr.Body.Bytes(), &post)
+	if err != nil {
+		log.Fatalf("Cannot convert to json: %v\n", err)
+	}
+
+	assert.Equal(t, post.Slug, "hello-world")
+	assert.Equal(t, post.Author.Username, "Pet")
+	assert.Equal(t, post.ContentHTML, "<p><strong>hi</strong></p>\n")
+	assert.Equal(t, len(post.Tags), 1)
+
+	id := strconv.Itoa(int(post.ID))
+	vars := map[string]string{"id": id}
+
+	samples := []struct {
+		handler      http.HandlerFunc
+		method       string
+		inputJSON    string
+		tokenGiven   string
+		statusCode   int
+		errorMessage string
+	}{
+		{handler: create, method: "POST", inputJSON: `{"title":"Hello World", "content": "again", "authorId": 1}`, tokenGiven: pet, statusCode: 500, errorMessage: "Title has already been used."},
+		{handler: create, method: "POST", inputJSON: `{"title":"Not mine", "content": "content", "authorId": 1}`, tokenGiven: kenny, statusCode: 401, errorMessage: "Unauthorized"},
+		{handler: update, method: "PUT", inputJSON: `{"title":"Stolen", "content": "content", "authorId": 1}`, tokenGiven: kenny, statusCode: 401, errorMessage: "Unauthorized"},
+		{handler: update, method: "PUT", inputJSON: `{"title":"Hello Again", "content": "updated", "authorId": 1}`, tokenGiven: pet, statusCode: 200},
+		{handler: remove, method: "DELETE", tokenGiven: kenny, statusCode: 401, errorMessage: "Unauthorized"},
+	}
+
+	for _, v := range samples {
+		rr := serve(v.handler, v.method, "/api/posts/"+id, v.inputJSON, v.tokenGiven, vars)
+		assert.Equal(t, rr.Code, v.statusCode)
+
+		if v.errorMessage != "" {
+			responseMap := map[string]interface{}{}
+			err := json.Unmarshal(rr.Body.Bytes(), &responseMap)
+			if err != nil {
+				t.Errorf("Cannot convert to json: %v", err)
+			}
+			assert.Equal(t, responseMap["error"], v.errorMessage)
+		}
+	}
+
+	// the renamed post moved to a new slug, the old one redirects
+	rr = serve(server.GetPostBySlug, "GET", "/api/posts/by-slug/hello-again", "", "", map[string]string{"slug": "hello-again"})
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	rr = serve(server.GetPostBySlug, "GET", "/api/posts/by-slug/hello-world", "", "", map[string]string{"slug": "hello-world"})
+	assert.Equal(t, rr.Code, http.StatusMovedPermanently)
+	assert.Equal(t, rr.Header().Get("Location"), "/api/posts/by-slug/hello-again")
+
+	rr = serve(remove, "DELETE", "/api/posts/"+id, "", pet, vars)
+	assert.Equal(t, rr.Code, http.StatusNoContent)
+
+	rr = serve(server.GetPostBySlug, "GET", "/api/posts/by-slug/hello-world", "", "", map[string]string{"slug": "hello-world"})
+	assert.Equal(t, rr.Code, http.StatusNotFound)
+}
+
+func TestGetPostsPaging(t *testing.T) {
+	server, users := newServer()
+
+	// five published posts a minute apart and a draft, oldest first
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		post := models.Post{
+			Title:     fmt.Sprintf("Post %d", i),
+			Content:   "content",
+			AuthorID:  users[i%2].ID,
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+		}
+
+		if i == 5 {
+			post.Status = models.PostStatusDraft
+		}
+
+		_, err := server.Posts.Create(&post)
+		if err != nil {
+			log.Fatalf("cannot create post: %v\n", err)
+		}
+	}
+
+	list := middlewares.SetMiddlewareOptionalAuthentication(server.GetPosts)
+
+	samples := []struct {
+		query      string
+		tokenGiven string
+		titles     []string
+	}{
+		{query: "?limit=2", titles: []string{"Post 4", "Post 3", "Post 2", "Post 1", "Post 0"}},
+		{query: "?limit=3&sort=created_at", titles: []string{"Post 0", "Post 1", "Post 2", "Post 3", "Post 4"}},
+		{query: "?author_id=2", titles: []string{"Post 3", "Post 1"}},
+		{query: "?author_id=2", tokenGiven: bearer(server, users[1].Email), titles: []string{"Post 5", "Post 3", "Post 1"}},
+	}
+
+	for _, v := range samples {
+		titles := []string{}
+		path := "/api/posts" + v.query
+
+		// follow the cursors to the last page
+		for path != "" {
+			rr := serve(list, "GET", path, "", v.tokenGiven, nil)
+			assert.Equal(t, rr.Code, http.StatusOK)
+
+			page := struct {
+				Data       []models.Post `json:"data"`
+				NextCursor string        `json:"next_cursor"`
+			}{}
+			err := json.Unmarshal(rr.Body.Bytes(), &page)
+			if err != nil {
+				log.Fatalf("Cannot convert to json: %v\n", err)
+			}
+
+			for _, post := range page.Data {
+				titles = append(titles, post.Title)
+			}
+
+			path = ""
+			if page.NextCursor != "" {
+				path = "/api/posts" + v.query + "&cursor=" + page.NextCursor
+			}
+		}
+
+		assert.Equal(t, titles, v.titles)
+	}
+}
+
+func TestPostLookupsUseRepository(t *testing.T) {
+	server, _ := newServer()
+
+	// a missing post is reported without touching the database, which this
+	// server does not have
+	vars := map[string]string{"id": "99"}
+
+	rr := serve(server.GetComments, "GET", "/api/posts/99/comments", "", "", vars)
+	assert.Equal(t, rr.Code, 404)
+
+	rr = serve(server.GetRevisions, "GET", "/api/posts/99/revisions", "", "", vars)
+	assert.Equal(t, rr.Code, 404)
+
+	rr = serve(server.GetRevisionDiff, "GET", "/api/posts/99/revisions/diff", "", "", vars)
+	assert.Equal(t, rr.Code, 404)
+}